@@ -120,7 +120,8 @@ func evaluateSDF2(obj bounder2, pos []ms2.Vec, dist []float32, userData any) err
 	return sdf.Evaluate(pos, dist, userData)
 }
 
-// Evaluate implements [gleval.SDF3].
+// Evaluate implements [gleval.SDF3]. Unions of many shapes skip evaluation of shapes
+// whose bounding box is farther from a batch of positions than the closest shape found so far.
 func (u *OpUnion) Evaluate(pos []ms3.Vec, dist []float32, userData any) error {
 	u.mustValidate()
 	vp, err := gleval.GetVecPool(userData)
@@ -129,6 +130,9 @@ func (u *OpUnion) Evaluate(pos []ms3.Vec, dist []float32, userData any) error {
 	}
 	auxDist := vp.Float.Acquire(len(dist))
 	defer vp.Float.Release(auxDist)
+	if len(u.joined) >= unionBVHMinShapes {
+		return u.evaluateBVH(pos, dist, auxDist, userData)
+	}
 	err = evaluateSDF3(u.joined[0], pos, dist, userData)
 	if err != nil {
 		return err
@@ -143,6 +147,75 @@ func (u *OpUnion) Evaluate(pos []ms3.Vec, dist []float32, userData any) error {
 	return nil
 }
 
+// unionCullBatch is the number of positions for which union shapes are culled together.
+const unionCullBatch = 128
+
+func (u *OpUnion) evaluateBVH(pos []ms3.Vec, dist, auxDist []float32, userData any) error {
+	cullDist := u.cullDist
+	if cullDist == 0 {
+		cullDist = math32.Inf(1)
+	}
+	bvh := u.cachedBVH()
+	for start := 0; start < len(pos); start += unionCullBatch {
+		end := min(start+unionCullBatch, len(pos))
+		d := dist[start:end]
+		for i := range d {
+			d[i] = math32.Inf(1)
+		}
+		err := u.evaluateBVHNode(bvh, 0, pos[start:end], d, auxDist[start:end], cullDist, userData)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// evaluateBVHNode min-reduces the distances of the shapes contained in the BVH node into dist.
+// A node is culled if for every position its box is farther than the current distance or cullDist.
+// Nodes whose box contains a position are never culled since shapes may be arbitrarily deep inside their box.
+// The box distance of a culled node is a lower bound of the distance to its shapes and is used in place
+// of the distance of positions for which the box is farther than cullDist but closer than the current distance.
+func (u *OpUnion) evaluateBVHNode(bvh []unionBVHNode, node int32, pos []ms3.Vec, dist, auxDist []float32, cullDist float32, userData any) error {
+	n := &bvh[node]
+	culled := true
+	for i, p := range pos {
+		bd := boxDist(n.box, p)
+		if bd == 0 || bd < dist[i] && bd <= cullDist {
+			culled = false
+			break
+		}
+	}
+	if culled {
+		for i, p := range pos {
+			dist[i] = minf(dist[i], boxDist(n.box, p))
+		}
+		return nil
+	} else if n.shape >= 0 {
+		err := evaluateSDF3(u.joined[n.shape], pos, auxDist, userData)
+		if err != nil {
+			return err
+		}
+		minReduce(dist, auxDist)
+		return nil
+	}
+	// Visit the child closest to the batch first so that the other is more likely to be culled.
+	first, second := node+1, n.right
+	if boxDist(bvh[second].box, pos[0]) < boxDist(bvh[first].box, pos[0]) {
+		first, second = second, first
+	}
+	err := u.evaluateBVHNode(bvh, first, pos, dist, auxDist, cullDist, userData)
+	if err != nil {
+		return err
+	}
+	return u.evaluateBVHNode(bvh, second, pos, dist, auxDist, cullDist, userData)
+}
+
+// boxDist returns the distance from p to the closest point of box, zero if p is inside box.
+func boxDist(box ms3.Box, p ms3.Vec) float32 {
+	q := ms3.MaxElem(ms3.Sub(box.Min, p), ms3.Sub(p, box.Max))
+	return ms3.Norm(ms3.MaxElem(q, ms3.Vec{}))
+}
+
 func (u *intersect) Evaluate(pos []ms3.Vec, dist []float32, userData any) error {
 	vp, err := gleval.GetVecPool(userData)
 	if err != nil {
@@ -579,81 +652,129 @@ func (a *arc2D) Evaluate(pos []ms2.Vec, dist []float32, userData any) error {
 }
 
 func (bz *quadbezier2d) Evaluate(poss []ms2.Vec, dist []float32, userData any) error {
-	// Inigo Quilez' exact quad bezier implementation.
 	thick := bz.thick / 2
-	A := bz.a
-	B := bz.b
-	C := bz.c
+	bq := makeBezierQ(bz.a, bz.b, bz.c)
+	for i, p := range poss {
+		dist[i] = math32.Sqrt(bq.distSq(p)) - thick
+	}
+	return nil
+}
+
+// bezierQ holds the terms of Inigo Quilez' exact quadratic bezier distance which do not depend on the position.
+type bezierQ struct {
+	A, a, b, c  ms2.Vec
+	a2          float32
+	kk, kx, kx2 float32
+}
+
+// makeBezierQ returns the distance terms of the quadratic bezier with control points A, B, C.
+func makeBezierQ(A, B, C ms2.Vec) bezierQ {
 	a := ms2.Sub(B, A)
-	a2 := ms2.Dot(a, a)
 	b := ms2.Add(A, ms2.Sub(C, ms2.Scale(2, B)))
-	c := ms2.Scale(2, a)
 	kk := 1. / ms2.Dot(b, b)
 	kx := kk * ms2.Dot(a, b)
-	kx2 := kx * kx
-	for i, p := range poss {
-		d := ms2.Sub(A, p)
-		ky := kk * (2*a2 + ms2.Dot(d, b)) / 3
-		kz := kk * ms2.Dot(d, a)
-		g := ky - kx2
-		q := kx*(2*kx2-3*ky) + kz
-		g3 := g * g * g
-		q2 := q * q
-		h := q2 + 4*g3
-		var res, sgn float32
-		var outQ ms2.Vec
-		if h >= 0 {
-			// 1 root.
-			h = math32.Sqrt(h)
-			x := ms2.Scale(0.5, ms2.AddScalar(-q, ms2.Vec{X: h, Y: -h}))
-			if math32.Abs(g) < 0.001 {
-				// When p≈0 and p<0, h-q has catastrophic cancelation. So, we do
-				// h=√(q²+4p³)=q·√(1+4p³/q²)=q·√(1+w) instead. Now we approximate
-				// √ by a linear Taylor expansion into h≈q(1+½w) so that the q's
-				// cancel each other in h-q. Expanding and simplifying further we
-				// get x=vec2(p³/q,-p³/q-q). And using a second degree Taylor
-				// expansion instead: x=vec2(k,-k-q) with k=(1-p³/q²)·p³/q
-				// k := p3 / q                // linear approx.
-				k := (1.0 - g3/q2) * g3 / q // quadratic approx.
-				x = ms2.Vec{X: k, Y: -k - q}
-			}
-			uv := ms2.MulElem(ms2.SignElem(x), powelem2(1./3, ms2.AbsElem(x)))
-			t := uv.X + uv.Y
-			// from NinjaKoala - single newton iteration to account for cancellation
-			t -= (t*(t*t+3.0*g) + q) / (3.0*t*t + 3.0*g)
-			t = ms1.Clamp(t-kx, 0, 1)
-			w := ms2.Add(d, ms2.Scale(t, ms2.Add(c, ms2.Scale(t, b))))
-			outQ = ms2.Add(w, p)
-			res = ms2.Dot(w, w)
-			sgn = ms2.Cross(ms2.Add(c, ms2.Scale(2*t, b)), w)
-		} else {
-			// 3 roots.
-			z := math32.Sqrt(-g)
-			m := cos_acos_3(q / (2 * g * z))
-			n := math32.Sqrt(1 - m*m)
-			n *= sqrt3
-			tx := ms1.Clamp((m+m)*z-kx, 0, 1)
-			ty := ms1.Clamp((-n-m)*z-kx, 0, 1)
-			// tz := ms1.Clamp((n-m)*z-kx, 0, 1)
-			qx := ms2.Add(d, ms2.Scale(tx, ms2.Add(c, ms2.Scale(tx, b))))
-			qy := ms2.Add(d, ms2.Scale(ty, ms2.Add(c, ms2.Scale(ty, b))))
-			dx := ms2.Dot(qx, qx)
-			dy := ms2.Dot(qy, qy)
-			sx := ms2.Cross(ms2.Add(a, ms2.Scale(tx, b)), qx)
-			sy := ms2.Cross(ms2.Add(a, ms2.Scale(ty, b)), qy)
-			if dx < dy {
-				res = dx
-				sgn = sx
-				outQ = ms2.Add(p, qx)
-			} else {
-				res = dy
-				sgn = sy
-				outQ = ms2.Add(p, qy)
-			}
+	return bezierQ{A: A, a: a, b: b, c: ms2.Scale(2, a), a2: ms2.Dot(a, a), kk: kk, kx: kx, kx2: kx * kx}
+}
+
+// distSq returns the squared distance from p to the quadratic bezier.
+func (bq *bezierQ) distSq(p ms2.Vec) float32 {
+	// Inigo Quilez' exact quad bezier implementation.
+	a, b, c := bq.a, bq.b, bq.c
+	a2, kk, kx, kx2 := bq.a2, bq.kk, bq.kx, bq.kx2
+	d := ms2.Sub(bq.A, p)
+	ky := kk * (2*a2 + ms2.Dot(d, b)) / 3
+	kz := kk * ms2.Dot(d, a)
+	g := ky - kx2
+	q := kx*(2*kx2-3*ky) + kz
+	g3 := g * g * g
+	q2 := q * q
+	h := q2 + 4*g3
+	var res float32
+	if h >= 0 {
+		// 1 root.
+		h = math32.Sqrt(h)
+		x := ms2.Scale(0.5, ms2.AddScalar(-q, ms2.Vec{X: h, Y: -h}))
+		if math32.Abs(g) < 0.001 {
+			// When p≈0 and p<0, h-q has catastrophic cancelation. So, we do
+			// h=√(q²+4p³)=q·√(1+4p³/q²)=q·√(1+w) instead. Now we approximate
+			// √ by a linear Taylor expansion into h≈q(1+½w) so that the q's
+			// cancel each other in h-q. Expanding and simplifying further we
+			// get x=vec2(p³/q,-p³/q-q). And using a second degree Taylor
+			// expansion instead: x=vec2(k,-k-q) with k=(1-p³/q²)·p³/q
+			// k := p3 / q                // linear approx.
+			k := (1.0 - g3/q2) * g3 / q // quadratic approx.
+			x = ms2.Vec{X: k, Y: -k - q}
+		}
+		uv := ms2.MulElem(ms2.SignElem(x), powelem2(1./3, ms2.AbsElem(x)))
+		t := uv.X + uv.Y
+		// from NinjaKoala - single newton iteration to account for cancellation
+		t -= (t*(t*t+3.0*g) + q) / (3.0*t*t + 3.0*g)
+		t = ms1.Clamp(t-kx, 0, 1)
+		w := ms2.Add(d, ms2.Scale(t, ms2.Add(c, ms2.Scale(t, b))))
+		res = ms2.Dot(w, w)
+	} else {
+		// 3 roots.
+		z := math32.Sqrt(-g)
+		m := cos_acos_3(q / (2 * g * z))
+		n := math32.Sqrt(1 - m*m)
+		n *= sqrt3
+		tx := ms1.Clamp((m+m)*z-kx, 0, 1)
+		ty := ms1.Clamp((-n-m)*z-kx, 0, 1)
+		// tz := ms1.Clamp((n-m)*z-kx, 0, 1)
+		qx := ms2.Add(d, ms2.Scale(tx, ms2.Add(c, ms2.Scale(tx, b))))
+		qy := ms2.Add(d, ms2.Scale(ty, ms2.Add(c, ms2.Scale(ty, b))))
+		res = math32.Min(ms2.Dot(qx, qx), ms2.Dot(qy, qy))
+	}
+	return res
+}
+
+// bezierCDistSq returns the squared distance from p to the cubic bezier with control points p0..p3.
+// The closest point is found by sampling the curve and refining the best sample with Newton's method.
+func bezierCDistSq(p, p0, p1, p2, p3 ms2.Vec) float32 {
+	// Coefficients of B(t)-p = ((A*t + B)*t + C)*t + D.
+	A := ms2.Add(ms2.Sub(p3, p0), ms2.Scale(3, ms2.Sub(p1, p2)))
+	B := ms2.Scale(3, ms2.Add(ms2.Sub(p2, ms2.Scale(2, p1)), p0))
+	C := ms2.Scale(3, ms2.Sub(p1, p0))
+	D := ms2.Sub(p0, p)
+	eval := func(t float32) ms2.Vec {
+		return ms2.Add(ms2.Scale(t, ms2.Add(ms2.Scale(t, ms2.Add(ms2.Scale(t, A), B)), C)), D)
+	}
+	var tb float32
+	d2 := ms2.Dot(D, D)
+	for i := 1; i <= 16; i++ {
+		t := float32(i) / 16
+		q := eval(t)
+		if dq := ms2.Dot(q, q); dq < d2 {
+			d2 = dq
+			tb = t
+		}
+	}
+	for i := 0; i < 4; i++ {
+		q := eval(tb)
+		dq := ms2.Add(ms2.Scale(tb, ms2.Add(ms2.Scale(3*tb, A), ms2.Scale(2, B))), C)
+		ddq := ms2.Add(ms2.Scale(6*tb, A), ms2.Scale(2, B))
+		df := ms2.Dot(dq, dq) + ms2.Dot(q, ddq)
+		if df != 0 {
+			tb = ms1.Clamp(tb-ms2.Dot(q, dq)/df, 0, 1)
+		}
+	}
+	q := eval(tb)
+	return math32.Min(d2, ms2.Dot(q, q))
+}
+
+func (c *path2D) Evaluate(pos []ms2.Vec, dist []float32, userData any) error {
+	for i, p := range pos {
+		d2 := float32(1e23)
+		winding := 0
+		for j := range c.segs {
+			seg := &c.segs[j]
+			d2 = math32.Min(d2, seg.distSq(p))
+			winding += seg.winding(p)
+		}
+		dist[i] = math32.Sqrt(d2)
+		if winding != 0 {
+			dist[i] = -dist[i]
 		}
-		_ = outQ // what is outQ for?
-		_ = sgn
-		dist[i] = math32.Sqrt(res) - thick
 	}
 	return nil
 }
@@ -817,6 +938,43 @@ func (p *poly2D) Evaluate(pos []ms2.Vec, dist []float32, userData any) error {
 	return nil
 }
 
+func (c *polySet) Evaluate(pos []ms2.Vec, dist []float32, userData any) error {
+	nonzero := c.rule == FillNonZero
+	for i, p := range pos {
+		d := float32(largenum)
+		var wn int
+		odd := false
+		for _, edge := range c.edges {
+			v1, v2 := edge[0], edge[1]
+			e := ms2.Sub(v2, v1)
+			w := ms2.Sub(p, v1)
+			b := ms2.Sub(w, ms2.Scale(ms1.Clamp(ms2.Dot(w, e)/ms2.Norm2(e), 0, 1), e))
+			d = math32.Min(d, ms2.Norm2(b))
+			// winding number from http://geomalgorithms.com/a03-_inclusion.html
+			isLeft := e.X*w.Y - e.Y*w.X
+			if v1.Y <= p.Y {
+				if v2.Y > p.Y && isLeft > 0 {
+					wn++
+					odd = !odd
+				}
+			} else if v2.Y <= p.Y && isLeft < 0 {
+				wn--
+				odd = !odd
+			}
+		}
+		inside := odd
+		if nonzero {
+			inside = wn != 0
+		}
+		if inside {
+			dist[i] = -math32.Sqrt(d)
+		} else {
+			dist[i] = math32.Sqrt(d)
+		}
+	}
+	return nil
+}
+
 // Evaluate implements [gleval.SDF2].
 func (u *OpUnion2D) Evaluate(pos []ms2.Vec, dist []float32, userData any) error {
 	// Same algorithm as UnionOp.Evaluate. see that method for commentary.
@@ -1272,3 +1430,105 @@ func (e *twist) Evaluate(pos []ms3.Vec, dist []float32, userData any) error {
 	}
 	return sdf.Evaluate(transformed, dist, userData)
 }
+
+func (g *gridSDF3) Evaluate(pos []ms3.Vec, dist []float32, userData any) error {
+	bb := g.bb
+	scale := ms3.DivElem(ms3.Vec{X: float32(g.nx - 1), Y: float32(g.ny - 1), Z: float32(g.nz - 1)}, bb.Size())
+	sy, sz := g.nx, g.nx*g.ny
+	for i, p := range pos {
+		q := ms3.ClampElem(p, bb.Min, bb.Max)
+		c := ms3.MulElem(ms3.Sub(q, bb.Min), scale)
+		ix, fx := gridCell(c.X, g.nx)
+		iy, fy := gridCell(c.Y, g.ny)
+		iz, fz := gridCell(c.Z, g.nz)
+		var d float32
+		if g.interp == GridCubic {
+			wx, wy, wz := catmullRom(fx), catmullRom(fy), catmullRom(fz)
+			for k := 0; k < 4; k++ {
+				kk := min(max(iz+k-1, 0), g.nz-1)
+				for j := 0; j < 4; j++ {
+					row := g.data[sy*min(max(iy+j-1, 0), g.ny-1)+sz*kk:]
+					w := wy[j] * wz[k]
+					for ii := 0; ii < 4; ii++ {
+						d += wx[ii] * w * row[min(max(ix+ii-1, 0), g.nx-1)]
+					}
+				}
+			}
+		} else {
+			c := ix + sy*iy + sz*iz
+			d00 := mixf(g.data[c], g.data[c+1], fx)
+			d10 := mixf(g.data[c+sy], g.data[c+sy+1], fx)
+			d01 := mixf(g.data[c+sz], g.data[c+sz+1], fx)
+			d11 := mixf(g.data[c+sy+sz], g.data[c+sy+sz+1], fx)
+			d = mixf(mixf(d00, d10, fy), mixf(d01, d11, fy), fz)
+		}
+		dist[i] = gridOutside(d, ms3.Norm(ms3.Sub(p, q)))
+	}
+	return nil
+}
+
+func (g *gridSDF2) Evaluate(pos []ms2.Vec, dist []float32, userData any) error {
+	bb := g.bb
+	scale := ms2.DivElem(ms2.Vec{X: float32(g.nx - 1), Y: float32(g.ny - 1)}, bb.Size())
+	for i, p := range pos {
+		q := ms2.ClampElem(p, bb.Min, bb.Max)
+		c := ms2.MulElem(ms2.Sub(q, bb.Min), scale)
+		ix, fx := gridCell(c.X, g.nx)
+		iy, fy := gridCell(c.Y, g.ny)
+		var d float32
+		if g.interp == GridCubic {
+			wx, wy := catmullRom(fx), catmullRom(fy)
+			for j := 0; j < 4; j++ {
+				row := g.data[g.nx*min(max(iy+j-1, 0), g.ny-1):]
+				for ii := 0; ii < 4; ii++ {
+					d += wx[ii] * wy[j] * row[min(max(ix+ii-1, 0), g.nx-1)]
+				}
+			}
+		} else {
+			c := ix + g.nx*iy
+			d = mixf(mixf(g.data[c], g.data[c+1], fx), mixf(g.data[c+g.nx], g.data[c+g.nx+1], fx), fy)
+		}
+		dist[i] = gridOutside(d, ms2.Norm(ms2.Sub(p, q)))
+	}
+	return nil
+}
+
+// gridCell returns the index of the grid cell containing grid coordinate c of an axis with n samples
+// and the fraction of c within the cell.
+func gridCell(c float32, n int) (int, float32) {
+	i := min(int(c), n-2)
+	return i, c - float32(i)
+}
+
+// gridOutside returns the distance of a point at distance out from the grid bounds given the interpolated
+// distance d at the closest point of the bounds. The surface lies within the bounds so the distance is at
+// least the hypotenuse of d and out.
+func gridOutside(d, out float32) float32 {
+	if out == 0 {
+		return d
+	} else if d <= 0 {
+		return out
+	}
+	return math32.Hypot(d, out)
+}
+
+func (hm *heightmap) Evaluate(pos []ms3.Vec, dist []float32, userData any) error {
+	bb := hm.Bounds()
+	hd := hm.halfDims()
+	maxCell := ms2.Vec{X: float32(hm.nx - 1), Y: float32(hm.ny - 1)}
+	for i, p := range pos {
+		q := ms3.ClampElem(p, bb.Min, bb.Max)
+		e := ms3.Sub(ms3.AbsElem(ms3.Sub(q, ms3.Vec{Z: hd.Z})), hd)
+		g := ms2.Vec{X: (q.X+hd.X)/hm.pitch - 0.5, Y: (q.Y+hd.Y)/hm.pitch - 0.5}
+		g = ms2.ClampElem(g, ms2.Vec{}, maxCell)
+		ix, fx := gridCell(g.X, hm.nx)
+		iy, fy := gridCell(g.Y, hm.ny)
+		c := ix + hm.nx*iy
+		h := mixf(mixf(hm.heights[c], hm.heights[c+1], fx), mixf(hm.heights[c+hm.nx], hm.heights[c+hm.nx+1], fx), fy)
+		top := hm.base + hm.height*h
+		d := gridOutside(maxf(e.Max(), (q.Z-top)*hm.invL), ms3.Norm(ms3.Sub(p, q)))
+		// The relief lies below the top surface extended beyond bounds.
+		dist[i] = maxf(d, (p.Z-top)*hm.invL)
+	}
+	return nil
+}
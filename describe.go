@@ -0,0 +1,315 @@
+package gsdf
+
+import (
+	"github.com/chewxy/math32"
+	"github.com/soypat/gsdf/glbuild"
+)
+
+// This file implements [glbuild.Describer] for all shapes in this package.
+// Describe returns the name of the Builder method which creates an equivalent
+// shape along with the arguments to pass to it, in order.
+
+func init() {
+	// 3D primitives.
+	RegisterShape("NewSphere", (*Builder).NewSphere, "r")
+	RegisterShape("NewBox", (*Builder).NewBox, "x", "y", "z", "round")
+	RegisterShape("NewCylinder", (*Builder).NewCylinder, "r", "h", "rounding")
+	RegisterShape("NewHexagonalPrism", (*Builder).NewHexagonalPrism, "face2Face", "h")
+	RegisterShape("NewTorus", (*Builder).NewTorus, "greaterRadius", "lesserRadius")
+	RegisterShape("NewBoxFrame", (*Builder).NewBoxFrame, "dimX", "dimY", "dimZ", "e")
+	RegisterShape("NewGridSDF3", (*Builder).NewGridSDF3, "data", "nx", "ny", "nz", "bounds", "interp")
+	RegisterShape("NewHeightmap", (*Builder).NewHeightmap, "heights", "nx", "ny", "pitch", "base", "height")
+	// 2D primitives.
+	RegisterShape("NewLine2D", (*Builder).NewLine2D, "x0", "y0", "x1", "y1", "width")
+	RegisterShape("NewLines2D", (*Builder).NewLines2D, "segments", "width")
+	RegisterShape("NewArc", (*Builder).NewArc, "radius", "arcAngle", "thick")
+	RegisterShape("NewCircle", (*Builder).NewCircle, "radius")
+	RegisterShape("NewEquilateralTriangle", (*Builder).NewEquilateralTriangle, "triangleHeight")
+	RegisterShape("NewRectangle", (*Builder).NewRectangle, "x", "y")
+	RegisterShape("NewHexagon", (*Builder).NewHexagon, "side")
+	RegisterShape("NewOctagon", (*Builder).NewOctagon, "constrain")
+	RegisterShape("NewEllipse", (*Builder).NewEllipse, "a", "b")
+	RegisterShape("NewPolygon", (*Builder).NewPolygon, "vertices")
+	RegisterShape("NewPolygonSet", (*Builder).NewPolygonSet, "contours", "fillRule")
+	RegisterShape("NewPath2D", (*Builder).NewPath2D, "path")
+	RegisterShape("NewDiamond2D", (*Builder).NewDiamond2D, "x_width", "y_height")
+	RegisterShape("NewRoundedX", (*Builder).NewRoundedX, "width", "thick")
+	RegisterShape("NewQuadraticBezier2D", (*Builder).NewQuadraticBezier2D, "a", "b", "c", "thick")
+	RegisterShape("NewGridSDF2", (*Builder).NewGridSDF2, "data", "nx", "ny", "bounds", "interp")
+	// 3D operations.
+	RegisterShape("Union", (*Builder).Union, "shaders")
+	RegisterShape("Difference", (*Builder).Difference, "a", "b")
+	RegisterShape("Intersection", (*Builder).Intersection, "a", "b")
+	RegisterShape("Xor", (*Builder).Xor, "s1", "s2")
+	RegisterShape("Scale", (*Builder).Scale, "s", "scaleFactor")
+	RegisterShape("Symmetry", (*Builder).Symmetry, "s", "mirrorX", "mirrorY", "mirrorZ")
+	RegisterShape("Transform", (*Builder).Transform, "s", "m")
+	RegisterShape("Translate", (*Builder).Translate, "s", "dirX", "dirY", "dirZ")
+	RegisterShape("Offset", (*Builder).Offset, "s", "sdfAdd")
+	RegisterShape("Array", (*Builder).Array, "s", "spacingX", "spacingY", "spacingZ", "nx", "ny", "nz")
+	RegisterShape("SmoothUnion", (*Builder).SmoothUnion, "k", "s1", "s2")
+	RegisterShape("SmoothDifference", (*Builder).SmoothDifference, "k", "s1", "s2")
+	RegisterShape("SmoothIntersect", (*Builder).SmoothIntersect, "k", "s1", "s2")
+	RegisterShape("Elongate", (*Builder).Elongate, "s", "dirX", "dirY", "dirZ")
+	RegisterShape("Shell", (*Builder).Shell, "s", "thickness")
+	RegisterShape("CircularArray", (*Builder).CircularArray, "s", "numInstances", "circleDiv")
+	RegisterShape("Twist", (*Builder).Twist, "s", "k")
+	// 2D operations.
+	RegisterShape("Extrude", (*Builder).Extrude, "s", "h")
+	RegisterShape("Revolve", (*Builder).Revolve, "s", "axisOffset")
+	RegisterShape("Union2D", (*Builder).Union2D, "shaders")
+	RegisterShape("Difference2D", (*Builder).Difference2D, "a", "b")
+	RegisterShape("Intersection2D", (*Builder).Intersection2D, "a", "b")
+	RegisterShape("Xor2D", (*Builder).Xor2D, "s1", "s2")
+	RegisterShape("Array2D", (*Builder).Array2D, "s", "spacingX", "spacingY", "nx", "ny")
+	RegisterShape("Offset2D", (*Builder).Offset2D, "s", "sdfAdd")
+	RegisterShape("Translate2D", (*Builder).Translate2D, "s", "dirX", "dirY")
+	RegisterShape("Rotate2D", (*Builder).Rotate2D, "s", "theta")
+	RegisterShape("Symmetry2D", (*Builder).Symmetry2D, "s", "mirrorX", "mirrorY")
+	RegisterShape("Annulus", (*Builder).Annulus, "s", "sub")
+	RegisterShape("CircularArray2D", (*Builder).CircularArray2D, "s", "numInstances", "circleDiv")
+	RegisterShape("Scale2D", (*Builder).Scale2D, "s", "scale")
+	RegisterShape("TranslateMulti2D", (*Builder).TranslateMulti2D, "s", "displacements")
+	RegisterShape("Elongate2D", (*Builder).Elongate2D, "s", "dirX", "dirY")
+}
+
+func params(nameValues ...any) []glbuild.Param {
+	p := make([]glbuild.Param, len(nameValues)/2)
+	for i := range p {
+		p[i] = glbuild.Param{Name: nameValues[2*i].(string), Value: nameValues[2*i+1]}
+	}
+	return p
+}
+
+func (s *sphere) Describe() (string, []glbuild.Param) {
+	return "NewSphere", params("r", s.r)
+}
+
+func (s *box) Describe() (string, []glbuild.Param) {
+	return "NewBox", params("x", s.dims.X, "y", s.dims.Y, "z", s.dims.Z, "round", s.round)
+}
+
+func (c *cylinder) Describe() (string, []glbuild.Param) {
+	return "NewCylinder", params("r", c.r, "h", c.h, "rounding", c.round)
+}
+
+func (s *hex) Describe() (string, []glbuild.Param) {
+	return "NewHexagonalPrism", params("face2Face", s.side, "h", s.h)
+}
+
+func (s *torus) Describe() (string, []glbuild.Param) {
+	return "NewTorus", params("greaterRadius", s.rGreater, "lesserRadius", s.rLesser)
+}
+
+func (bf *boxframe) Describe() (string, []glbuild.Param) {
+	return "NewBoxFrame", params("dimX", bf.dims.X, "dimY", bf.dims.Y, "dimZ", bf.dims.Z, "e", 2*bf.e)
+}
+
+func (l *line2D) Describe() (string, []glbuild.Param) {
+	return "NewLine2D", params("x0", l.a.X, "y0", l.a.Y, "x1", l.b.X, "y1", l.b.Y, "width", l.width)
+}
+
+func (l *lines2D) Describe() (string, []glbuild.Param) {
+	return "NewLines2D", params("segments", l.points, "width", l.width)
+}
+
+func (a *arc2D) Describe() (string, []glbuild.Param) {
+	return "NewArc", params("radius", a.radius, "arcAngle", a.angle, "thick", a.thick)
+}
+
+func (c *circle2D) Describe() (string, []glbuild.Param) {
+	return "NewCircle", params("radius", c.r)
+}
+
+func (t *equilateralTri2d) Describe() (string, []glbuild.Param) {
+	return "NewEquilateralTriangle", params("triangleHeight", t.hTri)
+}
+
+func (c *rect2D) Describe() (string, []glbuild.Param) {
+	return "NewRectangle", params("x", c.d.X, "y", c.d.Y)
+}
+
+func (c *hex2D) Describe() (string, []glbuild.Param) {
+	return "NewHexagon", params("side", c.side)
+}
+
+func (c *oct2D) Describe() (string, []glbuild.Param) {
+	return "NewOctagon", params("constrain", c.c)
+}
+
+func (c *ellipse2D) Describe() (string, []glbuild.Param) {
+	return "NewEllipse", params("a", c.a, "b", c.b)
+}
+
+func (c *poly2D) Describe() (string, []glbuild.Param) {
+	return "NewPolygon", params("vertices", c.vert)
+}
+
+func (c *polySet) Describe() (string, []glbuild.Param) {
+	return "NewPolygonSet", params("contours", c.contours, "fillRule", c.rule)
+}
+
+func (c *path2D) Describe() (string, []glbuild.Param) {
+	if len(c.segs) == 0 {
+		return "NewPath2D", params("path", &Path2D{}) // Only built with FlagNoDimensionPanic.
+	}
+	end := c.segs[len(c.segs)-1].end()
+	return "NewPath2D", params("path", &Path2D{segs: c.segs, start: end, cur: end})
+}
+
+func (c *diamond) Describe() (string, []glbuild.Param) {
+	return "NewDiamond2D", params("x_width", c.d.X, "y_height", c.d.Y)
+}
+
+func (c *x2d) Describe() (string, []glbuild.Param) {
+	return "NewRoundedX", params("width", c.dim, "thick", c.thick)
+}
+
+func (c *quadbezier2d) Describe() (string, []glbuild.Param) {
+	return "NewQuadraticBezier2D", params("a", c.a, "b", c.b, "c", c.c, "thick", c.thick)
+}
+
+func (g *gridSDF3) Describe() (string, []glbuild.Param) {
+	return "NewGridSDF3", params("data", g.data, "nx", g.nx, "ny", g.ny, "nz", g.nz, "bounds", g.bb, "interp", g.interp)
+}
+
+func (hm *heightmap) Describe() (string, []glbuild.Param) {
+	return "NewHeightmap", params("heights", hm.heights, "nx", hm.nx, "ny", hm.ny, "pitch", hm.pitch, "base", hm.base, "height", hm.height)
+}
+
+func (g *gridSDF2) Describe() (string, []glbuild.Param) {
+	return "NewGridSDF2", params("data", g.data, "nx", g.nx, "ny", g.ny, "bounds", g.bb, "interp", g.interp)
+}
+
+func (u *OpUnion) Describe() (string, []glbuild.Param) {
+	return "Union", params("shaders", u.joined)
+}
+
+func (s *diff) Describe() (string, []glbuild.Param) {
+	return "Difference", params("a", s.s1, "b", s.s2)
+}
+
+func (s *intersect) Describe() (string, []glbuild.Param) {
+	return "Intersection", params("a", s.s1, "b", s.s2)
+}
+
+func (s *xor) Describe() (string, []glbuild.Param) {
+	return "Xor", params("s1", s.s1, "s2", s.s2)
+}
+
+func (s *scale) Describe() (string, []glbuild.Param) {
+	return "Scale", params("s", s.s, "scaleFactor", s.scale)
+}
+
+func (s *symmetry) Describe() (string, []glbuild.Param) {
+	return "Symmetry", params("s", s.s, "mirrorX", s.xyz.X(), "mirrorY", s.xyz.Y(), "mirrorZ", s.xyz.Z())
+}
+
+func (s *transform) Describe() (string, []glbuild.Param) {
+	return "Transform", params("s", s.s, "m", s.t)
+}
+
+func (s *translate) Describe() (string, []glbuild.Param) {
+	return "Translate", params("s", s.s, "dirX", s.p.X, "dirY", s.p.Y, "dirZ", s.p.Z)
+}
+
+func (s *offset) Describe() (string, []glbuild.Param) {
+	return "Offset", params("s", s.s, "sdfAdd", s.off)
+}
+
+func (s *array) Describe() (string, []glbuild.Param) {
+	return "Array", params("s", s.s, "spacingX", s.d.X, "spacingY", s.d.Y, "spacingZ", s.d.Z, "nx", s.nx, "ny", s.ny, "nz", s.nz)
+}
+
+func (s *smoothUnion) Describe() (string, []glbuild.Param) {
+	return "SmoothUnion", params("k", s.k, "s1", s.s1, "s2", s.s2)
+}
+
+func (s *smoothDiff) Describe() (string, []glbuild.Param) {
+	return "SmoothDifference", params("k", s.k, "s1", s.s1, "s2", s.s2)
+}
+
+func (s *smoothIntersect) Describe() (string, []glbuild.Param) {
+	return "SmoothIntersect", params("k", s.k, "s1", s.s1, "s2", s.s2)
+}
+
+func (s *elongate) Describe() (string, []glbuild.Param) {
+	return "Elongate", params("s", s.s, "dirX", s.h.X, "dirY", s.h.Y, "dirZ", s.h.Z)
+}
+
+func (s *shell) Describe() (string, []glbuild.Param) {
+	return "Shell", params("s", s.s, "thickness", s.thick)
+}
+
+func (ca *circarray) Describe() (string, []glbuild.Param) {
+	return "CircularArray", params("s", ca.s, "numInstances", ca.nInst, "circleDiv", ca.circleDiv)
+}
+
+func (s *twist) Describe() (string, []glbuild.Param) {
+	return "Twist", params("s", s.s, "k", s.k)
+}
+
+func (e *extrusion) Describe() (string, []glbuild.Param) {
+	return "Extrude", params("s", e.s, "h", e.h)
+}
+
+func (r *revolution) Describe() (string, []glbuild.Param) {
+	return "Revolve", params("s", r.s2d, "axisOffset", r.off)
+}
+
+func (u *OpUnion2D) Describe() (string, []glbuild.Param) {
+	return "Union2D", params("shaders", u.joined)
+}
+
+func (s *diff2D) Describe() (string, []glbuild.Param) {
+	return "Difference2D", params("a", s.s1, "b", s.s2)
+}
+
+func (s *intersect2D) Describe() (string, []glbuild.Param) {
+	return "Intersection2D", params("a", s.s1, "b", s.s2)
+}
+
+func (s *xor2D) Describe() (string, []glbuild.Param) {
+	return "Xor2D", params("s1", s.s1, "s2", s.s2)
+}
+
+func (s *array2D) Describe() (string, []glbuild.Param) {
+	return "Array2D", params("s", s.s, "spacingX", s.d.X, "spacingY", s.d.Y, "nx", s.nx, "ny", s.ny)
+}
+
+func (s *offset2D) Describe() (string, []glbuild.Param) {
+	return "Offset2D", params("s", s.s, "sdfAdd", s.f)
+}
+
+func (s *translate2D) Describe() (string, []glbuild.Param) {
+	return "Translate2D", params("s", s.s, "dirX", s.p.X, "dirY", s.p.Y)
+}
+
+func (r *rotation2D) Describe() (string, []glbuild.Param) {
+	m := r.t.Array() // Row major rotation matrix {cos, -sin, sin, cos}.
+	return "Rotate2D", params("s", r.s, "theta", math32.Atan2(m[2], m[0]))
+}
+
+func (s *symmetry2D) Describe() (string, []glbuild.Param) {
+	return "Symmetry2D", params("s", s.s, "mirrorX", s.xy.X(), "mirrorY", s.xy.Y())
+}
+
+func (a *annulus2D) Describe() (string, []glbuild.Param) {
+	return "Annulus", params("s", a.s, "sub", a.r)
+}
+
+func (ca *circarray2D) Describe() (string, []glbuild.Param) {
+	return "CircularArray2D", params("s", ca.s, "numInstances", ca.nInst, "circleDiv", ca.circleDiv)
+}
+
+func (s *scale2D) Describe() (string, []glbuild.Param) {
+	return "Scale2D", params("s", s.s, "scale", s.scale)
+}
+
+func (s *translateMulti2D) Describe() (string, []glbuild.Param) {
+	return "TranslateMulti2D", params("s", s.s, "displacements", s.displacements)
+}
+
+func (s *elongate2D) Describe() (string, []glbuild.Param) {
+	return "Elongate2D", params("s", s.s, "dirX", s.h.X, "dirY", s.h.Y)
+}
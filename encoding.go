@@ -0,0 +1,344 @@
+package gsdf
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+
+	"github.com/soypat/geometry/ms2"
+	"github.com/soypat/geometry/ms3"
+	"github.com/soypat/gsdf/glbuild"
+)
+
+// jsonVersion is the version of the document written by [EncodeJSON].
+// It must be incremented on changes which break decoding of existing documents.
+const jsonVersion = 1
+
+var (
+	shaderType   = reflect.TypeOf((*glbuild.Shader)(nil)).Elem()
+	shader3DType = reflect.TypeOf((*glbuild.Shader3D)(nil)).Elem()
+	shader2DType = reflect.TypeOf((*glbuild.Shader2D)(nil)).Elem()
+	builderType  = reflect.TypeOf((*Builder)(nil))
+	errorType    = reflect.TypeOf((*error)(nil)).Elem()
+	mat4Type     = reflect.TypeOf(ms3.Mat4{})
+	pathType     = reflect.TypeOf((*Path2D)(nil))
+)
+
+type shapeCtor struct {
+	fn     reflect.Value
+	params []string
+}
+
+var shapeRegistry = map[string]shapeCtor{}
+
+// RegisterShape registers a shape constructor under name so that shapes which describe
+// themselves with that name (see [glbuild.Describer]) can be decoded by [Builder.DecodeJSON].
+// ctor must be a function which receives a *Builder followed by one argument per paramNames element
+// and returns a [glbuild.Shader3D] or [glbuild.Shader2D], optionally followed by an error.
+// Builder methods are registered as method expressions, i.e: (*Builder).NewSphere.
+//
+// RegisterShape is meant to be called from init functions of packages which define their own shapes.
+// It panics if ctor is not a valid constructor or if name is already registered.
+// Names of shapes outside of this package should be qualified with the package name, i.e: "threads.screw".
+func RegisterShape(name string, ctor any, paramNames ...string) {
+	fn := reflect.ValueOf(ctor)
+	if fn.Kind() != reflect.Func {
+		panic("shape constructor must be a function")
+	}
+	ft := fn.Type()
+	nout := ft.NumOut()
+	switch {
+	case name == "":
+		panic("empty shape name")
+	case shapeRegistry[name].fn.IsValid():
+		panic("shape " + name + " already registered")
+	case ft.NumIn() != len(paramNames)+1 || ft.In(0) != builderType:
+		panic("shape constructor " + name + " must receive *Builder followed by named parameters")
+	case nout < 1 || nout > 2 || !ft.Out(0).Implements(shaderType) || (nout == 2 && ft.Out(1) != errorType):
+		panic("shape constructor " + name + " must return a shader and optionally an error")
+	}
+	shapeRegistry[name] = shapeCtor{fn: fn, params: paramNames}
+}
+
+// EncodeJSON writes a versioned JSON document describing the shape tree rooted at s to w.
+// Each node is stored as its constructor name and named parameters; child shapes are nested in
+// the parameters. All nodes in the tree must implement [glbuild.Describer], which all shapes in this package do.
+// Shapes which appear more than once in the tree are written once per appearance.
+func EncodeJSON(w io.Writer, s glbuild.Shader) error {
+	node, err := encodeNode(s)
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(w).Encode(jsonDocument{Version: jsonVersion, Shape: node})
+}
+
+// jsonDocument is the top level object of an encoded shape tree.
+type jsonDocument struct {
+	Version int             `json:"version"`
+	Shape   json.RawMessage `json:"shape"`
+}
+
+// DecodeJSON reads a document written by [EncodeJSON] and rebuilds the shape tree with bld.
+// The returned shape is either a [glbuild.Shader3D] or a [glbuild.Shader2D]. Invalid shape parameters
+// are returned as errors even if bld is configured to panic on them. Errors accumulated by bld before the call
+// are not returned.
+func (bld *Builder) DecodeJSON(r io.Reader) (s glbuild.Shader, err error) {
+	var doc jsonDocument
+	err = json.NewDecoder(r).Decode(&doc)
+	if err != nil {
+		return nil, err
+	} else if doc.Version != jsonVersion {
+		return nil, fmt.Errorf("unsupported shape document version %d", doc.Version)
+	} else if len(doc.Shape) == 0 {
+		return nil, errors.New("shape document missing shape")
+	}
+	defer func() {
+		if a := recover(); a != nil {
+			s = nil
+			err = fmt.Errorf("building decoded shape: %v", a)
+		}
+	}()
+	nerrs := len(bld.accumErrs)
+	s, err = bld.decodeNode(doc.Shape)
+	if err != nil {
+		return nil, err
+	} else if len(bld.accumErrs) > nerrs {
+		return s, errors.Join(bld.accumErrs[nerrs:]...)
+	}
+	return s, nil
+}
+
+func encodeNode(s glbuild.Shader) (json.RawMessage, error) {
+	if s == nil {
+		return nil, errors.New("nil shape")
+	}
+	fn, params := glbuild.Describe(s)
+	if fn == "" {
+		return nil, fmt.Errorf("shape %T does not describe itself", s)
+	}
+	var buf bytes.Buffer
+	buf.WriteString(`{"type":`)
+	name, _ := json.Marshal(fn)
+	buf.Write(name)
+	buf.WriteString(`,"params":{`)
+	for i, p := range params {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ = json.Marshal(p.Name)
+		buf.Write(name)
+		buf.WriteByte(':')
+		v, err := encodeParam(p.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: parameter %q: %w", fn, p.Name, err)
+		}
+		buf.Write(v)
+	}
+	buf.WriteString("}}")
+	return buf.Bytes(), nil
+}
+
+func encodeParam(v any) (json.RawMessage, error) {
+	switch v := v.(type) {
+	case glbuild.Shader:
+		return encodeNode(v)
+	case []glbuild.Shader3D:
+		return encodeNodes(v)
+	case []glbuild.Shader2D:
+		return encodeNodes(v)
+	case ms3.Mat4:
+		return json.Marshal(v.Array())
+	case *Path2D:
+		return json.Marshal(encodePath(v))
+	}
+	return json.Marshal(v)
+}
+
+func encodeNodes[T glbuild.Shader](shapes []T) (json.RawMessage, error) {
+	nodes := make([]json.RawMessage, len(shapes))
+	for i, s := range shapes {
+		node, err := encodeNode(s)
+		if err != nil {
+			return nil, err
+		}
+		nodes[i] = node
+	}
+	return json.Marshal(nodes)
+}
+
+func (bld *Builder) decodeNode(data json.RawMessage) (glbuild.Shader, error) {
+	var node struct {
+		Type   string                     `json:"type"`
+		Params map[string]json.RawMessage `json:"params"`
+	}
+	err := json.Unmarshal(data, &node)
+	if err != nil {
+		return nil, err
+	}
+	ctor, ok := shapeRegistry[node.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown shape type %q", node.Type)
+	}
+	ft := ctor.fn.Type()
+	args := make([]reflect.Value, len(ctor.params)+1)
+	args[0] = reflect.ValueOf(bld)
+	for i, name := range ctor.params {
+		raw, ok := node.Params[name]
+		if !ok {
+			return nil, fmt.Errorf("%s: missing parameter %q", node.Type, name)
+		}
+		args[i+1], err = bld.decodeParam(raw, ft.In(i+1))
+		if err != nil {
+			return nil, fmt.Errorf("%s: parameter %q: %w", node.Type, name, err)
+		}
+	}
+	if len(node.Params) != len(ctor.params) {
+		for name := range node.Params {
+			if !slices.Contains(ctor.params, name) {
+				return nil, fmt.Errorf("%s: unknown parameter %q", node.Type, name)
+			}
+		}
+	}
+	return ctor.call(node.Type, args)
+}
+
+// call calls the constructor with args, the first of which is the Builder.
+func (ctor shapeCtor) call(name string, args []reflect.Value) (glbuild.Shader, error) {
+	var out []reflect.Value
+	if ctor.fn.Type().IsVariadic() {
+		out = ctor.fn.CallSlice(args)
+	} else {
+		out = ctor.fn.Call(args)
+	}
+	if len(out) == 2 && !out[1].IsNil() {
+		return nil, fmt.Errorf("%s: %w", name, out[1].Interface().(error))
+	}
+	s, _ := out[0].Interface().(glbuild.Shader)
+	if s == nil {
+		return nil, fmt.Errorf("%s: constructor returned nil shape", name)
+	}
+	return s, nil
+}
+
+func (bld *Builder) decodeParam(data json.RawMessage, typ reflect.Type) (reflect.Value, error) {
+	v := reflect.New(typ).Elem()
+	switch {
+	case typ == shader3DType || typ == shader2DType:
+		s, err := bld.decodeNode(data)
+		if err != nil {
+			return v, err
+		} else if !reflect.TypeOf(s).Implements(typ) {
+			return v, fmt.Errorf("shape %T is not a %s", s, typ)
+		}
+		v.Set(reflect.ValueOf(s))
+
+	case typ.Kind() == reflect.Slice && (typ.Elem() == shader3DType || typ.Elem() == shader2DType):
+		var nodes []json.RawMessage
+		err := json.Unmarshal(data, &nodes)
+		if err != nil {
+			return v, err
+		}
+		v.Set(reflect.MakeSlice(typ, len(nodes), len(nodes)))
+		for i := range nodes {
+			elem, err := bld.decodeParam(nodes[i], typ.Elem())
+			if err != nil {
+				return v, err
+			}
+			v.Index(i).Set(elem)
+		}
+
+	case typ == mat4Type:
+		var rowmajor [16]float32
+		err := json.Unmarshal(data, &rowmajor)
+		if err != nil {
+			return v, err
+		}
+		v.Set(reflect.ValueOf(ms3.NewMat4(rowmajor[:])))
+
+	case typ == pathType:
+		var segs []jsonPathSeg
+		err := json.Unmarshal(data, &segs)
+		if err != nil {
+			return v, err
+		}
+		path, err := decodePath(segs)
+		if err != nil {
+			return v, err
+		}
+		v.Set(reflect.ValueOf(path))
+
+	default:
+		err := json.Unmarshal(data, v.Addr().Interface())
+		if err != nil {
+			return v, err
+		}
+	}
+	return v, nil
+}
+
+// jsonPathSeg is the JSON representation of a [Path2D] segment.
+type jsonPathSeg struct {
+	Kind string `json:"kind"`
+	// P contains start, control and end points of lines and beziers.
+	// Arcs store start point, end point and center.
+	P []ms2.Vec `json:"p"`
+	// Arc contains arc radius, start angle and signed angle spanned.
+	Arc []float32 `json:"arc,omitempty"`
+}
+
+var pathSegKinds = [...]string{pathLine: "line", pathQuad: "quad", pathCubic: "cubic", pathArc: "arc"}
+
+// numPoints returns the number of points stored by a segment of the kind.
+func (k pathSegKind) numPoints() int {
+	switch k {
+	case pathQuad, pathArc:
+		return 3
+	case pathCubic:
+		return 4
+	}
+	return 2
+}
+
+// encodePath returns the segments of an already closed path.
+func encodePath(p *Path2D) []jsonPathSeg {
+	segs := make([]jsonPathSeg, len(p.segs))
+	for i, seg := range p.segs {
+		segs[i] = jsonPathSeg{
+			Kind: pathSegKinds[seg.kind],
+			P:    append([]ms2.Vec{}, seg.p[:seg.kind.numPoints()]...),
+		}
+		if seg.kind == pathArc {
+			segs[i].Arc = []float32{seg.r, seg.a0, seg.da}
+		}
+	}
+	return segs
+}
+
+func decodePath(segs []jsonPathSeg) (*Path2D, error) {
+	var path Path2D
+	for i, js := range segs {
+		var seg pathSeg
+		kind := slices.Index(pathSegKinds[:], js.Kind)
+		seg.kind = pathSegKind(kind)
+		if kind < 0 {
+			return nil, fmt.Errorf("segment %d: unknown kind %q", i, js.Kind)
+		} else if len(js.P) != seg.kind.numPoints() {
+			return nil, fmt.Errorf("segment %d: %s needs %d points", i, js.Kind, seg.kind.numPoints())
+		}
+		copy(seg.p[:], js.P)
+		if seg.kind == pathArc {
+			if len(js.Arc) != 3 {
+				return nil, fmt.Errorf("segment %d: arc needs radius, start angle and angle spanned", i)
+			}
+			seg.r, seg.a0, seg.da = js.Arc[0], js.Arc[1], js.Arc[2]
+		}
+		path.addSeg(seg)
+	}
+	// Stored paths are closed, avoid closing the last subpath again on build.
+	path.start = path.cur
+	return &path, nil
+}
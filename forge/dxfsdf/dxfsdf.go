@@ -0,0 +1,664 @@
+package dxfsdf
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	math "github.com/chewxy/math32"
+	"github.com/soypat/geometry/ms2"
+	"github.com/soypat/gsdf"
+	"github.com/soypat/gsdf/glbuild"
+)
+
+var defaultBuilder = &gsdf.Builder{}
+
+// Config configures DXF decoding.
+type Config struct {
+	Builder *gsdf.Builder
+	// Layers selects the layers from which entities are read. If empty entities on all layers are read.
+	Layers []string
+	// Tolerance is the maximum deviation permitted when flattening arcs and splines into polygons and the
+	// maximum gap between entity endpoints for them to be joined, in output units. If zero 1/1000th of the drawing size is used.
+	Tolerance float32
+	// Unit is the output length unit expressed in millimeters, i.e: 1 for millimeters and 25.4 for inches.
+	// If zero or if the DXF file does not specify $INSUNITS the coordinates are not scaled.
+	Unit float32
+}
+
+// Decode reads the LINE, ARC, CIRCLE, LWPOLYLINE and SPLINE entities of a DXF file's ENTITIES section and
+// assembles them into closed loops. Loops are classified as outer contours or holes by their nesting depth
+// and the resulting 2D shape is returned. Circles are built with [gsdf.Builder.NewCircle], all other loops
+// are built with [gsdf.Builder.NewPolygon] with curves flattened to within the configured tolerance.
+// ARC, CIRCLE and LWPOLYLINE entities with a negative extrusion direction, as written by CAD programs for
+// mirrored geometry, are mirrored into world coordinates.
+func Decode(r io.Reader, cfg Config) (glbuild.Shader2D, error) {
+	if cfg.Tolerance < 0 || cfg.Unit < 0 {
+		return nil, errors.New("negative tolerance or unit")
+	}
+	bld := cfg.Builder
+	if bld == nil {
+		bld = defaultBuilder
+	}
+	dwg, err := parse(r)
+	if err != nil {
+		return nil, err
+	}
+	scale := float32(1)
+	if mm := insunitsMillimeters(dwg.insunits); mm != 0 && cfg.Unit != 0 {
+		scale = mm / cfg.Unit
+	}
+	var ents []entity
+	for _, ent := range dwg.entities {
+		if len(cfg.Layers) == 0 || contains(cfg.Layers, ent.layer) {
+			ent.toWorld()
+			ents = append(ents, ent)
+		}
+	}
+	if len(ents) == 0 {
+		return nil, errors.New("no entities found")
+	}
+	tol := cfg.Tolerance
+	if tol == 0 {
+		bb := ents[0].bounds()
+		for _, ent := range ents[1:] {
+			bb = bb.Union(ent.bounds())
+		}
+		tol = 1e-3 * scale * ms2.Norm(bb.Size())
+		if tol == 0 {
+			return nil, errors.New("drawing has zero size")
+		}
+	}
+	var loops []loop
+	var edges []edge
+	for _, ent := range ents {
+		ent.scale(scale)
+		if ent.kind == "CIRCLE" {
+			loops = append(loops, loop{center: ent.pt(10), radius: ent.float(40)})
+			continue
+		}
+		e, err := ent.edge(tol)
+		if err != nil {
+			return nil, fmt.Errorf("%s entity on layer %q: %w", ent.kind, ent.layer, err)
+		}
+		if len(e.pts) < 2 {
+			continue
+		}
+		edges = append(edges, e)
+	}
+	chained, err := chainEdges(edges, tol)
+	if err != nil {
+		return nil, err
+	}
+	loops = append(loops, chained...)
+	return buildLoops(bld, loops)
+}
+
+// loop is a closed contour. It is a circle if radius is nonzero, else a polygon.
+type loop struct {
+	poly   []ms2.Vec
+	center ms2.Vec
+	radius float32
+}
+
+// sample returns a point on the loop.
+func (l *loop) sample() ms2.Vec {
+	if l.radius > 0 {
+		return ms2.Add(l.center, ms2.Vec{X: l.radius})
+	}
+	return l.poly[0]
+}
+
+// contains reports whether p lies inside the loop using the even-odd rule.
+func (l *loop) contains(p ms2.Vec) bool {
+	if l.radius > 0 {
+		return ms2.Norm(ms2.Sub(p, l.center)) < l.radius
+	}
+	inside := false
+	j := len(l.poly) - 1
+	for i, v1 := range l.poly {
+		v0 := l.poly[j]
+		if (v1.Y > p.Y) != (v0.Y > p.Y) && p.X < (v0.X-v1.X)*(p.Y-v1.Y)/(v0.Y-v1.Y)+v1.X {
+			inside = !inside
+		}
+		j = i
+	}
+	return inside
+}
+
+func (l *loop) shape(bld *gsdf.Builder) glbuild.Shader2D {
+	if l.radius > 0 {
+		return bld.Translate2D(bld.NewCircle(l.radius), l.center.X, l.center.Y)
+	}
+	return bld.NewPolygon(l.poly)
+}
+
+// buildLoops classifies loops as outer contours or holes by nesting depth and joins them into a single shape.
+func buildLoops(bld *gsdf.Builder, loops []loop) (glbuild.Shader2D, error) {
+	if len(loops) == 0 {
+		return nil, errors.New("no closed loops found")
+	}
+	depths := make([]int, len(loops))
+	for i := range loops {
+		p := loops[i].sample()
+		for j := range loops {
+			if i != j && loops[j].contains(p) {
+				depths[i]++
+			}
+		}
+	}
+	order := make([]int, len(loops))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return depths[order[i]] < depths[order[j]] })
+
+	var shape glbuild.Shader2D
+	for i := 0; i < len(order); {
+		// Loops of equal depth do not overlap so they are joined before being applied to the shape.
+		var group []glbuild.Shader2D
+		depth := depths[order[i]]
+		for ; i < len(order) && depths[order[i]] == depth; i++ {
+			group = append(group, loops[order[i]].shape(bld))
+		}
+		joined := group[0]
+		if len(group) > 1 {
+			joined = bld.Union2D(group...)
+		}
+		switch {
+		case depth%2 == 1:
+			if shape != nil {
+				shape = bld.Difference2D(shape, joined) // Holes.
+			}
+		case shape == nil:
+			shape = joined
+		default:
+			shape = bld.Union2D(shape, joined) // Islands inside holes.
+		}
+	}
+	return shape, bld.Err()
+}
+
+// edge is an open polyline obtained from a single entity.
+type edge struct {
+	pts []ms2.Vec
+	// arc center and radius if edge is a circular arc, used to detect full circles built from arcs.
+	center ms2.Vec
+	radius float32
+}
+
+func (e *edge) start() ms2.Vec { return e.pts[0] }
+func (e *edge) end() ms2.Vec   { return e.pts[len(e.pts)-1] }
+
+func (e *edge) reverse() {
+	for i, j := 0, len(e.pts)-1; i < j; i, j = i+1, j-1 {
+		e.pts[i], e.pts[j] = e.pts[j], e.pts[i]
+	}
+}
+
+// chainEdges joins edges with coincident endpoints into closed loops.
+func chainEdges(edges []edge, tol float32) ([]loop, error) {
+	near := func(a, b ms2.Vec) bool { return ms2.Norm(ms2.Sub(a, b)) <= tol }
+	used := make([]bool, len(edges))
+	var loops []loop
+	for i := range edges {
+		if used[i] {
+			continue
+		}
+		used[i] = true
+		chain := []*edge{&edges[i]}
+		start := edges[i].start()
+		end := edges[i].end()
+		for !near(start, end) {
+			found := false
+			for j := range edges {
+				if used[j] {
+					continue
+				}
+				if near(edges[j].end(), end) {
+					edges[j].reverse()
+				} else if !near(edges[j].start(), end) {
+					continue
+				}
+				used[j] = true
+				chain = append(chain, &edges[j])
+				end = edges[j].end()
+				found = true
+				break
+			}
+			if !found {
+				return nil, fmt.Errorf("open contour from %v to %v", start, end)
+			}
+		}
+		l := makeLoop(chain, tol)
+		if l.radius > 0 || len(l.poly) >= 3 {
+			loops = append(loops, l)
+		}
+	}
+	return loops, nil
+}
+
+// makeLoop joins a closed chain of edges into a loop. Chains of arcs of the same circle result in a circle.
+func makeLoop(chain []*edge, tol float32) loop {
+	first := chain[0]
+	isCircle := first.radius > 0
+	for _, e := range chain[1:] {
+		isCircle = isCircle && math.Abs(e.radius-first.radius) <= tol && ms2.Norm(ms2.Sub(e.center, first.center)) <= tol
+	}
+	if isCircle {
+		return loop{center: first.center, radius: first.radius}
+	}
+	var poly []ms2.Vec
+	for _, e := range chain {
+		poly = append(poly, e.pts[:len(e.pts)-1]...) // Last point is first point of next edge.
+	}
+	// Remove consecutive duplicates which are rejected by polygon validation.
+	dedup := poly[:1]
+	for _, v := range poly[1:] {
+		if v != dedup[len(dedup)-1] {
+			dedup = append(dedup, v)
+		}
+	}
+	return loop{poly: dedup}
+}
+
+// drawing is the parsed contents of a DXF file relevant to 2D shapes.
+type drawing struct {
+	insunits int
+	entities []entity
+}
+
+// entity is a DXF entity with its group code/value pairs.
+type entity struct {
+	kind  string
+	layer string
+	codes []int
+	vals  []float32
+}
+
+func parse(r io.Reader) (drawing, error) {
+	var dwg drawing
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 4096), 1<<20)
+	var line int
+	next := func() (code int, value string, err error) {
+		if !sc.Scan() {
+			if sc.Err() != nil {
+				return 0, "", sc.Err()
+			}
+			return 0, "", io.ErrUnexpectedEOF
+		}
+		code, err = strconv.Atoi(strings.TrimSpace(sc.Text()))
+		if err != nil {
+			return 0, "", fmt.Errorf("line %d: invalid group code: %w", line+1, err)
+		}
+		if !sc.Scan() {
+			return 0, "", io.ErrUnexpectedEOF
+		}
+		line += 2
+		return code, strings.TrimSpace(sc.Text()), nil
+	}
+	var section, headerVar string
+	var current *entity
+	for {
+		code, value, err := next()
+		if err != nil {
+			return dwg, err
+		}
+		if code == 0 {
+			if current != nil {
+				dwg.entities = append(dwg.entities, *current)
+				current = nil
+			}
+			switch value {
+			case "EOF":
+				return dwg, nil
+			case "SECTION":
+				code, value, err = next()
+				if err != nil {
+					return dwg, err
+				} else if code != 2 {
+					return dwg, fmt.Errorf("line %d: expected section name", line)
+				}
+				section = value
+			case "ENDSEC":
+				section = ""
+			case "LINE", "ARC", "CIRCLE", "LWPOLYLINE", "SPLINE":
+				if section == "ENTITIES" {
+					current = &entity{kind: value, layer: "0"}
+				}
+			}
+			continue
+		}
+		switch {
+		case section == "HEADER" && code == 9:
+			headerVar = value
+		case section == "HEADER" && code == 70 && headerVar == "$INSUNITS":
+			dwg.insunits, err = strconv.Atoi(value)
+		case current != nil && code == 8:
+			current.layer = value
+		case current != nil && isNumericCode(code):
+			var v float64
+			v, err = strconv.ParseFloat(value, 32)
+			current.codes = append(current.codes, code)
+			current.vals = append(current.vals, float32(v))
+		}
+		if err != nil {
+			return dwg, fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+}
+
+// isNumericCode reports whether the group code holds a number relevant to supported entities.
+func isNumericCode(code int) bool {
+	return (code >= 10 && code <= 59) || (code >= 70 && code <= 99) || (code >= 210 && code <= 230)
+}
+
+// float returns the first value of the group code or 0 if not present.
+func (ent *entity) float(code int) float32 {
+	for i, c := range ent.codes {
+		if c == code {
+			return ent.vals[i]
+		}
+	}
+	return 0
+}
+
+func (ent *entity) pt(code int) ms2.Vec {
+	return ms2.Vec{X: ent.float(code), Y: ent.float(code + 10)}
+}
+
+// points returns all points formed by code pairs (code, code+10) in order of appearance.
+func (ent *entity) points(code int) (pts []ms2.Vec) {
+	for i, c := range ent.codes {
+		if c == code && i+1 < len(ent.codes) && ent.codes[i+1] == code+10 {
+			pts = append(pts, ms2.Vec{X: ent.vals[i], Y: ent.vals[i+1]})
+		}
+	}
+	return pts
+}
+
+func (ent *entity) all(code int) (vals []float32) {
+	for i, c := range ent.codes {
+		if c == code {
+			vals = append(vals, ent.vals[i])
+		}
+	}
+	return vals
+}
+
+// toWorld converts the coordinates of entities defined in their object coordinate system (OCS) to world
+// coordinates. Only extrusion directions along ±Z are supported, for which the arbitrary axis algorithm
+// yields an OCS with the X axis negated when the extrusion direction is -Z.
+func (ent *entity) toWorld() {
+	isOCS := ent.kind == "ARC" || ent.kind == "CIRCLE" || ent.kind == "LWPOLYLINE"
+	if !isOCS || ent.float(230) >= 0 {
+		return
+	}
+	start, end := -1, -1
+	for i, c := range ent.codes {
+		switch c {
+		case 10, 42:
+			// Mirroring X reverses the direction of bulges.
+			ent.vals[i] = -ent.vals[i]
+		case 50:
+			start = i
+		case 51:
+			end = i
+		}
+	}
+	if start >= 0 && end >= 0 {
+		// Mirrored counter-clockwise arcs are clockwise so start and end angles are swapped
+		// to keep arcs counter-clockwise.
+		ent.vals[start], ent.vals[end] = 180-ent.vals[end], 180-ent.vals[start]
+	}
+}
+
+// scale scales all length values of the entity.
+func (ent *entity) scale(f float32) {
+	for i, c := range ent.codes {
+		isCoord := c >= 10 && c < 40
+		isRadius := c == 40 && ent.kind != "SPLINE" // Spline knot values are not lengths.
+		if isCoord || isRadius {
+			ent.vals[i] *= f
+		}
+	}
+}
+
+// bounds returns the bounding box of the entity's defining points.
+func (ent *entity) bounds() ms2.Box {
+	p := ent.pt(10)
+	bb := ms2.Box{Min: p, Max: p}
+	for _, code := range [2]int{10, 11} {
+		for _, v := range ent.points(code) {
+			bb = bb.IncludePoint(v)
+		}
+	}
+	if ent.kind == "CIRCLE" || ent.kind == "ARC" {
+		r := ent.float(40)
+		bb = bb.Union(ms2.Box{Min: ms2.AddScalar(-r, p), Max: ms2.AddScalar(r, p)})
+	}
+	return bb
+}
+
+// edge converts a non-circle entity to a polyline edge.
+func (ent *entity) edge(tol float32) (edge, error) {
+	switch ent.kind {
+	case "LINE":
+		return edge{pts: []ms2.Vec{ent.pt(10), ent.pt(11)}}, nil
+
+	case "ARC":
+		center := ent.pt(10)
+		r := ent.float(40)
+		if r <= 0 {
+			return edge{}, errors.New("non-positive radius")
+		}
+		a0 := ent.float(50) * math.Pi / 180
+		a1 := ent.float(51) * math.Pi / 180
+		for a1 <= a0 {
+			a1 += 2 * math.Pi // Arcs are always counter-clockwise.
+		}
+		return edge{pts: appendArc(nil, center, r, a0, a1-a0, tol), center: center, radius: r}, nil
+
+	case "LWPOLYLINE":
+		closed := int(ent.float(70))&1 != 0
+		// Bulges follow the vertex they belong to.
+		var pts []ms2.Vec
+		var bulges []float32
+		for i, c := range ent.codes {
+			switch c {
+			case 10:
+				pts = append(pts, ms2.Vec{X: ent.vals[i], Y: ent.float20(i)})
+				bulges = append(bulges, 0)
+			case 42:
+				if len(bulges) > 0 {
+					bulges[len(bulges)-1] = ent.vals[i]
+				}
+			}
+		}
+		if len(pts) < 2 {
+			return edge{}, errors.New("polyline with less than 2 vertices")
+		}
+		n := len(pts) - 1
+		if closed {
+			n++
+		}
+		out := []ms2.Vec{pts[0]}
+		for i := 0; i < n; i++ {
+			p0, p1 := pts[i], pts[(i+1)%len(pts)]
+			if bulges[i] != 0 {
+				out = appendBulge(out, p0, p1, bulges[i], tol)
+			} else {
+				out = append(out, p1)
+			}
+		}
+		return edge{pts: out}, nil
+
+	case "SPLINE":
+		return ent.splineEdge(tol)
+	}
+	return edge{}, errors.New("unsupported entity")
+}
+
+// float20 returns the Y value following the X value at index i.
+func (ent *entity) float20(i int) float32 {
+	if i+1 < len(ent.codes) && ent.codes[i+1] == ent.codes[i]+10 {
+		return ent.vals[i+1]
+	}
+	return 0
+}
+
+// splineEdge flattens a NURBS spline. Splines defined only by fit points are approximated by a polyline through them.
+func (ent *entity) splineEdge(tol float32) (edge, error) {
+	degree := int(ent.float(71))
+	knots := ent.all(40)
+	ctrl := ent.points(10)
+	weights := ent.all(41)
+	closed := int(ent.float(70))&1 != 0
+	if len(ctrl) == 0 {
+		fit := ent.points(11)
+		if len(fit) < 2 {
+			return edge{}, errors.New("spline without control or fit points")
+		}
+		if closed {
+			fit = append(fit, fit[0])
+		}
+		return edge{pts: fit}, nil
+	}
+	if degree < 1 || len(ctrl) <= degree || len(knots) != len(ctrl)+degree+1 {
+		return edge{}, errors.New("invalid spline definition")
+	}
+	if len(weights) != len(ctrl) {
+		weights = nil
+	}
+	// Choose sample count from control polygon length.
+	var length float32
+	for i := 1; i < len(ctrl); i++ {
+		length += ms2.Norm(ms2.Sub(ctrl[i], ctrl[i-1]))
+	}
+	n := int(math.Ceil(math.Sqrt(length/tol))) * len(ctrl)
+	if n < 8 {
+		n = 8
+	} else if n > 1<<14 {
+		n = 1 << 14
+	}
+	t0, t1 := knots[degree], knots[len(ctrl)]
+	pts := make([]ms2.Vec, 0, n+1)
+	for i := 0; i <= n; i++ {
+		t := t0 + (t1-t0)*float32(i)/float32(n)
+		pts = append(pts, deBoor(t, degree, knots, ctrl, weights))
+	}
+	return edge{pts: pts}, nil
+}
+
+// deBoor evaluates a (rational) B-spline at parameter t.
+func deBoor(t float32, degree int, knots []float32, ctrl []ms2.Vec, weights []float32) ms2.Vec {
+	// Find knot span k such that knots[k] <= t < knots[k+1].
+	k := degree
+	for k < len(ctrl)-1 && t >= knots[k+1] {
+		k++
+	}
+	type hpt struct{ x, y, w float32 }
+	d := make([]hpt, degree+1)
+	for j := range d {
+		p := ctrl[j+k-degree]
+		w := float32(1)
+		if weights != nil {
+			w = weights[j+k-degree]
+		}
+		d[j] = hpt{p.X * w, p.Y * w, w}
+	}
+	for r := 1; r <= degree; r++ {
+		for j := degree; j >= r; j-- {
+			i := j + k - degree
+			den := knots[i+degree-r+1] - knots[i]
+			var alpha float32
+			if den != 0 {
+				alpha = (t - knots[i]) / den
+			}
+			d[j] = hpt{
+				x: (1-alpha)*d[j-1].x + alpha*d[j].x,
+				y: (1-alpha)*d[j-1].y + alpha*d[j].y,
+				w: (1-alpha)*d[j-1].w + alpha*d[j].w,
+			}
+		}
+	}
+	return ms2.Vec{X: d[degree].x / d[degree].w, Y: d[degree].y / d[degree].w}
+}
+
+// appendBulge appends the flattened polyline arc from p0 to p1 with the given bulge, excluding p0.
+// Bulge is the tangent of a quarter of the included angle, positive for counter-clockwise arcs.
+func appendBulge(dst []ms2.Vec, p0, p1 ms2.Vec, bulge, tol float32) []ms2.Vec {
+	theta := 4 * math.Atan(bulge)
+	chord := ms2.Sub(p1, p0)
+	c := ms2.Norm(chord)
+	if c == 0 {
+		return dst
+	}
+	r := c / (2 * math.Abs(math.Sin(theta/2)))
+	// Distance from chord midpoint to center, center lies to the left of chord for positive bulge.
+	h := r * math.Cos(theta/2)
+	left := ms2.Scale(1/c, ms2.Vec{X: -chord.Y, Y: chord.X})
+	mid := ms2.Scale(0.5, ms2.Add(p0, p1))
+	center := ms2.Add(mid, ms2.Scale(math.Copysign(h, bulge), left))
+	start := ms2.Sub(p0, center)
+	a0 := math.Atan2(start.Y, start.X)
+	pts := appendArc(nil, center, r, a0, theta, tol)
+	pts[len(pts)-1] = p1 // Avoid floating point drift at arc end.
+	return append(dst, pts[1:]...)
+}
+
+// appendArc appends the points of the flattened arc starting at angle a0 and spanning da radians.
+func appendArc(dst []ms2.Vec, center ms2.Vec, r, a0, da, tol float32) []ms2.Vec {
+	// Maximum angle step such that the sagitta does not exceed tol.
+	maxStep := 2 * math.Acos(math.Max(0, 1-tol/r))
+	n := int(math.Ceil(math.Abs(da) / math.Max(maxStep, 1e-3)))
+	if n < 2 {
+		n = 2
+	}
+	for i := 0; i <= n; i++ {
+		s, c := math.Sincos(a0 + da*float32(i)/float32(n))
+		dst = append(dst, ms2.Vec{X: center.X + r*c, Y: center.Y + r*s})
+	}
+	return dst
+}
+
+// insunitsMillimeters returns the length of the DXF $INSUNITS unit in millimeters or zero if unitless or unknown.
+func insunitsMillimeters(insunits int) float32 {
+	switch insunits {
+	case 1:
+		return 25.4 // Inches.
+	case 2:
+		return 304.8 // Feet.
+	case 4:
+		return 1 // Millimeters.
+	case 5:
+		return 10 // Centimeters.
+	case 6:
+		return 1000 // Meters.
+	case 8:
+		return 25.4e-6 // Microinches.
+	case 9:
+		return 25.4e-3 // Mils.
+	case 10:
+		return 914.4 // Yards.
+	case 13:
+		return 1e-3 // Microns.
+	case 14:
+		return 100 // Decimeters.
+	}
+	return 0
+}
+
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
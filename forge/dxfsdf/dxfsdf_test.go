@@ -0,0 +1,165 @@
+package dxfsdf
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	math "github.com/chewxy/math32"
+	"github.com/soypat/geometry/ms2"
+	"github.com/soypat/gsdf"
+	"github.com/soypat/gsdf/glbuild"
+	"github.com/soypat/gsdf/gleval"
+)
+
+var bld gsdf.Builder
+
+// makeDXF returns a minimal DXF file with the argument entities' group codes.
+func makeDXF(insunits int, entities ...string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "0\nSECTION\n2\nHEADER\n9\n$INSUNITS\n70\n%d\n0\nENDSEC\n0\nSECTION\n2\nENTITIES\n", insunits)
+	for _, ent := range entities {
+		b.WriteString(ent)
+	}
+	b.WriteString("0\nENDSEC\n0\nEOF\n")
+	return b.String()
+}
+
+func line(layer string, x0, y0, x1, y1 float32) string {
+	return fmt.Sprintf("0\nLINE\n8\n%s\n10\n%g\n20\n%g\n11\n%g\n21\n%g\n", layer, x0, y0, x1, y1)
+}
+
+func arc(layer string, cx, cy, r, startDeg, endDeg float32) string {
+	return fmt.Sprintf("0\nARC\n8\n%s\n10\n%g\n20\n%g\n40\n%g\n50\n%g\n51\n%g\n", layer, cx, cy, r, startDeg, endDeg)
+}
+
+func TestDecode(t *testing.T) {
+	// 20x10 rectangle with rounded right side made with a bulge,
+	// a circular hole made of two arcs and a square hole made of lines in reversed order.
+	outer := "0\nLWPOLYLINE\n8\nPROFILE\n90\n4\n70\n1\n10\n-10\n20\n-5\n10\n10\n20\n-5\n42\n1\n10\n10\n20\n5\n10\n-10\n20\n5\n"
+	dxf := makeDXF(4,
+		outer,
+		arc("PROFILE", -5, 0, 2, 0, 180),
+		arc("PROFILE", -5, 0, 2, 180, 360),
+		line("PROFILE", 4, -1, 6, -1),
+		line("PROFILE", 4, 1, 4, -1),
+		line("PROFILE", 6, 1, 4, 1),
+		line("PROFILE", 6, -1, 6, 1),
+		"0\nCIRCLE\n8\nOTHER\n10\n100\n20\n100\n40\n1\n",
+	)
+	shape, err := Decode(strings.NewReader(dxf), Config{Builder: &bld, Layers: []string{"PROFILE"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bb := shape.Bounds()
+	want := ms2.NewBox(-10, -5, 15, 5)
+	if !bb.Equal(want, 0.05) {
+		t.Errorf("want bounds %v, got %v", want, bb)
+	}
+	d := evalSDF2(t, shape,
+		ms2.Vec{X: -5, Y: 0},    // Circular hole center.
+		ms2.Vec{X: 5, Y: 0},     // Square hole center.
+		ms2.Vec{X: 0, Y: 0},     // Solid.
+		ms2.Vec{X: 14, Y: 0},    // Solid in bulge.
+		ms2.Vec{X: 100, Y: 100}, // Filtered out layer.
+	)
+	if math.Abs(d[0]-2) > 0.05 {
+		t.Errorf("circular hole center want distance 2, got %v", d[0])
+	}
+	if math.Abs(d[1]-1) > 1e-5 {
+		t.Errorf("square hole center want distance 1, got %v", d[1])
+	}
+	if d[2] >= 0 || d[3] >= 0 || d[4] <= 0 {
+		t.Errorf("unexpected distances %v", d)
+	}
+
+	// Unit conversion from millimeters to centimeters.
+	shape, err = Decode(strings.NewReader(dxf), Config{Builder: &bld, Layers: []string{"PROFILE"}, Unit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bb = shape.Bounds()
+	want = ms2.NewBox(-1, -0.5, 1.5, 0.5)
+	if !bb.Equal(want, 0.005) {
+		t.Errorf("scaled: want bounds %v, got %v", want, bb)
+	}
+
+	// Missing line results in open contour.
+	dxf = makeDXF(0, line("0", 0, 0, 1, 0), line("0", 1, 0, 1, 1))
+	_, err = Decode(strings.NewReader(dxf), Config{Builder: &bld})
+	if err == nil {
+		t.Error("expected open contour error")
+	}
+}
+
+func TestDecodeMirrored(t *testing.T) {
+	// Entities with extrusion direction -Z have their object coordinate X axis pointing along world -X.
+	const extrusion = "210\n0\n220\n0\n230\n-1\n"
+	dxf := makeDXF(0,
+		// Left half disk closed by a line in world coordinates.
+		arc("0", 0, 0, 5, -90, 90)+extrusion,
+		line("0", 0, -5, 0, 5),
+		"0\nCIRCLE\n8\n0\n10\n20\n20\n0\n40\n1\n"+extrusion,
+		// Square with a bulged side.
+		"0\nLWPOLYLINE\n8\n0\n90\n4\n70\n1\n10\n30\n20\n0\n42\n1\n10\n32\n20\n0\n10\n32\n20\n2\n10\n30\n20\n2\n"+extrusion,
+	)
+	shape, err := Decode(strings.NewReader(dxf), Config{Builder: &bld})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ms2.NewBox(-32, -5, 0, 5)
+	if bb := shape.Bounds(); !bb.Equal(want, 0.05) {
+		t.Errorf("want bounds %v, got %v", want, bb)
+	}
+	d := evalSDF2(t, shape,
+		ms2.Vec{X: -2, Y: 0},     // Inside half disk.
+		ms2.Vec{X: 2, Y: 0},      // Outside half disk.
+		ms2.Vec{X: -20, Y: 0},    // Circle center.
+		ms2.Vec{X: -31, Y: 1},    // Inside square.
+		ms2.Vec{X: -31, Y: -0.5}, // Inside bulge below square.
+		ms2.Vec{X: 31, Y: 1},     // Unmirrored square.
+	)
+	if d[0] >= 0 || d[1] <= 0 || math.Abs(d[2]+1) > 1e-5 || d[3] >= 0 || d[4] >= 0 || d[5] <= 0 {
+		t.Errorf("unexpected distances %v", d)
+	}
+}
+
+func TestSpline(t *testing.T) {
+	// Closed degree 1 spline is a triangle.
+	spline := "0\nSPLINE\n8\n0\n70\n1\n71\n1\n72\n6\n73\n4\n" +
+		"40\n0\n40\n0\n40\n1\n40\n2\n40\n3\n40\n3\n" +
+		"10\n0\n20\n0\n10\n4\n20\n0\n10\n0\n20\n4\n10\n0\n20\n0\n"
+	shape, err := Decode(strings.NewReader(makeDXF(0, spline)), Config{Builder: &bld})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := evalSDF2(t, shape, ms2.Vec{X: 1, Y: 1}, ms2.Vec{X: 3, Y: 3})
+	if math.Abs(d[0]+1) > 1e-4 || d[1] <= 0 {
+		t.Errorf("unexpected distances %v", d)
+	}
+	// Quadratic spline with clamped knots is a quadratic bezier.
+	knots := []float32{0, 0, 0, 1, 1, 1}
+	ctrl := []ms2.Vec{{X: 0, Y: 0}, {X: 1, Y: 2}, {X: 2, Y: 0}}
+	for _, tt := range []float32{0, 0.25, 0.5, 1} {
+		got := deBoor(tt, 2, knots, ctrl, nil)
+		s := 1 - tt
+		want := ms2.Add(ms2.Add(ms2.Scale(s*s, ctrl[0]), ms2.Scale(2*s*tt, ctrl[1])), ms2.Scale(tt*tt, ctrl[2]))
+		if !ms2.EqualElem(got, want, 1e-6) {
+			t.Errorf("t=%v: want %v, got %v", tt, want, got)
+		}
+	}
+}
+
+func evalSDF2(t *testing.T, s glbuild.Shader2D, pos ...ms2.Vec) []float32 {
+	t.Helper()
+	sdf, err := gleval.NewCPUSDF2(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dist := make([]float32, len(pos))
+	err = sdf.Evaluate(pos, dist, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dist
+}
@@ -0,0 +1,207 @@
+package imagesdf
+
+import (
+	"errors"
+	"image"
+	"image/color"
+
+	math "github.com/chewxy/math32"
+	"github.com/soypat/geometry/ms2"
+	"github.com/soypat/gsdf"
+	"github.com/soypat/gsdf/glbuild"
+)
+
+var defaultBuilder = &gsdf.Builder{}
+
+// Config configures image conversion. Dark pixels are material by default: they are the tallest parts of
+// reliefs, as is needed for lithophanes, and the interior of thresholded shapes.
+type Config struct {
+	Builder *gsdf.Builder
+	// PixelPitch is the distance between neighboring pixel centers in output units. If zero 1 is used.
+	PixelPitch float32
+	// Invert selects light pixels as material instead of dark pixels, i.e: to emboss the light parts of an image.
+	Invert bool
+	// Level is the fraction of material between 0 and 1 above which pixels are inside shapes created
+	// with [Threshold]. If zero 0.5 is used.
+	Level float32
+}
+
+// Relief returns a relief of img which lies on the z=0 plane and is centered at the origin. The top surface
+// of each pixel lies at base+height*m where m is the pixel's material fraction, which is 1 for black and
+// 0 for white pixels unless cfg.Invert is set. Colors are converted to luminance and transparent pixels
+// are composited over white. The relief is built with [gsdf.Builder.NewHeightmap] so its distance is
+// corrected for the slope of the image and never overestimated.
+func Relief(img image.Image, base, height float32, cfg Config) (glbuild.Shader3D, error) {
+	pitch, err := cfg.validate(img)
+	if err != nil {
+		return nil, err
+	} else if base < 0 || height < 0 || base+height <= 0 {
+		return nil, errors.New("invalid relief base or height")
+	}
+	bld := cfg.Builder
+	if bld == nil {
+		bld = defaultBuilder
+	}
+	rect := img.Bounds()
+	nx, ny := rect.Dx(), rect.Dy()
+	heights := make([]float32, 0, nx*ny)
+	// Image rows go downwards, heightmap rows go upwards.
+	for y := rect.Max.Y - 1; y >= rect.Min.Y; y-- {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			heights = append(heights, material(img.At(x, y), cfg.Invert))
+		}
+	}
+	return bld.NewHeightmap(heights, nx, ny, pitch, base, height), nil
+}
+
+// Threshold returns the 2D shape of the pixels of img with a material fraction above cfg.Level, centered
+// at the origin. See [Relief] for how the material fraction is calculated. The shape is the signed Euclidean
+// distance transform of the thresholded image sampled at pixel centers and interpolated with
+// [gsdf.Builder.NewGridSDF2], so the outline passes midway between inside and outside pixel centers.
+func Threshold(img image.Image, cfg Config) (glbuild.Shader2D, error) {
+	pitch, err := cfg.validate(img)
+	if err != nil {
+		return nil, err
+	}
+	level := cfg.Level
+	if level == 0 {
+		level = 0.5
+	} else if level < 0 || level >= 1 {
+		return nil, errors.New("invalid threshold level")
+	}
+	bld := cfg.Builder
+	if bld == nil {
+		bld = defaultBuilder
+	}
+	rect := img.Bounds()
+	// Pad image with a border of outside pixels so the outline lies strictly within the grid bounds.
+	nx, ny := rect.Dx()+2, rect.Dy()+2
+	inside := make([]bool, nx*ny)
+	found := false
+	for j := 1; j < ny-1; j++ {
+		y := rect.Max.Y - j
+		for i := 1; i < nx-1; i++ {
+			if material(img.At(rect.Min.X+i-1, y), cfg.Invert) > level {
+				inside[i+nx*j] = true
+				found = true
+			}
+		}
+	}
+	if !found {
+		return nil, errors.New("no pixels above threshold level")
+	}
+	dist := signedDistanceTransform(inside, nx, ny)
+	for i := range dist {
+		dist[i] *= pitch
+	}
+	half := ms2.Vec{X: float32(nx-1) * pitch / 2, Y: float32(ny-1) * pitch / 2}
+	bounds := ms2.Box{Min: ms2.Scale(-1, half), Max: half}
+	return bld.NewGridSDF2(dist, nx, ny, bounds, gsdf.GridLinear), nil
+}
+
+func (cfg *Config) validate(img image.Image) (pitch float32, err error) {
+	rect := img.Bounds()
+	if rect.Dx() < 2 || rect.Dy() < 2 {
+		return 0, errors.New("image must be at least 2x2 pixels")
+	}
+	pitch = cfg.PixelPitch
+	if pitch == 0 {
+		pitch = 1
+	} else if pitch < 0 || math.IsNaN(pitch) || math.IsInf(pitch, 1) {
+		return 0, errors.New("invalid pixel pitch")
+	}
+	return pitch, nil
+}
+
+// material returns the material fraction of a color, which is its darkness or its luminance if invert is set.
+func material(c color.Color, invert bool) float32 {
+	r, g, b, a := c.RGBA()
+	bg := 0xffff - a // Composite premultiplied color over white.
+	lum := (0.299*float32(r+bg) + 0.587*float32(g+bg) + 0.114*float32(b+bg)) / 0xffff
+	lum = math.Max(0, math.Min(1, lum))
+	if invert {
+		return lum
+	}
+	return 1 - lum
+}
+
+// signedDistanceTransform returns the distance in pixels from each pixel center to the outline which passes
+// midway between inside and outside pixel centers, negative for inside pixels.
+func signedDistanceTransform(inside []bool, nx, ny int) []float32 {
+	toInside := squaredDistanceTransform(inside, true, nx, ny)
+	toOutside := squaredDistanceTransform(inside, false, nx, ny)
+	dist := make([]float32, len(inside))
+	for i, in := range inside {
+		if in {
+			dist[i] = 0.5 - math.Sqrt(toOutside[i])
+		} else {
+			dist[i] = math.Sqrt(toInside[i]) - 0.5
+		}
+	}
+	return dist
+}
+
+// squaredDistanceTransform returns the squared distance from each pixel center to the nearest pixel whose
+// inside value equals feature. It is the exact separable algorithm by Felzenszwalb and Huttenlocher.
+func squaredDistanceTransform(inside []bool, feature bool, nx, ny int) []float32 {
+	const far = 1e20
+	f := make([]float32, len(inside))
+	for i, in := range inside {
+		if in != feature {
+			f[i] = far
+		}
+	}
+	n := max(nx, ny)
+	line := make([]float32, n)
+	d := make([]float32, n)
+	v := make([]int, n)
+	z := make([]float32, n+1)
+	// Transform columns and then rows.
+	for i := 0; i < nx; i++ {
+		for j := 0; j < ny; j++ {
+			line[j] = f[i+nx*j]
+		}
+		edt1(d[:ny], line[:ny], v, z)
+		for j := 0; j < ny; j++ {
+			f[i+nx*j] = d[j]
+		}
+	}
+	for j := 0; j < ny; j++ {
+		row := f[nx*j : nx*(j+1)]
+		copy(line, row)
+		edt1(row, line[:nx], v, z)
+	}
+	return f
+}
+
+// edt1 stores in d the one dimensional squared distance transform of sampled function f, which is the lower
+// envelope of the parabolas rooted at each sample. v and z are buffers of at least len(f) and len(f)+1 elements.
+func edt1(d, f []float32, v []int, z []float32) {
+	k := 0
+	v[0] = 0
+	z[0] = -math.MaxFloat32
+	z[1] = math.MaxFloat32
+	for q := 1; q < len(f); q++ {
+		var s float32
+		for {
+			r := v[k]
+			s = ((f[q] + float32(q*q)) - (f[r] + float32(r*r))) / float32(2*(q-r))
+			if s > z[k] || k == 0 {
+				break
+			}
+			k--
+		}
+		k++
+		v[k] = q
+		z[k] = s
+		z[k+1] = math.MaxFloat32
+	}
+	k = 0
+	for q := range f {
+		for z[k+1] < float32(q) {
+			k++
+		}
+		dq := float32(q - v[k])
+		d[q] = dq*dq + f[v[k]]
+	}
+}
@@ -0,0 +1,143 @@
+package imagesdf
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	math "github.com/chewxy/math32"
+	"github.com/soypat/geometry/ms2"
+	"github.com/soypat/geometry/ms3"
+	"github.com/soypat/gsdf"
+	"github.com/soypat/gsdf/gleval"
+)
+
+var bld gsdf.Builder
+
+func TestThreshold(t *testing.T) {
+	// 20x10 white image with a 10x4 black rectangle centered in it.
+	img := image.NewGray(image.Rect(0, 0, 20, 10))
+	for i := range img.Pix {
+		img.Pix[i] = 255
+	}
+	for y := 3; y < 7; y++ {
+		for x := 5; x < 15; x++ {
+			img.SetGray(x, y, color.Gray{})
+		}
+	}
+	const pitch = 0.5
+	shape, err := Threshold(img, Config{Builder: &bld, PixelPitch: pitch})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sdf, err := gleval.NewCPUSDF2(shape)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rect, err := gleval.NewCPUSDF2(bld.NewRectangle(10*pitch, 4*pitch))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bb := sdf.Bounds()
+	if want := (ms2.Vec{X: 10.5 * pitch, Y: 5.5 * pitch}); bb.Max != want || bb.Min != ms2.Scale(-1, want) {
+		t.Errorf("bounds got %+v, want ±%v", bb, want)
+	}
+	pos := ms2.AppendGrid(nil, bb, 43, 23)
+	got := make([]float32, len(pos))
+	want := make([]float32, len(pos))
+	var vp gleval.VecPool
+	if err = sdf.Evaluate(pos, got, &vp); err != nil {
+		t.Fatal(err)
+	} else if err = rect.Evaluate(pos, want, &vp); err != nil {
+		t.Fatal(err)
+	}
+	for i, p := range pos {
+		// Distance transform samples are exact away from corners.
+		if math.Abs(got[i]-want[i]) > pitch/2 || (math.Abs(want[i]) > pitch/2 && (got[i] < 0) != (want[i] < 0)) {
+			t.Errorf("distance at %v: got %f, want %f", p, got[i], want[i])
+		}
+	}
+
+	// Inverting selects the white pixels.
+	shape, err = Threshold(img, Config{Builder: &bld, PixelPitch: pitch, Invert: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sdf, err = gleval.NewCPUSDF2(shape)
+	if err != nil {
+		t.Fatal(err)
+	}
+	corner := []ms2.Vec{{X: -4.75, Y: 2.25}, {}}
+	if err = sdf.Evaluate(corner, got[:2], &vp); err != nil {
+		t.Fatal(err)
+	} else if got[0] >= 0 || got[1] <= 0 {
+		t.Errorf("inverted threshold: got %f at corner pixel and %f at center", got[0], got[1])
+	}
+
+	// Errors.
+	if _, err = Threshold(image.NewGray(image.Rect(0, 0, 4, 4)), Config{Builder: &bld, Invert: true}); err == nil {
+		t.Error("expected error for black image with light material")
+	}
+	if _, err = Threshold(img, Config{Builder: &bld, Level: 1}); err == nil {
+		t.Error("expected error for invalid level")
+	}
+}
+
+func TestRelief(t *testing.T) {
+	// Horizontal gradient from black to white with a transparent last row.
+	const nx, ny = 6, 4
+	img := image.NewNRGBA(image.Rect(0, 0, nx, ny))
+	for y := 0; y < ny; y++ {
+		for x := 0; x < nx; x++ {
+			v := uint8(255 * x / (nx - 1))
+			a := uint8(255)
+			if y == ny-1 {
+				a = 0
+			}
+			img.SetNRGBA(x, y, color.NRGBA{R: v, G: v, B: v, A: a})
+		}
+	}
+	const pitch, base, height = 2, 1, 3
+	shape, err := Relief(img, base, height, Config{Builder: &bld, PixelPitch: pitch})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sdf, err := gleval.NewCPUSDF3(shape)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bb := sdf.Bounds()
+	if want := (ms3.Box{Min: ms3.Vec{X: -nx, Y: -ny}, Max: ms3.Vec{X: nx, Y: ny, Z: base + height}}); bb != want {
+		t.Errorf("bounds got %+v, want %+v", bb, want)
+	}
+	var pos []ms3.Vec
+	var tops []float32
+	for y := 0; y < ny; y++ {
+		for x := 0; x < nx; x++ {
+			top := base + height*(1-float32(x)/(nx-1))
+			if y == ny-1 {
+				top = base // Transparent pixels are white.
+			}
+			// Image rows go downwards.
+			c := ms3.Vec{X: (float32(x)+0.5)*pitch - nx, Y: ny - (float32(y)+0.5)*pitch, Z: top}
+			pos = append(pos, ms3.Add(c, ms3.Vec{Z: -0.01}), ms3.Add(c, ms3.Vec{Z: 0.01}))
+			tops = append(tops, top)
+		}
+	}
+	dist := make([]float32, len(pos))
+	if err = sdf.Evaluate(pos, dist, &gleval.VecPool{}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < len(pos); i += 2 {
+		if dist[i] >= 0 || dist[i+1] <= 0 {
+			t.Errorf("pixel surface at %v not at height %f: got %f below and %f above", pos[i], tops[i/2], dist[i], dist[i+1])
+		}
+	}
+
+	if _, err = Relief(img, 0, 0, Config{Builder: &bld}); err == nil {
+		t.Error("expected error for zero height relief")
+	}
+	if _, err = Relief(image.NewGray(image.Rect(0, 0, 1, 5)), base, height, Config{Builder: &bld}); err == nil {
+		t.Error("expected error for single column image")
+	}
+}
@@ -0,0 +1,292 @@
+package svgsdf
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	math "github.com/chewxy/math32"
+	"github.com/soypat/gsdf"
+	"github.com/soypat/gsdf/glbuild"
+)
+
+var defaultBuilder = &gsdf.Builder{}
+
+// Config configures SVG document decoding.
+type Config struct {
+	// RelativeTolerance sets the permissible curve tolerance when flattening filled curves. Must be between 0..1. If zero a reasonable value is chosen.
+	RelativeTolerance float32
+	Builder           *gsdf.Builder
+}
+
+// style holds the inherited presentation attributes of an element.
+type style struct {
+	transform   Transform
+	fill        bool
+	fillRule    FillRule
+	stroke      bool
+	strokeWidth float32
+	hidden      bool
+}
+
+// Decode reads a SVG document and returns the union of all shapes drawn by its path, rect, circle,
+// ellipse, line, polyline and polygon elements. Transforms, fill, fill-rule, stroke and stroke-width
+// are honored, both as attributes and in the style attribute. Coordinates are in SVG user units;
+// lengths in absolute CSS units are converted at 96 user units per inch while percentage and
+// font relative lengths are ignored.
+// Since the SVG Y axis points downwards the result is mirrored about the X axis so that the shape
+// appears as in a SVG viewer in a Y-up coordinate system.
+func Decode(r io.Reader, cfg Config) (glbuild.Shader2D, error) {
+	if cfg.RelativeTolerance < 0 || cfg.RelativeTolerance >= 1 {
+		return nil, errors.New("invalid RelativeTolerance")
+	}
+	tol := cfg.RelativeTolerance
+	if tol == 0 {
+		tol = 0.1
+	}
+	bld := cfg.Builder
+	if bld == nil {
+		bld = defaultBuilder
+	}
+	dec := xml.NewDecoder(r)
+	stack := []style{{
+		transform:   Transform{1, 0, 0, -1, 0, 0},
+		fill:        true,
+		strokeWidth: 1,
+	}}
+	var shapes []glbuild.Shader2D
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "defs", "clipPath", "mask", "symbol", "marker", "pattern", "style", "metadata", "title", "desc", "text":
+				err = dec.Skip()
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+			st, err := stack[len(stack)-1].inherit(t.Attr)
+			if err != nil {
+				return nil, fmt.Errorf("element %q: %w", t.Name.Local, err)
+			}
+			stack = append(stack, st)
+			if st.hidden {
+				continue
+			}
+			d, err := elementPathData(t)
+			if err != nil {
+				return nil, fmt.Errorf("element %q: %w", t.Name.Local, err)
+			} else if d == "" {
+				continue
+			}
+			path, err := ParsePath(d)
+			if err != nil {
+				return nil, fmt.Errorf("element %q: %w", t.Name.Local, err)
+			}
+			path.Transform(st.transform)
+			if st.fill && t.Name.Local != "line" {
+				// Paths which enclose no area are silently not filled, like SVG viewers do.
+				s, err := path.Fill(bld, st.fillRule, tol)
+				if err != nil && err != errNoArea {
+					return nil, fmt.Errorf("element %q fill: %w", t.Name.Local, err)
+				} else if err == nil {
+					shapes = append(shapes, s)
+				}
+			}
+			if st.stroke {
+				// Scale stroke width by the transform's mean scaling.
+				tr := st.transform
+				scale := math.Sqrt(math.Abs(tr[0]*tr[3] - tr[1]*tr[2]))
+				s, err := path.Stroke(bld, st.strokeWidth*scale)
+				if err != nil {
+					return nil, fmt.Errorf("element %q stroke: %w", t.Name.Local, err)
+				}
+				shapes = append(shapes, s)
+			}
+		}
+	}
+	if len(shapes) == 0 {
+		return nil, errors.New("no shapes found in SVG")
+	}
+	return union(bld, shapes), bld.Err()
+}
+
+// inherit returns the style of an element with attributes attrs whose parent has style st.
+func (st style) inherit(attrs []xml.Attr) (style, error) {
+	var err error
+	for _, attr := range attrs {
+		if attr.Name.Local == "transform" {
+			var t Transform
+			t, err = ParseTransform(attr.Value)
+			if err != nil {
+				return st, err
+			}
+			st.transform = st.transform.Mul(t)
+			continue
+		}
+		err = st.setProperty(attr.Name.Local, attr.Value)
+		if err != nil {
+			return st, err
+		}
+	}
+	// Style attribute has precedence over presentation attributes.
+	for _, attr := range attrs {
+		if attr.Name.Local != "style" {
+			continue
+		}
+		for _, decl := range strings.Split(attr.Value, ";") {
+			name, value, ok := strings.Cut(decl, ":")
+			if !ok {
+				continue
+			}
+			err = st.setProperty(strings.TrimSpace(name), strings.TrimSpace(value))
+			if err != nil {
+				return st, err
+			}
+		}
+	}
+	return st, nil
+}
+
+func (st *style) setProperty(name, value string) (err error) {
+	switch name {
+	case "fill":
+		st.fill = value != "none"
+	case "fill-rule":
+		switch value {
+		case "evenodd":
+			st.fillRule = FillEvenOdd
+		case "nonzero":
+			st.fillRule = FillNonZero
+		}
+	case "stroke":
+		st.stroke = value != "none"
+	case "stroke-width":
+		var w float32
+		w, err = parseLength(value)
+		if errors.Is(err, errRelativeLength) {
+			return nil // Keep inherited stroke width.
+		}
+		st.strokeWidth = w
+	case "display":
+		st.hidden = value == "none"
+	}
+	return err
+}
+
+// elementPathData returns the path data equivalent of a shape element. An empty string is returned for non-shape elements.
+func elementPathData(el xml.StartElement) (string, error) {
+	var err error
+	lookup := func(name string) (float32, bool) {
+		for _, a := range el.Attr {
+			if a.Name.Local == name && err == nil {
+				v, lerr := parseLength(a.Value)
+				if errors.Is(lerr, errRelativeLength) {
+					return 0, false // Viewport relative lengths are ignored.
+				}
+				err = lerr
+				return v, lerr == nil
+			}
+		}
+		return 0, false
+	}
+	attr := func(name string) float32 {
+		v, _ := lookup(name)
+		return v
+	}
+	var d string
+	switch el.Name.Local {
+	case "path":
+		for _, a := range el.Attr {
+			if a.Name.Local == "d" {
+				d = a.Value
+			}
+		}
+	case "rect":
+		x, y, w, h := attr("x"), attr("y"), attr("width"), attr("height")
+		if w <= 0 || h <= 0 {
+			break
+		}
+		rx, okx := lookup("rx")
+		ry, oky := lookup("ry")
+		switch {
+		case okx && !oky:
+			ry = rx
+		case oky && !okx:
+			rx = ry
+		}
+		rx = math.Min(math.Max(rx, 0), w/2)
+		ry = math.Min(math.Max(ry, 0), h/2)
+		if rx == 0 || ry == 0 {
+			d = fmt.Sprintf("M%g %gh%gv%gh%gZ", x, y, w, h, -w)
+			break
+		}
+		// Rounded corners are quarter ellipse arcs drawn clockwise from the top edge.
+		d = fmt.Sprintf("M%g %gh%ga%g %g 0 0 1 %g %gv%ga%g %g 0 0 1 %g %gh%ga%g %g 0 0 1 %g %gv%ga%g %g 0 0 1 %g %gZ",
+			x+rx, y, w-2*rx, rx, ry, rx, ry,
+			h-2*ry, rx, ry, -rx, ry,
+			-(w - 2*rx), rx, ry, -rx, -ry,
+			-(h - 2*ry), rx, ry, rx, -ry)
+	case "circle":
+		cx, cy, r := attr("cx"), attr("cy"), attr("r")
+		if r > 0 {
+			d = fmt.Sprintf("M%g %gA%g %g 0 1 0 %g %gA%g %g 0 1 0 %g %gZ", cx-r, cy, r, r, cx+r, cy, r, r, cx-r, cy)
+		}
+	case "ellipse":
+		cx, cy, rx, ry := attr("cx"), attr("cy"), attr("rx"), attr("ry")
+		if rx > 0 && ry > 0 {
+			d = fmt.Sprintf("M%g %gA%g %g 0 1 0 %g %gA%g %g 0 1 0 %g %gZ", cx-rx, cy, rx, ry, cx+rx, cy, rx, ry, cx-rx, cy)
+		}
+	case "line":
+		d = fmt.Sprintf("M%g %gL%g %g", attr("x1"), attr("y1"), attr("x2"), attr("y2"))
+	case "polyline", "polygon":
+		for _, a := range el.Attr {
+			if a.Name.Local == "points" {
+				d = "M" + a.Value
+			}
+		}
+		if d != "" && el.Name.Local == "polygon" {
+			d += "Z"
+		}
+	}
+	return d, err
+}
+
+// errRelativeLength is returned by parseLength for lengths relative to the viewport or font size.
+var errRelativeLength = errors.New("relative length unit")
+
+// parseLength parses a SVG length and returns it in user units. Absolute CSS units
+// are converted assuming 96 user units per inch.
+func parseLength(s string) (float32, error) {
+	s = strings.TrimSpace(s)
+	scale := float32(1)
+	for _, u := range [...]struct {
+		suffix string
+		scale  float32
+	}{
+		{"px", 1}, {"in", 96}, {"cm", 96 / 2.54}, {"mm", 96 / 25.4}, {"pt", 96. / 72}, {"pc", 16},
+	} {
+		if strings.HasSuffix(s, u.suffix) {
+			s = strings.TrimSuffix(s, u.suffix)
+			scale = u.scale
+			break
+		}
+	}
+	if strings.HasSuffix(s, "%") || strings.HasSuffix(s, "em") || strings.HasSuffix(s, "ex") {
+		return 0, errRelativeLength
+	}
+	v, err := strconv.ParseFloat(s, 32)
+	return scale * float32(v), err
+}
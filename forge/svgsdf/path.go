@@ -0,0 +1,379 @@
+package svgsdf
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	math "github.com/chewxy/math32"
+	"github.com/soypat/geometry/ms2"
+)
+
+type segKind uint8
+
+const (
+	segLine segKind = iota
+	segQuad
+	segCubic
+)
+
+// segment is a path segment starting at the end of the previous segment. Control points are absolute.
+type segment struct {
+	kind segKind
+	// p holds the segment points. The end point is p[0] for lines, p[1] for quadratic and p[2] for cubic beziers.
+	p [3]ms2.Vec
+}
+
+func (s segment) end() ms2.Vec {
+	return s.p[s.kind]
+}
+
+// subpath is a sequence of connected segments started by a moveto command.
+type subpath struct {
+	start  ms2.Vec
+	segs   []segment
+	closed bool
+}
+
+// Path is a parsed SVG path. All commands are converted to absolute
+// lines, quadratic and cubic beziers. Elliptical arcs are converted to cubic beziers.
+type Path struct {
+	subpaths []subpath
+}
+
+// ParsePath parses SVG path data, the contents of the "d" attribute of a path element.
+// All path commands (M, L, H, V, C, S, Q, T, A, Z and their relative counterparts) are supported.
+func ParsePath(d string) (*Path, error) {
+	var (
+		sc       = pathScanner{s: d}
+		path     Path
+		cmd      byte
+		cur      ms2.Vec
+		start    ms2.Vec
+		lastCtrl ms2.Vec // Last control point, used for S and T reflection.
+		lastCmd  byte    // Last uppercase command executed.
+		args     [7]float32
+	)
+	for {
+		sc.skipSep()
+		if sc.done() {
+			break
+		}
+		c := sc.s[sc.i]
+		if isCommand(c) {
+			cmd = c
+			sc.i++
+		} else if cmd == 0 {
+			return nil, fmt.Errorf("path data must start with command at %d", sc.i)
+		} else if cmd == 'Z' || cmd == 'z' {
+			return nil, fmt.Errorf("unexpected number after closepath at %d", sc.i)
+		}
+		rel := cmd >= 'a'
+		upper := cmd &^ 0x20
+		prevCmd := lastCmd
+		lastCmd = upper
+		nargs := cmdArgs(upper)
+		for i := 0; i < nargs; i++ {
+			var err error
+			if upper == 'A' && (i == 3 || i == 4) {
+				args[i], err = sc.flag()
+			} else {
+				args[i], err = sc.number()
+			}
+			if err != nil {
+				return nil, fmt.Errorf("command %q: %w", cmd, err)
+			}
+		}
+		var offset ms2.Vec
+		if rel {
+			offset = cur
+		}
+		pt := func(i int) ms2.Vec { return ms2.Add(offset, ms2.Vec{X: args[i], Y: args[i+1]}) }
+		if upper != 'M' && upper != 'Z' && (len(path.subpaths) == 0 || path.subpaths[len(path.subpaths)-1].closed) {
+			// Drawing command after closepath or without moveto starts new subpath at current point.
+			path.subpaths = append(path.subpaths, subpath{start: cur})
+			start = cur
+		}
+		sp := func() *subpath {
+			return &path.subpaths[len(path.subpaths)-1]
+		}
+		var seg segment
+		switch upper {
+		case 'M':
+			cur = pt(0)
+			start = cur
+			path.subpaths = append(path.subpaths, subpath{start: cur})
+			// Subsequent coordinate pairs are implicit lineto commands.
+			cmd = 'L' | (cmd & 0x20)
+			lastCtrl = cur
+			continue
+		case 'Z':
+			if len(path.subpaths) > 0 {
+				sp().closed = true
+			}
+			cur = start
+			lastCtrl = cur
+			continue
+		case 'L':
+			seg = segment{kind: segLine, p: [3]ms2.Vec{pt(0)}}
+		case 'H':
+			x := args[0]
+			if rel {
+				x += cur.X
+			}
+			seg = segment{kind: segLine, p: [3]ms2.Vec{{X: x, Y: cur.Y}}}
+		case 'V':
+			y := args[0]
+			if rel {
+				y += cur.Y
+			}
+			seg = segment{kind: segLine, p: [3]ms2.Vec{{X: cur.X, Y: y}}}
+		case 'C':
+			seg = segment{kind: segCubic, p: [3]ms2.Vec{pt(0), pt(2), pt(4)}}
+		case 'S':
+			c1 := cur
+			if prevCmd == 'C' || prevCmd == 'S' {
+				c1 = reflect(lastCtrl, cur)
+			}
+			seg = segment{kind: segCubic, p: [3]ms2.Vec{c1, pt(0), pt(2)}}
+		case 'Q':
+			seg = segment{kind: segQuad, p: [3]ms2.Vec{pt(0), pt(2)}}
+		case 'T':
+			c := cur
+			if prevCmd == 'Q' || prevCmd == 'T' {
+				c = reflect(lastCtrl, cur)
+			}
+			seg = segment{kind: segQuad, p: [3]ms2.Vec{c, pt(0)}}
+		case 'A':
+			end := pt(5)
+			sp().segs = appendArc(sp().segs, cur, end, args[0], args[1], args[2], args[3] != 0, args[4] != 0)
+			cur = end
+			lastCtrl = cur
+			continue
+		}
+		sp().segs = append(sp().segs, seg)
+		switch seg.kind {
+		case segQuad:
+			lastCtrl = seg.p[0]
+		case segCubic:
+			lastCtrl = seg.p[1]
+		default:
+			lastCtrl = seg.end()
+		}
+		cur = seg.end()
+	}
+	if len(path.subpaths) == 0 {
+		return nil, errors.New("empty path data")
+	}
+	return &path, nil
+}
+
+// Transform applies the affine transform t to all points of the path.
+func (p *Path) Transform(t Transform) {
+	for i := range p.subpaths {
+		sp := &p.subpaths[i]
+		sp.start = t.Apply(sp.start)
+		for j := range sp.segs {
+			seg := &sp.segs[j]
+			for k := 0; k <= int(seg.kind); k++ {
+				seg.p[k] = t.Apply(seg.p[k])
+			}
+		}
+	}
+}
+
+// Bounds returns the bounding box of the path's points, including bezier control points.
+func (p *Path) Bounds() ms2.Box {
+	bb := ms2.Box{Min: p.subpaths[0].start, Max: p.subpaths[0].start}
+	for _, sp := range p.subpaths {
+		bb = bb.IncludePoint(sp.start)
+		for _, seg := range sp.segs {
+			for k := 0; k <= int(seg.kind); k++ {
+				bb = bb.IncludePoint(seg.p[k])
+			}
+		}
+	}
+	return bb
+}
+
+func reflect(ctrl, about ms2.Vec) ms2.Vec {
+	return ms2.Sub(ms2.Scale(2, about), ctrl)
+}
+
+func isCommand(c byte) bool {
+	switch c &^ 0x20 {
+	case 'M', 'L', 'H', 'V', 'C', 'S', 'Q', 'T', 'A', 'Z':
+		return true
+	}
+	return false
+}
+
+func cmdArgs(upperCmd byte) int {
+	switch upperCmd {
+	case 'Z':
+		return 0
+	case 'H', 'V':
+		return 1
+	case 'M', 'L', 'T':
+		return 2
+	case 'S', 'Q':
+		return 4
+	case 'C':
+		return 6
+	case 'A':
+		return 7
+	}
+	return 0
+}
+
+// appendArc appends the SVG elliptical arc from p0 to p1 as cubic bezier segments.
+// See https://www.w3.org/TR/SVG11/implnote.html#ArcImplementationNotes.
+func appendArc(dst []segment, p0, p1 ms2.Vec, rx, ry, xrotDeg float32, largeArc, sweep bool) []segment {
+	if p0 == p1 {
+		return dst
+	}
+	rx, ry = math.Abs(rx), math.Abs(ry)
+	if rx == 0 || ry == 0 {
+		return append(dst, segment{kind: segLine, p: [3]ms2.Vec{p1}})
+	}
+	sinphi, cosphi := math.Sincos(xrotDeg * math.Pi / 180)
+	// Step 1: compute (x1', y1').
+	dx, dy := (p0.X-p1.X)/2, (p0.Y-p1.Y)/2
+	x1 := cosphi*dx + sinphi*dy
+	y1 := -sinphi*dx + cosphi*dy
+	// Correct out of range radii.
+	lambda := x1*x1/(rx*rx) + y1*y1/(ry*ry)
+	if lambda > 1 {
+		sq := math.Sqrt(lambda)
+		rx *= sq
+		ry *= sq
+	}
+	// Step 2: compute (cx', cy').
+	num := rx*rx*ry*ry - rx*rx*y1*y1 - ry*ry*x1*x1
+	den := rx*rx*y1*y1 + ry*ry*x1*x1
+	coef := math.Sqrt(math.Max(0, num/den))
+	if largeArc == sweep {
+		coef = -coef
+	}
+	cx1 := coef * rx * y1 / ry
+	cy1 := -coef * ry * x1 / rx
+	// Step 3: compute (cx, cy).
+	cx := cosphi*cx1 - sinphi*cy1 + (p0.X+p1.X)/2
+	cy := sinphi*cx1 + cosphi*cy1 + (p0.Y+p1.Y)/2
+	// Step 4: compute start angle and angle extent.
+	theta1 := math.Atan2((y1-cy1)/ry, (x1-cx1)/rx)
+	dtheta := math.Atan2((-y1-cy1)/ry, (-x1-cx1)/rx) - theta1
+	if sweep && dtheta < 0 {
+		dtheta += 2 * math.Pi
+	} else if !sweep && dtheta > 0 {
+		dtheta -= 2 * math.Pi
+	}
+	// Split arc into pieces of at most 90 degrees and approximate each with a cubic bezier.
+	n := int(math.Ceil(math.Abs(dtheta) / (math.Pi / 2)))
+	if n < 1 {
+		n = 1
+	}
+	step := dtheta / float32(n)
+	k := 4. / 3. * math.Tan(step/4)
+	ellipse := func(theta float32) (pt, deriv ms2.Vec) {
+		s, c := math.Sincos(theta)
+		pt = ms2.Vec{X: cx + rx*c*cosphi - ry*s*sinphi, Y: cy + rx*c*sinphi + ry*s*cosphi}
+		deriv = ms2.Vec{X: -rx*s*cosphi - ry*c*sinphi, Y: -rx*s*sinphi + ry*c*cosphi}
+		return pt, deriv
+	}
+	start, dstart := ellipse(theta1)
+	for i := 1; i <= n; i++ {
+		end, dend := ellipse(theta1 + float32(i)*step)
+		if i == n {
+			end = p1 // Avoid floating point drift at the arc end.
+		}
+		dst = append(dst, segment{kind: segCubic, p: [3]ms2.Vec{
+			ms2.Add(start, ms2.Scale(k, dstart)),
+			ms2.Sub(end, ms2.Scale(k, dend)),
+			end,
+		}})
+		start, dstart = end, dend
+	}
+	return dst
+}
+
+// pathScanner tokenizes SVG path data.
+type pathScanner struct {
+	s string
+	i int
+}
+
+func (sc *pathScanner) done() bool { return sc.i >= len(sc.s) }
+
+func (sc *pathScanner) skipSep() {
+	for sc.i < len(sc.s) {
+		switch sc.s[sc.i] {
+		case ' ', '\t', '\n', '\r', '\f', ',':
+			sc.i++
+		default:
+			return
+		}
+	}
+}
+
+// number reads a floating point number. Numbers may be not be separated
+// if unambiguous, i.e: "1-2" and "0.5.5" are two numbers each.
+func (sc *pathScanner) number() (float32, error) {
+	sc.skipSep()
+	start := sc.i
+	s := sc.s
+	i := sc.i
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		i++
+	}
+	digits := 0
+	for ; i < len(s) && isDigit(s[i]); i++ {
+		digits++
+	}
+	if i < len(s) && s[i] == '.' {
+		i++
+		for ; i < len(s) && isDigit(s[i]); i++ {
+			digits++
+		}
+	}
+	if digits == 0 {
+		return 0, fmt.Errorf("expected number at %d", start)
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		j := i + 1
+		if j < len(s) && (s[j] == '+' || s[j] == '-') {
+			j++
+		}
+		if j < len(s) && isDigit(s[j]) {
+			for j < len(s) && isDigit(s[j]) {
+				j++
+			}
+			i = j
+		}
+	}
+	v, err := strconv.ParseFloat(s[start:i], 32)
+	if err != nil {
+		return 0, err
+	}
+	sc.i = i
+	return float32(v), nil
+}
+
+// flag reads a single arc flag character which need not be separated from the next number.
+func (sc *pathScanner) flag() (float32, error) {
+	sc.skipSep()
+	if sc.done() {
+		return 0, errors.New("expected arc flag")
+	}
+	switch sc.s[sc.i] {
+	case '0':
+		sc.i++
+		return 0, nil
+	case '1':
+		sc.i++
+		return 1, nil
+	}
+	return 0, fmt.Errorf("invalid arc flag at %d", sc.i)
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
@@ -0,0 +1,279 @@
+package svgsdf
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/soypat/geometry/ms2"
+	"github.com/soypat/gsdf"
+	"github.com/soypat/gsdf/glbuild"
+)
+
+var errNoArea = errors.New("path encloses no area")
+
+// FillRule determines which regions enclosed by a path are filled. See the SVG fill-rule property.
+type FillRule uint8
+
+const (
+	// FillNonZero fills regions with a nonzero winding number. This is the SVG default.
+	FillNonZero FillRule = iota
+	// FillEvenOdd fills regions which are enclosed an odd amount of times.
+	FillEvenOdd
+)
+
+func (fr FillRule) filled(winding int) bool {
+	if fr == FillEvenOdd {
+		return winding%2 != 0
+	}
+	return winding != 0
+}
+
+// Fill returns the SDF of the area enclosed by the path according to the fill rule.
+// Curves are flattened into polygons using curveTol as the relative tolerance of the
+// bezier sampler, see [ms2.Spline3Sampler]. Open subpaths are implicitly closed as per SVG.
+//
+// Each subpath is converted to a polygon and classified as an outer contour or a hole
+// by evaluating the fill rule on either side of it. Contours are then added or subtracted
+// in order of nesting. Contours are expected not to intersect each other.
+func (p *Path) Fill(bld *gsdf.Builder, rule FillRule, curveTol float32) (glbuild.Shader2D, error) {
+	if curveTol <= 0 || curveTol >= 1 {
+		return nil, errors.New("curve tolerance must be between 0 and 1")
+	}
+	return fillPolygons(bld, p.polygons(curveTol), rule)
+}
+
+// Stroke returns the SDF of the path's outline drawn with the given stroke width. Straight segments are drawn with
+// [gsdf.Builder.NewLines2D] and curves with [gsdf.Builder.NewQuadraticBezier2D]. Cubic beziers are approximated by quadratic beziers.
+func (p *Path) Stroke(bld *gsdf.Builder, width float32) (glbuild.Shader2D, error) {
+	if width <= 0 {
+		return nil, errors.New("stroke width must be positive")
+	}
+	var shapes []glbuild.Shader2D
+	var lines [][2]ms2.Vec
+	addLine := func(a, b ms2.Vec) {
+		if a != b {
+			lines = append(lines, [2]ms2.Vec{a, b})
+		}
+	}
+	addQuad := func(a, b, c ms2.Vec) {
+		if isLinearQuad(a, b, c) {
+			addLine(a, c)
+		} else {
+			shapes = append(shapes, bld.NewQuadraticBezier2D(a, b, c, width))
+		}
+	}
+	for _, sp := range p.subpaths {
+		prev := sp.start
+		for _, seg := range sp.segs {
+			switch seg.kind {
+			case segLine:
+				addLine(prev, seg.p[0])
+			case segQuad:
+				addQuad(prev, seg.p[0], seg.p[1])
+			case segCubic:
+				// Split cubic in 4 and approximate each piece with a quadratic bezier.
+				c := [4]ms2.Vec{prev, seg.p[0], seg.p[1], seg.p[2]}
+				l, r := splitCubic(c)
+				ll, lr := splitCubic(l)
+				rl, rr := splitCubic(r)
+				for _, q := range [4][4]ms2.Vec{ll, lr, rl, rr} {
+					ctrl := ms2.Scale(0.25, ms2.Sub(ms2.Scale(3, ms2.Add(q[1], q[2])), ms2.Add(q[0], q[3])))
+					addQuad(q[0], ctrl, q[3])
+				}
+			}
+			prev = seg.end()
+		}
+		if sp.closed {
+			addLine(prev, sp.start)
+		}
+	}
+	switch len(lines) {
+	case 0:
+	case 1:
+		l := lines[0]
+		shapes = append(shapes, bld.NewLine2D(l[0].X, l[0].Y, l[1].X, l[1].Y, width))
+	default:
+		shapes = append(shapes, bld.NewLines2D(lines, width))
+	}
+	if len(shapes) == 0 {
+		return nil, errors.New("path has no stroke")
+	}
+	return union(bld, shapes), bld.Err()
+}
+
+// polygons flattens all subpaths into closed polygons.
+func (p *Path) polygons(curveTol float32) [][]ms2.Vec {
+	const maxDepth = 6
+	quadSampler := ms2.Spline3Sampler{Spline: ms2.SplineBezierQuadratic(), Tolerance: curveTol}
+	cubicSampler := ms2.Spline3Sampler{Spline: ms2.SplineBezierCubic(), Tolerance: curveTol}
+	var polys [][]ms2.Vec
+	for _, sp := range p.subpaths {
+		poly := []ms2.Vec{sp.start}
+		prev := sp.start
+		for _, seg := range sp.segs {
+			switch seg.kind {
+			case segQuad:
+				quadSampler.SetSplinePoints(prev, seg.p[0], seg.p[1], ms2.Vec{})
+				poly = quadSampler.SampleBisect(poly, maxDepth)
+			case segCubic:
+				cubicSampler.SetSplinePoints(prev, seg.p[0], seg.p[1], seg.p[2])
+				poly = cubicSampler.SampleBisect(poly, maxDepth)
+			}
+			prev = seg.end()
+			poly = append(poly, prev)
+		}
+		poly = dedupPolygon(poly)
+		if len(poly) >= 3 {
+			polys = append(polys, poly)
+		}
+	}
+	return polys
+}
+
+// fillPolygons builds the SDF of the area enclosed by polygons under a fill rule.
+func fillPolygons(bld *gsdf.Builder, polys [][]ms2.Vec, rule FillRule) (glbuild.Shader2D, error) {
+	type contour struct {
+		poly  []ms2.Vec
+		depth int
+		add   bool
+	}
+	var contours []contour
+	for i, poly := range polys {
+		area := signedArea(poly)
+		if area == 0 {
+			continue
+		}
+		// Sample a point to each side of the contour's longest edge.
+		var a, b ms2.Vec
+		var maxLen float32 = -1
+		for j := range poly {
+			v0, v1 := poly[j], poly[(j+1)%len(poly)]
+			if l := ms2.Norm(ms2.Sub(v1, v0)); l > maxLen {
+				a, b, maxLen = v0, v1, l
+			}
+		}
+		dir := ms2.Scale(1/maxLen, ms2.Sub(b, a))
+		interior := ms2.Vec{X: -dir.Y, Y: dir.X} // Left of edge is interior for counter-clockwise contours.
+		if area < 0 {
+			interior = ms2.Scale(-1, interior)
+		}
+		mid := ms2.Scale(0.5, ms2.Add(a, b))
+		eps := 1e-3 * maxLen
+		pin := ms2.Add(mid, ms2.Scale(eps, interior))
+		pout := ms2.Sub(mid, ms2.Scale(eps, interior))
+		var win, wout, depth int
+		for j, other := range polys {
+			w := windingNumber(other, pin)
+			win += w
+			wout += windingNumber(other, pout)
+			if j != i && w != 0 {
+				depth++
+			}
+		}
+		filledIn, filledOut := rule.filled(win), rule.filled(wout)
+		if filledIn == filledOut {
+			continue // Contour does not separate filled and empty regions.
+		}
+		contours = append(contours, contour{poly: poly, depth: depth, add: filledIn})
+	}
+	sort.SliceStable(contours, func(i, j int) bool { return contours[i].depth < contours[j].depth })
+
+	var shape glbuild.Shader2D
+	for i := 0; i < len(contours); {
+		// Contours of equal depth do not overlap so they are joined before being applied to the shape.
+		var adds, subs []glbuild.Shader2D
+		depth := contours[i].depth
+		for ; i < len(contours) && contours[i].depth == depth; i++ {
+			s := bld.NewPolygon(contours[i].poly)
+			if contours[i].add {
+				adds = append(adds, s)
+			} else {
+				subs = append(subs, s)
+			}
+		}
+		if len(adds) > 0 {
+			if shape == nil {
+				shape = union(bld, adds)
+			} else {
+				shape = bld.Union2D(shape, union(bld, adds))
+			}
+		}
+		if len(subs) > 0 && shape != nil {
+			shape = bld.Difference2D(shape, union(bld, subs))
+		}
+	}
+	if shape == nil {
+		return nil, errNoArea
+	}
+	return shape, bld.Err()
+}
+
+func union(bld *gsdf.Builder, shapes []glbuild.Shader2D) glbuild.Shader2D {
+	if len(shapes) == 1 {
+		return shapes[0]
+	}
+	return bld.Union2D(shapes...)
+}
+
+// windingNumber returns the winding number of the closed polygon around p.
+// See http://geomalgorithms.com/a03-_inclusion.html.
+func windingNumber(poly []ms2.Vec, p ms2.Vec) (wn int) {
+	j := len(poly) - 1
+	for i, v1 := range poly {
+		v0 := poly[j]
+		isLeft := ms2.Cross(ms2.Sub(v1, v0), ms2.Sub(p, v0))
+		if v0.Y <= p.Y {
+			if v1.Y > p.Y && isLeft > 0 {
+				wn++
+			}
+		} else if v1.Y <= p.Y && isLeft < 0 {
+			wn--
+		}
+		j = i
+	}
+	return wn
+}
+
+// signedArea returns the area of the polygon which is positive for counter-clockwise polygons.
+func signedArea(poly []ms2.Vec) (area float32) {
+	j := len(poly) - 1
+	for i, v := range poly {
+		area += ms2.Cross(poly[j], v)
+		j = i
+	}
+	return area / 2
+}
+
+// dedupPolygon removes consecutive repeated vertices of a closed polygon.
+func dedupPolygon(poly []ms2.Vec) []ms2.Vec {
+	if len(poly) == 0 {
+		return poly
+	}
+	out := poly[:1]
+	for _, v := range poly[1:] {
+		if v != out[len(out)-1] {
+			out = append(out, v)
+		}
+	}
+	for len(out) > 1 && out[0] == out[len(out)-1] {
+		out = out[:len(out)-1]
+	}
+	return out
+}
+
+// isLinearQuad reports whether the quadratic bezier is a straight line, which the
+// quadratic bezier SDF can not represent.
+func isLinearQuad(a, b, c ms2.Vec) bool {
+	const tol = 1e-5
+	curvature := ms2.Norm(ms2.Add(ms2.Sub(a, ms2.Scale(2, b)), c))
+	return curvature <= tol*ms2.Norm(ms2.Sub(c, a))
+}
+
+// splitCubic splits a cubic bezier in two halves using de Casteljau's algorithm.
+func splitCubic(c [4]ms2.Vec) (left, right [4]ms2.Vec) {
+	mid := func(a, b ms2.Vec) ms2.Vec { return ms2.Scale(0.5, ms2.Add(a, b)) }
+	p01, p12, p23 := mid(c[0], c[1]), mid(c[1], c[2]), mid(c[2], c[3])
+	p012, p123 := mid(p01, p12), mid(p12, p23)
+	p0123 := mid(p012, p123)
+	return [4]ms2.Vec{c[0], p01, p012, p0123}, [4]ms2.Vec{p0123, p123, p23, c[3]}
+}
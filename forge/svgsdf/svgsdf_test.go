@@ -0,0 +1,214 @@
+package svgsdf
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	math "github.com/chewxy/math32"
+	"github.com/soypat/geometry/ms2"
+	"github.com/soypat/gsdf"
+	"github.com/soypat/gsdf/glbuild"
+	"github.com/soypat/gsdf/gleval"
+)
+
+var bld gsdf.Builder
+
+func TestParsePath(t *testing.T) {
+	for _, test := range []struct {
+		d      string
+		bounds ms2.Box
+	}{
+		{d: "M0 0 L1 0 L1 1Z", bounds: ms2.NewBox(0, 0, 1, 1)},
+		{d: "m1,1 h2 v3 H1 z", bounds: ms2.NewBox(1, 1, 3, 4)},
+		{d: "M0 0 1 0 1-1", bounds: ms2.NewBox(0, -1, 1, 0)},
+		{d: "M0,0C0,1 1,1 1,0S2,-1 2,0", bounds: ms2.NewBox(0, -1, 2, 1)},
+		{d: "M0 0Q1 1 2 0T4 0", bounds: ms2.NewBox(0, -1, 4, 1)},
+		{d: "M-1 0A1 1 0 1 0 1 0A1 1 0 1 0-1 0z", bounds: ms2.NewBox(-1, -1, 1, 1)},
+		{d: "M0 0a.5.5 0 00 1 0", bounds: ms2.NewBox(0, 0, 1, 0.5)},
+	} {
+		path, err := ParsePath(test.d)
+		if err != nil {
+			t.Errorf("%q: %s", test.d, err)
+			continue
+		}
+		got := path.Bounds()
+		if !got.Equal(test.bounds, 0.01) {
+			t.Errorf("%q: want bounds %+v, got %+v", test.d, test.bounds, got)
+		}
+	}
+	for _, bad := range []string{"", "0 0", "M0", "M0 0 L1", "M0 0 A1 1 0 2 0 1 1", "M0 0 Z 1 1"} {
+		_, err := ParsePath(bad)
+		if err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestParseTransform(t *testing.T) {
+	p := ms2.Vec{X: 1, Y: 2}
+	for _, test := range []struct {
+		s    string
+		want ms2.Vec
+	}{
+		{s: "translate(1)", want: ms2.Vec{X: 2, Y: 2}},
+		{s: "translate(1, 3) scale(2)", want: ms2.Vec{X: 3, Y: 7}},
+		{s: "scale(2,-1)", want: ms2.Vec{X: 2, Y: -2}},
+		{s: "rotate(90)", want: ms2.Vec{X: -2, Y: 1}},
+		{s: "rotate(90 1 2)", want: p},
+		{s: "matrix(1 0 0 1 5 6)", want: ms2.Vec{X: 6, Y: 8}},
+		{s: "skewX(45)", want: ms2.Vec{X: 3, Y: 2}},
+	} {
+		tr, err := ParseTransform(test.s)
+		if err != nil {
+			t.Errorf("%q: %s", test.s, err)
+			continue
+		}
+		got := tr.Apply(p)
+		if !ms2.EqualElem(got, test.want, 1e-5) {
+			t.Errorf("%q: want %v, got %v", test.s, test.want, got)
+		}
+	}
+}
+
+func TestFillRule(t *testing.T) {
+	// Two nested squares with same orientation.
+	path, err := ParsePath("M-2-2H2V2H-2Z M-1-1H1V1H-1Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	outside, ring, center := ms2.Vec{X: 3}, ms2.Vec{X: 1.5}, ms2.Vec{}
+	nonzero, err := path.Fill(&bld, FillNonZero, 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := evalSDF2(t, nonzero, outside, ring, center)
+	if d[0] <= 0 || d[1] >= 0 || d[2] >= 0 {
+		t.Errorf("nonzero fill: expected outside positive, ring and center negative, got %v", d)
+	}
+	evenodd, err := path.Fill(&bld, FillEvenOdd, 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d = evalSDF2(t, evenodd, outside, ring, center)
+	if d[0] <= 0 || d[1] >= 0 || d[2] <= 0 {
+		t.Errorf("evenodd fill: expected outside and center positive, ring negative, got %v", d)
+	}
+	if math.Abs(d[2]-1) > 1e-5 {
+		t.Errorf("evenodd fill: expected distance 1 at center of hole, got %v", d[2])
+	}
+
+	// Inner square with opposite orientation is a hole for both fill rules.
+	path, err = ParsePath("M-2-2H2V2H-2Z M-1-1V1H1V-1Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonzero, err = path.Fill(&bld, FillNonZero, 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d = evalSDF2(t, nonzero, outside, ring, center)
+	if d[0] <= 0 || d[1] >= 0 || d[2] <= 0 {
+		t.Errorf("nonzero fill with reversed hole: expected outside and center positive, ring negative, got %v", d)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	const doc = `<svg xmlns="http://www.w3.org/2000/svg" width="100" height="100">
+<defs><rect width="1000" height="1000"/></defs>
+<g transform="translate(50,50)" style="fill-rule:evenodd">
+	<path d="M-20-20h40v40h-40z M-10-10h20v20h-20z"/>
+	<circle cx="0" cy="-40" r="5"/>
+	<line x1="30" y1="0" x2="40" y2="0" stroke="black" stroke-width="2"/>
+</g>
+</svg>`
+	shape, err := Decode(strings.NewReader(doc), Config{Builder: &bld})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Y axis is flipped.
+	d := evalSDF2(t, shape,
+		ms2.Vec{X: 50, Y: -50},  // Hole center.
+		ms2.Vec{X: 35, Y: -50},  // Ring.
+		ms2.Vec{X: 50, Y: -10},  // Circle center.
+		ms2.Vec{X: 85, Y: -50},  // Line.
+		ms2.Vec{X: 50, Y: -200}, // Outside.
+	)
+	if d[0] <= 0 || d[1] >= 0 || d[2] >= 0 || d[3] >= 0 || d[4] <= 0 {
+		t.Errorf("unexpected distances %v", d)
+	}
+	if math.Abs(d[2]+5) > 0.1 {
+		t.Errorf("circle center want distance -5, got %v", d[2])
+	}
+}
+
+func TestParseLength(t *testing.T) {
+	for _, test := range []struct {
+		s    string
+		want float32
+	}{
+		{s: "12", want: 12},
+		{s: " 12px ", want: 12},
+		{s: "1in", want: 96},
+		{s: "2.54cm", want: 96},
+		{s: "25.4mm", want: 96},
+		{s: "72pt", want: 96},
+		{s: "6pc", want: 96},
+		{s: "-1e1", want: -10},
+	} {
+		got, err := parseLength(test.s)
+		if err != nil {
+			t.Errorf("%q: %s", test.s, err)
+		} else if math.Abs(got-test.want) > 1e-4 {
+			t.Errorf("%q: want %v, got %v", test.s, test.want, got)
+		}
+	}
+	for _, rel := range []string{"1%", "2em", "1ex"} {
+		_, err := parseLength(rel)
+		if !errors.Is(err, errRelativeLength) {
+			t.Errorf("%q: expected relative length error, got %v", rel, err)
+		}
+	}
+	_, err := parseLength("1furlong")
+	if err == nil {
+		t.Error("expected error for unknown unit")
+	}
+}
+
+func TestDecodeRoundedRect(t *testing.T) {
+	const doc = `<svg xmlns="http://www.w3.org/2000/svg">
+<rect x="10mm" y="0" width="40" height="20" rx="5" transform="scale(1,-1)"/>
+<rect x="0" y="50" width="100%" height="10" stroke-width="50%" stroke="none"/>
+</svg>`
+	shape, err := Decode(strings.NewReader(doc), Config{Builder: &bld})
+	if err != nil {
+		t.Fatal(err)
+	}
+	x0 := float32(10 * 96 / 25.4)
+	d := evalSDF2(t, shape,
+		ms2.Vec{X: x0 + 20, Y: 10},   // Center.
+		ms2.Vec{X: x0 + 0.5, Y: 0.5}, // Cut off by rounded corner.
+		ms2.Vec{X: x0 + 20, Y: 0.5},  // Inside near straight edge.
+		ms2.Vec{X: x0 + 5, Y: 5},     // Corner arc center.
+	)
+	if d[0] >= 0 || d[1] <= 0 || d[2] >= 0 {
+		t.Errorf("unexpected distances %v", d)
+	}
+	if math.Abs(d[3]+5) > 0.1 {
+		t.Errorf("corner arc center want distance -5, got %v", d[3])
+	}
+}
+
+func evalSDF2(t *testing.T, s glbuild.Shader2D, pos ...ms2.Vec) []float32 {
+	t.Helper()
+	sdf, err := gleval.NewCPUSDF2(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dist := make([]float32, len(pos))
+	err = sdf.Evaluate(pos, dist, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dist
+}
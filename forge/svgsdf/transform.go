@@ -0,0 +1,119 @@
+package svgsdf
+
+import (
+	"fmt"
+	"strings"
+
+	math "github.com/chewxy/math32"
+	"github.com/soypat/geometry/ms2"
+)
+
+// Transform is a 2D affine transform with the same element order as the SVG matrix(a,b,c,d,e,f) transform:
+//
+//	x' = a*x + c*y + e
+//	y' = b*x + d*y + f
+type Transform [6]float32
+
+// IdentityTransform returns the transform that leaves points unchanged.
+func IdentityTransform() Transform {
+	return Transform{1, 0, 0, 1, 0, 0}
+}
+
+// Apply returns the point v transformed by t.
+func (t Transform) Apply(v ms2.Vec) ms2.Vec {
+	return ms2.Vec{
+		X: t[0]*v.X + t[2]*v.Y + t[4],
+		Y: t[1]*v.X + t[3]*v.Y + t[5],
+	}
+}
+
+// Mul returns the composition of t and b such that b is applied first and t second.
+func (t Transform) Mul(b Transform) Transform {
+	return Transform{
+		t[0]*b[0] + t[2]*b[1],
+		t[1]*b[0] + t[3]*b[1],
+		t[0]*b[2] + t[2]*b[3],
+		t[1]*b[2] + t[3]*b[3],
+		t[0]*b[4] + t[2]*b[5] + t[4],
+		t[1]*b[4] + t[3]*b[5] + t[5],
+	}
+}
+
+// ParseTransform parses the contents of a SVG transform attribute. A list of transforms is composed
+// in order of appearance so that the rightmost transform is applied first, as specified by SVG.
+// Supported transforms are matrix, translate, scale, rotate, skewX and skewY.
+func ParseTransform(s string) (Transform, error) {
+	result := IdentityTransform()
+	sc := pathScanner{s: s}
+	for {
+		sc.skipSep()
+		if sc.done() {
+			break
+		}
+		open := strings.IndexByte(sc.s[sc.i:], '(')
+		if open < 0 {
+			return result, fmt.Errorf("transform missing '(' at %d", sc.i)
+		}
+		name := strings.TrimSpace(sc.s[sc.i : sc.i+open])
+		sc.i += open + 1
+		var args [6]float32
+		n := 0
+		for {
+			sc.skipSep()
+			if sc.done() {
+				return result, fmt.Errorf("transform %q missing ')'", name)
+			}
+			if sc.s[sc.i] == ')' {
+				sc.i++
+				break
+			}
+			if n == len(args) {
+				return result, fmt.Errorf("too many arguments to transform %q", name)
+			}
+			v, err := sc.number()
+			if err != nil {
+				return result, fmt.Errorf("transform %q: %w", name, err)
+			}
+			args[n] = v
+			n++
+		}
+		var t Transform
+		badArgs := false
+		switch name {
+		case "matrix":
+			badArgs = n != 6
+			t = Transform(args)
+		case "translate":
+			badArgs = n != 1 && n != 2
+			t = Transform{1, 0, 0, 1, args[0], args[1]}
+		case "scale":
+			badArgs = n != 1 && n != 2
+			sy := args[1]
+			if n == 1 {
+				sy = args[0]
+			}
+			t = Transform{args[0], 0, 0, sy, 0, 0}
+		case "rotate":
+			badArgs = n != 1 && n != 3
+			s, c := math.Sincos(args[0] * math.Pi / 180)
+			t = Transform{c, s, -s, c, 0, 0}
+			if n == 3 {
+				cx, cy := args[1], args[2]
+				t = Transform{1, 0, 0, 1, cx, cy}.Mul(t).Mul(Transform{1, 0, 0, 1, -cx, -cy})
+			}
+		case "skewX":
+			badArgs = n != 1
+			t = Transform{1, 0, math.Tan(args[0] * math.Pi / 180), 1, 0, 0}
+		case "skewY":
+			badArgs = n != 1
+			t = Transform{1, math.Tan(args[0] * math.Pi / 180), 0, 1, 0, 0}
+		default:
+			return result, fmt.Errorf("unknown transform %q", name)
+		}
+		if badArgs {
+			return result, fmt.Errorf("transform %q got %d arguments", name, n)
+		}
+		result = result.Mul(t)
+	}
+	return result, nil
+}
@@ -95,6 +95,32 @@ func Screw(bld *gsdf.Builder, length float32, thread Threader) (glbuild.Shader3D
 	return &s, nil
 }
 
+func init() {
+	gsdf.RegisterShape("threads.screw", newScrew, "thread", "pitch", "lead", "length", "taper")
+}
+
+// newScrew creates a screw from the parameters returned by its Describe method.
+func newScrew(bld *gsdf.Builder, thread glbuild.Shader2D, pitch, lead, length, taper float32) (glbuild.Shader3D, error) {
+	if thread == nil {
+		return nil, errors.New("nil thread")
+	}
+	if length <= 0 || pitch <= 0 {
+		return nil, errors.New("need greater than zero length and pitch")
+	}
+	return &screw{thread: thread, pitch: pitch, lead: lead, lengthDiv2: length / 2, taper: taper}, nil
+}
+
+// Describe implements [glbuild.Describer] so that screws can be encoded with [gsdf.EncodeJSON].
+func (s *screw) Describe() (string, []glbuild.Param) {
+	return "threads.screw", []glbuild.Param{
+		{Name: "thread", Value: s.thread},
+		{Name: "pitch", Value: s.pitch},
+		{Name: "lead", Value: s.lead},
+		{Name: "length", Value: 2 * s.lengthDiv2},
+		{Name: "taper", Value: s.taper},
+	}
+}
+
 func (s *screw) AppendShaderObjects(objects []glbuild.ShaderObject) []glbuild.ShaderObject {
 	return objects
 }
@@ -1,6 +1,7 @@
 package threads
 
 import (
+	"bytes"
 	"math"
 	"testing"
 
@@ -42,3 +43,25 @@ func TestScrew(t *testing.T) {
 		t.Error("expected inside of SDF", inside)
 	}
 }
+
+func TestScrewJSON(t *testing.T) {
+	iso := ISO{D: 1, P: 0.1, Ext: true}
+	screw, err := Screw(&bld, 2, iso)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc bytes.Buffer
+	err = gsdf.EncodeJSON(&doc, bld.Translate(screw, 0, 0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded := doc.String()
+	got, err := bld.DecodeJSON(&doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := string(bld.Translate(screw, 0, 0, 1).AppendShaderName(nil))
+	if name := string(got.AppendShaderName(nil)); name != want {
+		t.Errorf("mismatched decoded screw name %q, want %q\n%s", name, want, encoded)
+	}
+}
@@ -81,6 +81,29 @@ type Shader2D interface {
 	Bounds() ms2.Box
 }
 
+// Param is a named argument of the function that constructs a [Shader].
+type Param struct {
+	Name  string
+	Value any
+}
+
+// Describer is implemented by shaders that can report how they were constructed.
+// Describe returns the name of the constructor function and the arguments passed to it in order.
+// Arguments which are themselves shaders (i.e: a [Shader3D], [Shader2D] or a slice of these) are the node's children.
+type Describer interface {
+	Describe() (fn string, params []Param)
+}
+
+// Describe returns the constructor name and parameters of s if it implements [Describer].
+// An empty name is returned otherwise.
+func Describe(s Shader) (fn string, params []Param) {
+	d, ok := s.(Describer)
+	if !ok {
+		return "", nil
+	}
+	return d.Describe()
+}
+
 // shader3D2D can create SDF shader source code for a operation that receives 2D
 // shaders to generate a 3D shape.
 type shader3D2D interface {
@@ -123,6 +146,14 @@ func MakeShaderFunction(shaderDef []byte) (sf ShaderObject, err error) {
 func (ssbo ShaderObject) IsFunction() bool { return len(ssbo.funcSource) > 0 }
 func (ssbo ShaderObject) IsBindable() bool { return !ssbo.IsFunction() }
 
+// dataBytes returns the buffer data as bytes or nil for functions.
+func (ssbo ShaderObject) dataBytes() []byte {
+	if ssbo.Data == nil {
+		return nil
+	}
+	return unsafe.Slice((*byte)(ssbo.Data), ssbo.Size)
+}
+
 func MakeShaderBufferReadOnly[T any](namePtr []byte, data []T) (ssbo ShaderObject, err error) {
 	var z T
 	ssbo = ShaderObject{
@@ -331,7 +362,7 @@ func (p *Programmer) writeShaders(w io.Writer, nodes []Shader) (n int, objs []Sh
 						break // conflicting function name.
 					}
 					// Conflict found!
-					if obj.Data == old.Data && obj.Size == old.Size && obj.Element == old.Element {
+					if obj.Size == old.Size && obj.Element == old.Element && bytes.Equal(obj.dataBytes(), old.dataBytes()) {
 						continue OBJWRITE // Skip this object, is duplicate and already has been added.
 					}
 					break // Conflict is not identical.
@@ -391,44 +422,52 @@ func (p *Programmer) writeShaders(w io.Writer, nodes []Shader) (n int, objs []Sh
 			return n, nil, err
 		}
 	}
-	objs = append(objs[:0], p.objsScratch...) // Clone slice and return it.
+	for _, obj := range p.objsScratch {
+		if obj.IsBindable() && obj.Binding == -1 {
+			continue // Duplicate buffer bound by an identical buffer.
+		}
+		objs = append(objs, obj) // Clone slice and return it.
+	}
 	return n, objs, err
 }
 
 const shorteningBufsize = 1024
 
+// ShortenNames3D replaces shaders in the tree rooted at root whose names are longer than maxRewriteLen
+// with shaders of shortened names. Shortened names are derived from the name and body of the shader.
+// Children are renamed before their parents so that names depend only on the structure of the tree.
 func ShortenNames3D(root *Shader3D, maxRewriteLen int) error {
-	scratch := make([]byte, shorteningBufsize)
-	rewrite3 := func(a any, s3 *Shader3D) error {
-		scratch = rewriteName3(s3, scratch, maxRewriteLen)
-		return nil
-	}
-	rewrite2 := func(a any, s2 *Shader2D) error {
-		scratch = rewriteName2(s2, scratch, maxRewriteLen)
-		return nil
-	}
-	err := forEachNodeBFS(*root, rewrite3, rewrite2)
+	r, err := shortenNames(*root, maxRewriteLen)
 	if err != nil {
 		return err
 	}
-	return rewrite3(nil, root)
+	*root = r.(Shader3D)
+	return nil
 }
 
+// ShortenNames2D is the 2D counterpart of [ShortenNames3D].
 func ShortenNames2D(root *Shader2D, maxRewriteLen int) error {
-	scratch := make([]byte, shorteningBufsize)
-	rewrite3 := func(a any, s3 *Shader3D) error {
-		scratch = rewriteName3(s3, scratch, maxRewriteLen)
-		return nil
-	}
-	rewrite2 := func(a any, s2 *Shader2D) error {
-		scratch = rewriteName2(s2, scratch, maxRewriteLen)
-		return nil
-	}
-	err := forEachNodeBFS(*root, rewrite3, rewrite2)
+	r, err := shortenNames(*root, maxRewriteLen)
 	if err != nil {
 		return err
 	}
-	return rewrite2(nil, root)
+	*root = r.(Shader2D)
+	return nil
+}
+
+func shortenNames(root Shader, maxRewriteLen int) (Shader, error) {
+	scratch := make([]byte, shorteningBufsize)
+	return Rewrite(root, func(s Shader) (Shader, error) {
+		switch sd := s.(type) {
+		case Shader3D:
+			scratch = rewriteName3(&sd, scratch, maxRewriteLen)
+			return sd, nil
+		case Shader2D:
+			scratch = rewriteName2(&sd, scratch, maxRewriteLen)
+			return sd, nil
+		}
+		return nil, fmt.Errorf("found shader %T that does not implement Shader3D nor Shader2D", s)
+	})
 }
 
 func rewriteName3(s3 *Shader3D, scratch []byte, rewritelen int) []byte {
@@ -727,59 +766,6 @@ func AppendAllNodes(dst []Shader, root Shader) ([]Shader, error) {
 	return dst, nil
 }
 
-func forEachNodeBFS(root Shader, fn3 func(userData any, s3 *Shader3D) error, fn2 func(userData any, s2 *Shader2D) error) error {
-	var userData any
-	children := []Shader{root}
-	nextChild := 0
-	nilChild := errors.New("got nil child in AppendAllNodes")
-	for len(children[nextChild:]) > 0 {
-		newChildren := children[nextChild:]
-		for _, obj := range newChildren {
-			nextChild++
-			obj3, ok3 := obj.(Shader3D)
-			obj2, ok2 := obj.(Shader2D)
-			if !ok2 && !ok3 {
-				return fmt.Errorf("found shader %T that does not implement Shader3D nor Shader2D", obj)
-			}
-			var err error
-			if ok3 {
-				// Got Shader3D in obj.
-				err = obj3.ForEachChild(userData, func(userData any, s *Shader3D) error {
-					if s == nil || *s == nil {
-						return nilChild
-					}
-					children = append(children, *s)
-					return fn3(userData, s)
-				})
-				if obj32, ok32 := obj.(shader3D2D); ok32 {
-					// The Shader3D obj contains Shader2D children, such is case for 2D->3D operations i.e: revolution and extrusion operations.
-					err = obj32.ForEach2DChild(userData, func(userData any, s *Shader2D) error {
-						if s == nil || *s == nil {
-							return nilChild
-						}
-						children = append(children, *s)
-						return fn2(userData, s)
-					})
-				}
-			}
-			if err == nil && !ok3 && ok2 {
-				// Got Shader2D in obj.
-				err = obj2.ForEach2DChild(userData, func(userData any, s *Shader2D) error {
-					if s == nil || *s == nil {
-						return nilChild
-					}
-					children = append(children, *s)
-					return fn2(userData, s)
-				})
-			}
-			if err != nil {
-				return err
-			}
-		}
-	}
-	return nil
-}
-
 func forEachNodeDFS(obj Shader, fnEnter3, fnExit3 func(s3 Shader3D) error, fnEnter2, fnExit2 func(s2 Shader2D) error) (err error) {
 	var userData any
 	obj3, ok3 := obj.(Shader3D)
@@ -1099,6 +1085,11 @@ func (ob3 *overloadBounds3) Evaluate(pos []ms3.Vec, dist []float32, userData any
 	}
 	return sdf.Evaluate(pos, dist, userData)
 }
+
+// EvaluateInterval implements the gleval.SDF3Interval interface.
+func (ob3 *overloadBounds3) EvaluateInterval(boxes []ms3.Box, lo, hi []float32, userData any) error {
+	return evaluateInterval3(ob3.Shader3D, boxes, lo, hi, userData)
+}
 func (ob3 *overloadBounds3) unwrap() Shader { return ob3.Shader3D }
 
 // OverloadShader2DBounds overloads a [Shader2D] Bounds method with the argument bounding box.
@@ -1125,6 +1116,11 @@ func (ob3 *overloadBounds2) Evaluate(pos []ms2.Vec, dist []float32, userData any
 	return sdf.Evaluate(pos, dist, userData)
 }
 
+// EvaluateInterval implements the gleval.SDF2Interval interface.
+func (ob2 *overloadBounds2) EvaluateInterval(boxes []ms2.Box, lo, hi []float32, userData any) error {
+	return evaluateInterval2(ob2.Shader2D, boxes, lo, hi, userData)
+}
+
 func (ob2 *overloadBounds2) unwrap() Shader { return ob2.Shader2D }
 
 var _ Shader3D = (*CachedShader3D)(nil) // Interface implementation compile-time check.
@@ -1187,6 +1183,14 @@ func (c3 *CachedShader3D) Evaluate(pos []ms3.Vec, dist []float32, userData any)
 	return sdf.Evaluate(pos, dist, userData)
 }
 
+// EvaluateInterval implements the gleval.SDF3Interval interface.
+func (c3 *CachedShader3D) EvaluateInterval(boxes []ms3.Box, lo, hi []float32, userData any) error {
+	return evaluateInterval3(c3.Shader, boxes, lo, hi, userData)
+}
+
+// Describe calls the underlying Shader's Describe method. Implements [Describer].
+func (c3 *CachedShader3D) Describe() (string, []Param) { return Describe(c3.Shader) }
+
 func (c3 *CachedShader3D) unwrap() Shader { return c3.Shader }
 
 var _ Shader2D = (*CachedShader2D)(nil) // Interface implementation compile-time check.
@@ -1234,11 +1238,19 @@ func (c2 *CachedShader2D) Evaluate(pos []ms2.Vec, dist []float32, userData any)
 	return sdf.Evaluate(pos, dist, userData)
 }
 
+// EvaluateInterval implements the gleval.SDF2Interval interface.
+func (c2 *CachedShader2D) EvaluateInterval(boxes []ms2.Box, lo, hi []float32, userData any) error {
+	return evaluateInterval2(c2.Shader, boxes, lo, hi, userData)
+}
+
 // AppendShaderObjects returns the underlying [Shader]'s buffer declarations.
 func (c2 *CachedShader2D) AppendShaderObjects(objs []ShaderObject) []ShaderObject {
 	return c2.Shader.AppendShaderObjects(objs)
 }
 
+// Describe calls the underlying Shader's Describe method. Implements [Describer].
+func (c2 *CachedShader2D) Describe() (string, []Param) { return Describe(c2.Shader) }
+
 func (c2 *CachedShader2D) unwrap() Shader { return c2.Shader }
 
 type nameOverloadShader3D struct {
@@ -1282,8 +1294,30 @@ type (
 	sdf2 interface {
 		Evaluate(pos []ms2.Vec, dist []float32, userData any) error
 	}
+	sdf3Interval interface {
+		EvaluateInterval(boxes []ms3.Box, lo, hi []float32, userData any) error
+	}
+	sdf2Interval interface {
+		EvaluateInterval(boxes []ms2.Box, lo, hi []float32, userData any) error
+	}
 )
 
+func evaluateInterval3(s Shader3D, boxes []ms3.Box, lo, hi []float32, userData any) error {
+	sdf, ok := s.(sdf3Interval)
+	if !ok {
+		return fmt.Errorf("%T does not implement gleval.SDF3Interval", s)
+	}
+	return sdf.EvaluateInterval(boxes, lo, hi, userData)
+}
+
+func evaluateInterval2(s Shader2D, boxes []ms2.Box, lo, hi []float32, userData any) error {
+	sdf, ok := s.(sdf2Interval)
+	if !ok {
+		return fmt.Errorf("%T does not implement gleval.SDF2Interval", s)
+	}
+	return sdf.EvaluateInterval(boxes, lo, hi, userData)
+}
+
 func (nos3 *nameOverloadShader3D) Evaluate(pos []ms3.Vec, dist []float32, userData any) error {
 	sdf, ok := nos3.Shader.(sdf3)
 	if !ok {
@@ -1292,10 +1326,16 @@ func (nos3 *nameOverloadShader3D) Evaluate(pos []ms3.Vec, dist []float32, userDa
 	return sdf.Evaluate(pos, dist, userData)
 }
 
+func (nos3 *nameOverloadShader3D) EvaluateInterval(boxes []ms3.Box, lo, hi []float32, userData any) error {
+	return evaluateInterval3(nos3.Shader, boxes, lo, hi, userData)
+}
+
 func (nos3 *nameOverloadShader3D) AppendShaderName(b []byte) []byte {
 	return append(b, nos3.name...)
 }
 
+func (nos3 *nameOverloadShader3D) Describe() (string, []Param) { return Describe(nos3.Shader) }
+
 func (nos3 *nameOverloadShader3D) unwrap() Shader { return nos3.Shader }
 
 type nameOverloadShader2D struct {
@@ -1325,11 +1365,17 @@ func (nos2 *nameOverloadShader2D) Evaluate(pos []ms2.Vec, dist []float32, userDa
 	return sdf.Evaluate(pos, dist, userData)
 }
 
+func (nos2 *nameOverloadShader2D) EvaluateInterval(boxes []ms2.Box, lo, hi []float32, userData any) error {
+	return evaluateInterval2(nos2.Shader, boxes, lo, hi, userData)
+}
+
 // AppendShaderObjects returns the underlying [Shader]'s buffer declarations.
 func (nos2 *nameOverloadShader2D) AppendShaderObjects(objs []ShaderObject) []ShaderObject {
 	return nos2.Shader.AppendShaderObjects(objs)
 }
 
+func (nos2 *nameOverloadShader2D) Describe() (string, []Param) { return Describe(nos2.Shader) }
+
 func (nos2 *nameOverloadShader2D) unwrap() Shader { return nos2.Shader }
 
 func hash(b []byte, in uint64) uint64 {
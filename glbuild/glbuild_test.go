@@ -69,3 +69,97 @@ func TestSprintShader(t *testing.T) {
 		t.Error(str)
 	}
 }
+
+func TestWalk(t *testing.T) {
+	bolt := bld.NewCylinder(0.2, 2, 0)
+	plate := bld.Extrude(bld.Difference2D(bld.NewRectangle(4, 4), bld.NewCircle(0.2)), 0.5)
+	assembly := bld.Union(plate, bolt, bld.Translate(bolt, 1, 0, 0))
+	primitives := 0
+	maxDepth := 0
+	bom := make(map[string]int)
+	err := glbuild.Walk(assembly, func(s glbuild.Shader, depth int) error {
+		n := glbuild.Inspect(s)
+		if n.IsPrimitive() {
+			primitives++
+			bom[n.Kind]++
+		}
+		maxDepth = max(maxDepth, depth)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if primitives != 4 {
+		t.Errorf("want 4 primitives, got %d", primitives)
+	}
+	if maxDepth != 3 {
+		t.Errorf("want depth 3, got %d", maxDepth)
+	}
+	if bom["NewCylinder"] != 2 || bom["NewRectangle"] != 1 || bom["NewCircle"] != 1 {
+		t.Errorf("unexpected bill of materials %v", bom)
+	}
+	n := glbuild.Inspect(bld.Translate(bolt, 1, 2, 3))
+	if n.Kind != "Translate" || len(n.Children) != 1 || len(n.Params) != 3 || n.Param("dirY") != float32(2) {
+		t.Errorf("unexpected node inspection %+v", n)
+	}
+	// Children of skipped nodes are not visited.
+	visited := 0
+	err = glbuild.Walk(assembly, func(s glbuild.Shader, depth int) error {
+		visited++
+		if depth == 1 {
+			return glbuild.SkipChildren
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if visited != 4 {
+		t.Errorf("want 4 visited nodes, got %d", visited)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	placeholder := bld.NewSphere(1)
+	part := bld.Union(placeholder, bld.Translate(placeholder, 2, 0, 0))
+	replacement := bld.NewBox(1, 1, 1, 0)
+	calls := 0
+	got, err := glbuild.Rewrite(part, func(s glbuild.Shader) (glbuild.Shader, error) {
+		calls++
+		if s == placeholder {
+			return replacement, nil
+		}
+		return s, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Errorf("want shared placeholder rewritten once for 3 calls, got %d calls", calls)
+	}
+	want3D := bld.Union(replacement, bld.Translate(replacement, 2, 0, 0))
+	if string(got.AppendShaderName(nil)) != string(want3D.AppendShaderName(nil)) {
+		t.Errorf("want %s, got %s", want3D.AppendShaderName(nil), got.AppendShaderName(nil))
+	}
+	// The original tree is left intact.
+	wantOriginal := bld.Union(placeholder, bld.Translate(placeholder, 2, 0, 0))
+	if string(part.AppendShaderName(nil)) != string(wantOriginal.AppendShaderName(nil)) {
+		t.Errorf("original tree modified: want %s, got %s", wantOriginal.AppendShaderName(nil), part.AppendShaderName(nil))
+	}
+	// Nodes without replaced descendants are kept as is.
+	same, err := glbuild.Rewrite(part, func(s glbuild.Shader) (glbuild.Shader, error) { return s, nil })
+	if err != nil {
+		t.Fatal(err)
+	} else if same != part {
+		t.Error("identity rewrite should return the original root")
+	}
+	// 3D nodes may not be replaced by 2D shaders.
+	_, err = glbuild.Rewrite(part, func(s glbuild.Shader) (glbuild.Shader, error) {
+		if _, ok := s.(glbuild.Shader3D); ok && glbuild.Inspect(s).IsPrimitive() {
+			return bld.NewCircle(1), nil
+		}
+		return s, nil
+	})
+	if err == nil {
+		t.Error("expected error replacing 3D shader with 2D shader")
+	}
+}
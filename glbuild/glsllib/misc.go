@@ -17,6 +17,18 @@ func WindingNumber() glbuild.ShaderObject {
 	return obj
 }
 
+//go:embed windingNZ.glsl
+var windingNZSrc []byte
+
+// WindingNonZero accumulates the winding number of directed edge v1->v2 around p in d_w.y
+// and the minimum squared distance to the edge in d_w.x. Used for nonzero fill rule polygons.
+//
+//	vec2 gsdfWindingNonZero(vec2 p, vec2 v1, vec2 v2, vec2 d_w)
+func WindingNonZero() glbuild.ShaderObject {
+	obj, _ := glbuild.MakeShaderFunction(windingNZSrc)
+	return obj
+}
+
 //go:embed linesq2D.glsl
 var line2DSrc []byte
 
@@ -27,3 +39,27 @@ func LineSquared2D() glbuild.ShaderObject {
 	obj, _ := glbuild.MakeShaderFunction(line2DSrc)
 	return obj
 }
+
+//go:embed pathpoint2D.glsl
+var pathPoint2DSrc []byte
+
+// PathPoint2D evaluates a path segment encoded as three vec4 at parameter t in 0..1.
+//
+//	vec2 gsdfPathPoint2D(vec4 s0, vec4 s1, vec4 s2, float t)
+func PathPoint2D() glbuild.ShaderObject {
+	obj, _ := glbuild.MakeShaderFunction(pathPoint2DSrc)
+	return obj
+}
+
+//go:embed pathseg2D.glsl
+var pathSeg2DSrc []byte
+
+// PathSegment2D accumulates the squared distance and winding number of a path segment
+// in d_w, much like [WindingNonZero] does for polygon edges. Depends on [WindingNonZero],
+// [QuadraticBezier2D] and [PathPoint2D].
+//
+//	vec2 gsdfPathSeg2D(vec2 p, vec4 s0, vec4 s1, vec4 s2, vec2 d_w)
+func PathSegment2D() glbuild.ShaderObject {
+	obj, _ := glbuild.MakeShaderFunction(pathSeg2DSrc)
+	return obj
+}
@@ -0,0 +1,19 @@
+vec2 gsdfPathPoint2D(vec4 s0, vec4 s1, vec4 s2, float t) {
+	int kind = int(s0.x);
+	if (t == 0.0) {
+		return s0.zw;
+	}
+	float u = 1.0-t;
+	if (kind == 0) {
+		return mix(s0.zw, s1.xy, t);
+	} else if (kind == 1) {
+		return u*u*s0.zw + 2.0*u*t*s1.xy + t*t*s1.zw;
+	} else if (kind == 2) {
+		return u*u*u*s0.zw + 3.0*u*t*(u*s1.xy + t*s1.zw) + t*t*t*s2.xy;
+	}
+	if (t == 1.0) {
+		return s1.xy;
+	}
+	float a = s2.y + t*s2.z;
+	return s1.zw + s2.x*vec2(cos(a), sin(a));
+}
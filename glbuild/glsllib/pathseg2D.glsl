@@ -0,0 +1,132 @@
+vec2 gsdfPathSeg2D(vec2 p, vec4 s0, vec4 s1, vec4 s2, vec2 d_w) {
+	int kind = int(s0.x);
+	if (kind == 0) {
+		return gsdfWindingNonZero(p, s0.zw, s1.xy, d_w);
+	}
+	vec2 a = s0.zw;
+	vec2 b;
+	float d2, xlo, xhi;
+	vec2 sp = vec2(0.0);
+	int n = 0;
+	if (kind == 1) {
+		b = s1.zw;
+		float d = gsdfBezierQ2D(p, a, s1.xy, b, 0.0);
+		d2 = d*d;
+		xlo = min(min(a.x, b.x), s1.x);
+		xhi = max(max(a.x, b.x), s1.x);
+		float den = a.y - 2.0*s1.y + b.y;
+		float t = (a.y - s1.y) / den;
+		if (den != 0.0 && t > 0.0 && t < 1.0) {
+			sp.x = t;
+			n = 1;
+		}
+	} else if (kind == 2) {
+		b = s2.xy;
+		// Coefficients of B(t) = ((A*t + B)*t + C)*t + D, relative to p.
+		vec2 A = b - 3.0*s1.zw + 3.0*s1.xy - a;
+		vec2 B = 3.0*(s1.zw - 2.0*s1.xy + a);
+		vec2 C = 3.0*(s1.xy - a);
+		vec2 D = a - p;
+		float tb = 0.0;
+		d2 = dot(D, D);
+		for (int i=1; i<=16; i++) {
+			float t = float(i)/16.0;
+			vec2 q = ((A*t + B)*t + C)*t + D;
+			float dq = dot(q, q);
+			if (dq < d2) {
+				d2 = dq;
+				tb = t;
+			}
+		}
+		// Newton refinement of closest point.
+		for (int i=0; i<4; i++) {
+			vec2 q = ((A*tb + B)*tb + C)*tb + D;
+			vec2 dq = (3.0*A*tb + 2.0*B)*tb + C;
+			float df = dot(dq, dq) + dot(q, 6.0*A*tb + 2.0*B);
+			if (df != 0.0) {
+				tb = clamp(tb - dot(q, dq)/df, 0.0, 1.0);
+			}
+		}
+		vec2 q = ((A*tb + B)*tb + C)*tb + D;
+		d2 = min(d2, dot(q, q));
+		xlo = min(min(a.x, b.x), min(s1.x, s1.z));
+		xhi = max(max(a.x, b.x), max(s1.x, s1.z));
+		// Y extrema are roots of the derivative qa*t^2 + qb*t + qc.
+		float ya = s1.y - a.y, yb = s1.w - s1.y, yc = b.y - s1.w;
+		float qa = ya - 2.0*yb + yc, qb = 2.0*(yb - ya), qc = ya;
+		vec2 roots = vec2(-1.0);
+		if (abs(qa) < 1e-12) {
+			if (qb != 0.0) {
+				roots.x = -qc/qb;
+			}
+		} else {
+			float disc = qb*qb - 4.0*qa*qc;
+			if (disc >= 0.0) {
+				disc = sqrt(disc);
+				roots = vec2(-qb - disc, -qb + disc) / (2.0*qa);
+			}
+		}
+		for (int i=0; i<2; i++) {
+			if (roots[i] > 0.0 && roots[i] < 1.0) {
+				sp[n] = roots[i];
+				n++;
+			}
+		}
+	} else {
+		b = s1.xy;
+		vec2 c = s1.zw;
+		float r = s2.x, a0 = s2.y, da = s2.z;
+		float mid = a0 + 0.5*da;
+		vec2 w = p - c;
+		float lw = length(w);
+		if (dot(w, vec2(cos(mid), sin(mid))) >= cos(0.5*abs(da))*lw) {
+			d2 = (lw-r)*(lw-r);
+		} else {
+			d2 = min(dot(p-a, p-a), dot(p-b, p-b));
+		}
+		xlo = c.x - r;
+		xhi = c.x + r;
+		// Y extrema at angles pi/2 + k*pi.
+		const float pi = 3.14159265358979;
+		float k0 = ceil((min(a0, a0+da) - 0.5*pi)/pi);
+		for (int i=0; i<3; i++) {
+			float t = (0.5*pi + (k0+float(i))*pi - a0) / da;
+			if (t > 0.0 && t < 1.0 && n < 2) {
+				sp[n] = t;
+				n++;
+			}
+		}
+	}
+	d_w.x = min(d_w.x, d2);
+	if (p.x >= xhi) {
+		return d_w;
+	} else if (p.x < xlo) {
+		// Upward crossings add to the winding number and downward crossings subtract.
+		d_w.y += float(a.y <= p.y) - float(b.y <= p.y);
+		return d_w;
+	}
+	if (n == 2 && sp.x > sp.y) {
+		sp = sp.yx;
+	}
+	// Accumulate signed ray crossings of y-monotone pieces.
+	for (int i=0; i<=n; i++) {
+		float ta = i==0 ? 0.0 : sp[max(i-1, 0)];
+		float tb = i==n ? 1.0 : sp[min(i, 1)];
+		bool below = gsdfPathPoint2D(s0, s1, s2, ta).y <= p.y;
+		if (below == (gsdfPathPoint2D(s0, s1, s2, tb).y <= p.y)) {
+			continue;
+		}
+		for (int k=0; k<24; k++) {
+			float tm = 0.5*(ta+tb);
+			if ((gsdfPathPoint2D(s0, s1, s2, tm).y <= p.y) == below) {
+				ta = tm;
+			} else {
+				tb = tm;
+			}
+		}
+		if (gsdfPathPoint2D(s0, s1, s2, 0.5*(ta+tb)).x > p.x) {
+			d_w.y += below ? 1.0 : -1.0;
+		}
+	}
+	return d_w;
+}
@@ -0,0 +1,16 @@
+vec2 gsdfWindingNonZero(vec2 p, vec2 v1, vec2 v2, vec2 d_w) {
+	vec2 e = v2 - v1;
+	vec2 w = p - v1;
+	vec2 b = w - e*clamp( dot(w,e)/dot(e,e), 0.0, 1.0 );
+	d_w.x = min( d_w.x, dot(b,b) );
+	// winding number from http://geomalgorithms.com/a03-_inclusion.html
+	float isLeft = e.x*w.y - e.y*w.x;
+	if (v1.y <= p.y) {
+		if (v2.y > p.y && isLeft > 0.0) {
+			d_w.y += 1.0;
+		}
+	} else if (v2.y <= p.y && isLeft < 0.0) {
+		d_w.y -= 1.0;
+	}
+	return d_w;
+}
@@ -0,0 +1,255 @@
+package glbuild
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"unsafe"
+)
+
+// SkipChildren is used as a return value from the function passed to [Walk] to
+// indicate that the children of the current node are not to be visited.
+// It is not returned as an error by Walk.
+var SkipChildren = errors.New("skip children")
+
+// Node is the result of inspecting a single [Shader] of a shape tree. See [Inspect].
+type Node struct {
+	Shader Shader
+	// Kind is the name of the shader's constructor as reported by [Describer].
+	// If the shader does not implement Describer Kind is the name of the shader's Go type.
+	Kind string
+	// Params are the constructor parameters of the shader which are not shaders themselves.
+	// Params is nil if the shader does not implement [Describer].
+	Params []Param
+	// Children are the direct children of the shader. 3D children are listed before 2D children.
+	Children []Shader
+}
+
+// IsPrimitive reports whether the node has no children.
+func (n Node) IsPrimitive() bool { return len(n.Children) == 0 }
+
+// Param returns the value of the parameter with the given name or nil if not found.
+func (n Node) Param(name string) any {
+	for _, p := range n.Params {
+		if p.Name == name {
+			return p.Value
+		}
+	}
+	return nil
+}
+
+// Inspect returns the kind, parameters and direct children of s.
+func Inspect(s Shader) Node {
+	n := Node{Shader: s}
+	fn, params := Describe(s)
+	if fn != "" {
+		n.Kind = fn
+		n.Params = make([]Param, 0, len(params))
+		for _, p := range params {
+			if !isShaderParam(p.Value) {
+				n.Params = append(n.Params, p)
+			}
+		}
+	} else {
+		tp := reflect.TypeOf(s)
+		if tp.Kind() == reflect.Pointer {
+			tp = tp.Elem()
+		}
+		n.Kind = tp.Name()
+	}
+	n.Children, _ = appendChildren(nil, s)
+	return n
+}
+
+// Walk calls fn for every node of the tree rooted at root in depth-first pre-order,
+// i.e: a node is visited before its children. depth is zero for root.
+// Nodes which appear more than once in the tree are visited once per appearance.
+// If fn returns [SkipChildren] the children of the node are not visited.
+// If fn returns any other non-nil error Walk stops and returns that error.
+func Walk(root Shader, fn func(s Shader, depth int) error) error {
+	if root == nil {
+		return errors.New("nil root shader")
+	}
+	return walk(root, 0, fn)
+}
+
+func walk(s Shader, depth int, fn func(s Shader, depth int) error) error {
+	err := fn(s, depth)
+	if err == SkipChildren {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	children, err := appendChildren(nil, s)
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		err = walk(child, depth+1, fn)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rewrite calls fn for every node of the tree rooted at root in depth-first post-order,
+// i.e: a node's children are rewritten before the node itself. The node is then replaced by
+// the shader returned by fn, which may be the node itself if it is to be kept. Replacements of 3D
+// nodes must implement [Shader3D] and replacements of 2D nodes must implement [Shader2D].
+//
+// The tree rooted at root is not modified. Nodes with a replaced child are copied and the copy
+// is passed to fn, so fn receives the original node only if none of its descendants were replaced.
+// Copies are shallow except for slices of children; shaders must therefore be pointers to structs
+// which hold no other state derived from their children. Nodes which appear more than once in the
+// tree are rewritten once and all appearances are replaced by the same shader. Rewrite returns the
+// replacement of root.
+func Rewrite(root Shader, fn func(s Shader) (Shader, error)) (Shader, error) {
+	if root == nil {
+		return nil, errors.New("nil root shader")
+	}
+	return rewrite(root, fn, make(map[Shader]Shader))
+}
+
+func rewrite(s Shader, fn func(s Shader) (Shader, error), replaced map[Shader]Shader) (Shader, error) {
+	memo := reflect.TypeOf(s).Comparable()
+	if memo {
+		if r, ok := replaced[s]; ok {
+			return r, nil
+		}
+	}
+	children, err := appendChildren(nil, s)
+	if err != nil {
+		return nil, err
+	}
+	changed := false
+	for i, child := range children {
+		if child == nil {
+			return nil, errors.New("got nil child in Rewrite")
+		}
+		children[i], err = rewrite(child, fn, replaced)
+		if err != nil {
+			return nil, err
+		}
+		changed = changed || children[i] != child
+	}
+	node := s
+	if changed {
+		node, err = copyShader(s)
+		if err != nil {
+			return nil, err
+		}
+		err = setChildren(node, children)
+		if err != nil {
+			return nil, err
+		}
+	}
+	r, err := fn(node)
+	if err != nil {
+		return nil, err
+	} else if r == nil {
+		return nil, fmt.Errorf("nil replacement for shader %T", s)
+	}
+	if memo {
+		replaced[s] = r
+	}
+	return r, nil
+}
+
+// setChildren replaces the direct children of s with children, which are in the order returned by appendChildren.
+func setChildren(s Shader, children []Shader) (err error) {
+	s3, ok3 := s.(Shader3D)
+	if ok3 {
+		err = s3.ForEachChild(nil, func(userData any, child *Shader3D) error {
+			r3, ok := children[0].(Shader3D)
+			if !ok {
+				return fmt.Errorf("replacement %T for 3D shader %T is not a Shader3D", children[0], *child)
+			}
+			*child = r3
+			children = children[1:]
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if s2, ok := s.(has2DChildren); ok {
+		err = s2.ForEach2DChild(nil, func(userData any, child *Shader2D) error {
+			r2, ok := children[0].(Shader2D)
+			if !ok {
+				return fmt.Errorf("replacement %T for 2D shader %T is not a Shader2D", children[0], *child)
+			}
+			*child = r2
+			children = children[1:]
+			return nil
+		})
+	}
+	return err
+}
+
+// copyShader returns a shallow copy of s in which slices of child shaders are also copied
+// so that replacing the children of the copy does not modify s.
+func copyShader(s Shader) (Shader, error) {
+	v := reflect.ValueOf(s)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot copy shader %T with replaced children: not a pointer to struct", s)
+	}
+	cp := reflect.New(v.Elem().Type())
+	cp.Elem().Set(v.Elem())
+	copyChildSlices(cp.Elem())
+	return cp.Interface().(Shader), nil
+}
+
+var shaderType = reflect.TypeOf((*Shader)(nil)).Elem()
+
+func copyChildSlices(v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		switch {
+		case f.Kind() == reflect.Struct:
+			copyChildSlices(f)
+		case f.Kind() == reflect.Slice && f.Type().Elem().Kind() == reflect.Interface && f.Type().Elem().Implements(shaderType):
+			// Unexported fields can't be set through reflection so use the field's address.
+			f = reflect.NewAt(f.Type(), unsafe.Pointer(f.UnsafeAddr())).Elem()
+			f.Set(reflect.AppendSlice(reflect.MakeSlice(f.Type(), 0, f.Len()), f))
+		}
+	}
+}
+
+// appendChildren appends the direct children of s to dst. 3D children are appended before 2D children.
+func appendChildren(dst []Shader, s Shader) ([]Shader, error) {
+	var err error
+	s3, ok3 := s.(Shader3D)
+	if ok3 {
+		err = s3.ForEachChild(nil, func(userData any, child *Shader3D) error {
+			dst = append(dst, *child)
+			return nil
+		})
+		if err != nil {
+			return dst, err
+		}
+	}
+	s2, ok2 := s.(has2DChildren)
+	if ok2 {
+		err = s2.ForEach2DChild(nil, func(userData any, child *Shader2D) error {
+			dst = append(dst, *child)
+			return nil
+		})
+	} else if !ok3 {
+		err = fmt.Errorf("found shader %T that does not implement Shader3D nor Shader2D", s)
+	}
+	return dst, err
+}
+
+// has2DChildren is implemented by [Shader2D] and by 3D shaders built from 2D shaders such as extrusions.
+type has2DChildren interface {
+	ForEach2DChild(userData any, fn func(userData any, s *Shader2D) error) error
+}
+
+func isShaderParam(v any) bool {
+	switch v.(type) {
+	case Shader, []Shader3D, []Shader2D:
+		return true
+	}
+	return false
+}
@@ -75,6 +75,26 @@ func AssertSDF2(s bounder2) (SDF2, error) {
 	return evaluator, nil
 }
 
+// AssertSDF3Interval returns the argument as a [SDF3Interval], or an error naming the concrete type
+// if it does not implement interval evaluation.
+func AssertSDF3Interval(s bounder3) (SDF3Interval, error) {
+	evaluator, ok := s.(SDF3Interval)
+	if !ok {
+		return nil, fmt.Errorf("%T does not implement 3D interval evaluator", s)
+	}
+	return evaluator, nil
+}
+
+// AssertSDF2Interval returns the argument as a [SDF2Interval], or an error naming the concrete type
+// if it does not implement interval evaluation.
+func AssertSDF2Interval(s bounder2) (SDF2Interval, error) {
+	evaluator, ok := s.(SDF2Interval)
+	if !ok {
+		return nil, fmt.Errorf("%T does not implement 2D interval evaluator", s)
+	}
+	return evaluator, nil
+}
+
 // SDF3CPU wraps a [SDF3] with an owned [VecPool], providing a self-contained
 // CPU evaluator. Construct with [NewCPUSDF3].
 type SDF3CPU struct {
@@ -117,6 +137,36 @@ func (sdf *SDF3CPU) Evaluate(pos []ms3.Vec, dist []float32, userData any) error
 	return nil
 }
 
+// EvaluateInterval computes a guaranteed distance range over each box in boxes, writing
+// the results into lo and hi. It returns an error if the underlying SDF does not implement [SDF3Interval].
+//
+// userData is handled in the same way as in [SDF3CPU.Evaluate].
+func (sdf *SDF3CPU) EvaluateInterval(boxes []ms3.Box, lo, hi []float32, userData any) error {
+	isdf, err := AssertSDF3Interval(sdf.SDF)
+	if err != nil {
+		return err
+	}
+	useOwnVecPool := userData == nil
+	if useOwnVecPool {
+		userData = &sdf.vp
+	}
+	if len(boxes) != len(lo) || len(boxes) != len(hi) {
+		return errMismatchBufferLength
+	} else if len(boxes) == 0 {
+		return errEmptyBuffers
+	}
+	err = isdf.EvaluateInterval(boxes, lo, hi, userData)
+	if useOwnVecPool {
+		err2 := sdf.vp.AssertAllReleased()
+		if err2 != nil && err != nil {
+			return fmt.Errorf("VecPool leak: %s\nSDF3 error: %s", err2, err)
+		} else if err2 != nil {
+			return err2
+		}
+	}
+	return err
+}
+
 // Bounds returns the bounding box of the underlying SDF.
 func (sdf *SDF3CPU) Bounds() ms3.Box {
 	return sdf.SDF.Bounds()
@@ -170,6 +220,36 @@ func (sdf *SDF2CPU) Evaluate(pos []ms2.Vec, dist []float32, userData any) error
 	return nil
 }
 
+// EvaluateInterval computes a guaranteed distance range over each box in boxes, writing
+// the results into lo and hi. It returns an error if the underlying SDF does not implement [SDF2Interval].
+//
+// userData is handled in the same way as in [SDF2CPU.Evaluate].
+func (sdf *SDF2CPU) EvaluateInterval(boxes []ms2.Box, lo, hi []float32, userData any) error {
+	isdf, err := AssertSDF2Interval(sdf.SDF)
+	if err != nil {
+		return err
+	}
+	useOwnVecPool := userData == nil
+	if useOwnVecPool {
+		userData = &sdf.vp
+	}
+	if len(boxes) != len(lo) || len(boxes) != len(hi) {
+		return errMismatchBufferLength
+	} else if len(boxes) == 0 {
+		return errEmptyBuffers
+	}
+	err = isdf.EvaluateInterval(boxes, lo, hi, userData)
+	if useOwnVecPool {
+		err2 := sdf.vp.AssertAllReleased()
+		if err2 != nil && err != nil {
+			return fmt.Errorf("VecPool leak: %s\nSDF2 error: %s", err2, err)
+		} else if err2 != nil {
+			return err2
+		}
+	}
+	return err
+}
+
 // Bounds returns the bounds of the underlying SDF.
 func (sdf *SDF2CPU) Bounds() ms2.Box {
 	return sdf.SDF.Bounds()
@@ -210,6 +290,9 @@ type VecPool struct {
 	V3    bufPool[ms3.Vec]
 	V2    bufPool[ms2.Vec]
 	Float bufPool[float32]
+	// Box3 and Box2 are used by interval evaluators, see [SDF3Interval].
+	Box3 bufPool[ms3.Box]
+	Box2 bufPool[ms2.Box]
 }
 
 // AssertAllReleased returns an error if any buffer is still acquired or if a
@@ -228,6 +311,14 @@ func (vp *VecPool) AssertAllReleased() error {
 	if err != nil {
 		return err
 	}
+	err = vp.Box3.assertAllReleased()
+	if err != nil {
+		return err
+	}
+	err = vp.Box2.assertAllReleased()
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -239,19 +330,23 @@ func (vp *VecPool) SetMinAllocationLen(minumumAlloca int) {
 	vp.Float.minAllocation = minumumAlloca
 	vp.V2.minAllocation = minumumAlloca
 	vp.V3.minAllocation = minumumAlloca
+	vp.Box3.minAllocation = minumumAlloca
+	vp.Box2.minAllocation = minumumAlloca
 }
 
 // TotalSize returns the number of bytes allocated by all underlying buffers.
 func (vp *VecPool) TotalSize() uint64 {
-	return vp.Float.TotalSize() + vp.V2.TotalSize() + vp.V3.TotalSize()
+	return vp.Float.TotalSize() + vp.V2.TotalSize() + vp.V3.TotalSize() + vp.Box3.TotalSize() + vp.Box2.TotalSize()
 }
 
-// Deallocate frees all backing memory in all three pools. Panics if any buffer is
+// Deallocate frees all backing memory in all pools. Panics if any buffer is
 // still acquired. After this call the pool is valid for reuse.
 func (vp *VecPool) Deallocate() {
 	vp.Float.Deallocate()
 	vp.V2.Deallocate()
 	vp.V3.Deallocate()
+	vp.Box3.Deallocate()
+	vp.Box2.Deallocate()
 }
 
 // bufPool is a fixed-element-type pool backed by two parallel slices: _ins holds the
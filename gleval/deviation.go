@@ -0,0 +1,154 @@
+package gleval
+
+import (
+	"errors"
+	"math"
+
+	"github.com/chewxy/math32"
+	"github.com/soypat/geometry/ms3"
+)
+
+// DeviationConfig configures [MeshDeviation] and [SDFDeviation]. The zero value is ready for use.
+type DeviationConfig struct {
+	// FaceSubdivisions is the number of segments each triangle edge is divided into to sample points
+	// over triangle faces in addition to vertices. 1 samples vertices only. Defaults to 3, which samples
+	// the centroid and two points along each edge.
+	FaceSubdivisions int
+	// Resolution is the approximate spacing between surface samples of SDFs compared with [SDFDeviation].
+	// Defaults to 1/64th of the largest bounds dimension.
+	Resolution float32
+	// EvalBufferSize is the amount of positions evaluated per SDF evaluation call. Defaults to 4096.
+	EvalBufferSize int
+}
+
+// Deviation contains statistics of the distance from sampled points to a surface.
+type Deviation struct {
+	// Max is the largest absolute distance found at MaxPos. It is the one-sided Hausdorff distance
+	// from the sampled points to the surface.
+	Max    float32
+	MaxPos ms3.Vec
+	// Mean is the mean absolute distance.
+	Mean float32
+	// RMS is the root mean square of distances.
+	RMS float32
+	// Samples is the number of points sampled.
+	Samples int
+}
+
+// MeshDeviation measures how far triangles deviate from the surface of sdf by evaluating sdf
+// at the unique triangle vertices and at points sampled over the faces. sdf must be an exact
+// distance field for the result to be the distance to the surface.
+// userData is passed to the SDF evaluations.
+func MeshDeviation(sdf SDF3, triangles []ms3.Triangle, cfg DeviationConfig, userData any) (Deviation, error) {
+	if sdf == nil {
+		return Deviation{}, errors.New("nil SDF3")
+	} else if len(triangles) == 0 {
+		return Deviation{}, errors.New("no triangles")
+	}
+	if cfg.FaceSubdivisions == 0 {
+		cfg.FaceSubdivisions = 3
+	}
+	if cfg.EvalBufferSize == 0 {
+		cfg.EvalBufferSize = 4096
+	}
+	if cfg.FaceSubdivisions < 1 {
+		return Deviation{}, errors.New("face subdivisions must be at least 1")
+	} else if cfg.EvalBufferSize < 1 {
+		return Deviation{}, errors.New("invalid evaluation buffer size")
+	}
+	seen := make(map[ms3.Vec]struct{}, len(triangles)/2)
+	var points []ms3.Vec
+	for _, tri := range triangles {
+		for _, v := range tri {
+			if _, ok := seen[v]; !ok {
+				seen[v] = struct{}{}
+				points = append(points, v)
+			}
+		}
+	}
+	n := cfg.FaceSubdivisions
+	inv := 1 / float32(n)
+	for _, tri := range triangles {
+		// Barycentric lattice points excluding vertices.
+		for i := 0; i <= n; i++ {
+			for j := 0; i+j <= n; j++ {
+				k := n - i - j
+				if i == n || j == n || k == n {
+					continue
+				}
+				p := ms3.Scale(float32(i)*inv, tri[0])
+				p = ms3.Add(p, ms3.Scale(float32(j)*inv, tri[1]))
+				p = ms3.Add(p, ms3.Scale(float32(k)*inv, tri[2]))
+				points = append(points, p)
+			}
+		}
+	}
+	return deviationAt(sdf, points, cfg.EvalBufferSize, userData)
+}
+
+// SDFDeviation compares the surfaces of two SDFs by sampling points over the surface of a and
+// measuring their distance to the surface of b and vice versa. The Hausdorff distance between the
+// surfaces is the largest of both Max values. Both SDFs must be exact distance fields for the results
+// to be distances between surfaces. userData is passed to the SDF evaluations and must contain a
+// [VecPool] for normal calculation used in surface sampling. If userData is nil a new VecPool is used.
+func SDFDeviation(a, b SDF3, cfg DeviationConfig, userData any) (ab, ba Deviation, err error) {
+	if a == nil || b == nil {
+		return ab, ba, errors.New("nil SDF3")
+	}
+	if cfg.Resolution == 0 {
+		cfg.Resolution = a.Bounds().Union(b.Bounds()).Size().Max() / 64
+	}
+	if cfg.EvalBufferSize == 0 {
+		cfg.EvalBufferSize = 4096
+	}
+	if cfg.EvalBufferSize < 8 {
+		return ab, ba, errors.New("deviation evaluation buffer size must be at least 8")
+	}
+	if userData == nil {
+		userData = new(VecPool)
+	}
+	pa, _, err := appendSurfacePoints(nil, nil, a, cfg.Resolution, cfg.EvalBufferSize, userData)
+	if err != nil {
+		return ab, ba, err
+	}
+	pb, _, err := appendSurfacePoints(nil, nil, b, cfg.Resolution, cfg.EvalBufferSize, userData)
+	if err != nil {
+		return ab, ba, err
+	} else if len(pa) == 0 || len(pb) == 0 {
+		return ab, ba, errors.New("no surface found within SDF3 bounds")
+	}
+	ab, err = deviationAt(b, pa, cfg.EvalBufferSize, userData)
+	if err != nil {
+		return ab, ba, err
+	}
+	ba, err = deviationAt(a, pb, cfg.EvalBufferSize, userData)
+	return ab, ba, err
+}
+
+// deviationAt evaluates sdf at points and returns the distance statistics.
+func deviationAt(sdf SDF3, points []ms3.Vec, bufSize int, userData any) (Deviation, error) {
+	dist := make([]float32, min(bufSize, len(points)))
+	var dev Deviation
+	var sum, sum2 float64
+	for i0 := 0; i0 < len(points); i0 += bufSize {
+		i1 := min(i0+bufSize, len(points))
+		d := dist[:i1-i0]
+		err := sdf.Evaluate(points[i0:i1], d, userData)
+		if err != nil {
+			return Deviation{}, err
+		}
+		for i, v := range d {
+			v = math32.Abs(v)
+			if v > dev.Max || math32.IsNaN(v) {
+				dev.Max = v
+				dev.MaxPos = points[i0+i]
+			}
+			sum += float64(v)
+			sum2 += float64(v) * float64(v)
+		}
+	}
+	dev.Samples = len(points)
+	dev.Mean = float32(sum / float64(len(points)))
+	dev.RMS = float32(math.Sqrt(sum2 / float64(len(points))))
+	return dev, nil
+}
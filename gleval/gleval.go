@@ -36,6 +36,30 @@ type SDF2 interface {
 	Bounds() ms2.Box
 }
 
+// SDF3Interval is implemented by 3D signed distance fields which can bound
+// their distance over whole axis aligned boxes using interval arithmetic.
+// Unlike point evaluations, interval bounds remain valid for distance fields
+// that are not exact such as twisted shapes or smooth booleans.
+type SDF3Interval interface {
+	SDF3
+	// EvaluateInterval stores in lo and hi a guaranteed range of the signed distance
+	// over each of the boxes such that for every position p inside boxes[i]
+	// the distance evaluated at p is within [lo[i], hi[i]].
+	// boxes, lo and hi must be of same length.
+	EvaluateInterval(boxes []ms3.Box, lo, hi []float32, userData any) error
+}
+
+// SDF2Interval is implemented by 2D signed distance fields which can bound
+// their distance over whole axis aligned boxes using interval arithmetic.
+type SDF2Interval interface {
+	SDF2
+	// EvaluateInterval stores in lo and hi a guaranteed range of the signed distance
+	// over each of the boxes such that for every position p inside boxes[i]
+	// the distance evaluated at p is within [lo[i], hi[i]].
+	// boxes, lo and hi must be of same length.
+	EvaluateInterval(boxes []ms2.Box, lo, hi []float32, userData any) error
+}
+
 // These interfaces are implemented by all SDF interfaces such as SDF3/2 and Shader3D/2D.
 // Using these instead of `any` Aids in catching mistakes at compile time such as passing a Shader3D instead of Shader2D as an argument.
 type (
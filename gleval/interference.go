@@ -0,0 +1,293 @@
+package gleval
+
+import (
+	"errors"
+	"math"
+	"slices"
+
+	"github.com/chewxy/math32"
+	"github.com/soypat/geometry/ms3"
+)
+
+// InterferenceConfig configures [ComputeInterference]. The zero value is ready for use.
+type InterferenceConfig struct {
+	// Resolution is the edge length of the smallest cells searched and the accuracy of
+	// the clearance and witness points. Defaults to 1/128th of the largest dimension of the shapes' bounds.
+	Resolution float32
+	// EvalBufferSize is the amount of cells evaluated per SDF evaluation call. Defaults to 4096.
+	EvalBufferSize int
+}
+
+// Interference contains the results of [ComputeInterference].
+type Interference struct {
+	// Intersects is true if the shapes share volume.
+	Intersects bool
+	// Volume is the interpenetration volume, the volume shared by both shapes.
+	// VolumeError is the upper bound of the absolute error in Volume. See [MassProperties].
+	Volume      float32
+	VolumeError float32
+	// Clearance is the minimum distance between the surfaces of the shapes.
+	// It is zero when the shapes intersect.
+	Clearance float32
+	// WitnessA and WitnessB are the points on the surface of the first and second shape respectively
+	// which are separated by the clearance. When the shapes intersect both are the point of deepest interpenetration.
+	WitnessA, WitnessB ms3.Vec
+}
+
+// ComputeInterference checks for collision between shapes a and b. It returns whether they intersect,
+// the interpenetration volume and, when they do not intersect, the minimum clearance between them
+// along with the closest points on each surface.
+//
+// Intersection is searched for within the overlap of the shapes' bounds and clearance within the union of
+// the bounds by hierarchically subdividing the region and discarding cells which can not contain a better
+// result than found so far. Cells are bounded with interval evaluation if both SDFs implement [SDF3Interval],
+// otherwise distance fields are assumed to be exact. Clearance is accurate for exact distance fields only,
+// for distance fields which underestimate distances such as the difference of shapes it is a lower bound.
+// userData is passed to the SDF evaluations.
+func ComputeInterference(a, b SDF3, cfg InterferenceConfig, userData any) (Interference, error) {
+	if a == nil || b == nil {
+		return Interference{}, errors.New("nil SDF3")
+	}
+	ba, bb := a.Bounds(), b.Bounds()
+	side := ba.Union(bb).Size().Max()
+	if cfg.Resolution == 0 {
+		cfg.Resolution = side / 128
+	}
+	if cfg.EvalBufferSize == 0 {
+		cfg.EvalBufferSize = 4096
+	}
+	if cfg.Resolution <= 0 || math32.IsNaN(cfg.Resolution) || math32.IsInf(cfg.Resolution, 0) {
+		return Interference{}, errors.New("invalid interference resolution")
+	} else if cfg.EvalBufferSize < 8 {
+		return Interference{}, errors.New("interference evaluation buffer size must be at least 8")
+	}
+	search := pairSearch{
+		a:        a,
+		b:        b,
+		res:      cfg.Resolution,
+		pos:      make([]ms3.Vec, cfg.EvalBufferSize),
+		distA:    make([]float32, cfg.EvalBufferSize),
+		distB:    make([]float32, cfg.EvalBufferSize),
+		userData: userData,
+	}
+	if ia, ib := intervalOf(a, ba, userData), intervalOf(b, bb, userData); ia != nil && ib != nil {
+		search.ia, search.ib = ia, ib
+		search.boxes = make([]ms3.Box, cfg.EvalBufferSize)
+		search.loA = make([]float32, cfg.EvalBufferSize)
+		search.loB = make([]float32, cfg.EvalBufferSize)
+		search.hi = make([]float32, cfg.EvalBufferSize)
+	}
+
+	var result Interference
+	overlap := ba.Intersect(bb)
+	if !overlap.Empty() {
+		// Minimize the intersection distance field. A negative minimum means the shapes intersect.
+		depth, p, err := search.minimize(overlap, func(da, db float32) float32 { return max(da, db) })
+		if err != nil {
+			return Interference{}, err
+		}
+		if depth < 0 {
+			mp, err := ComputeMassProperties(&intersectSDF3{a: a, b: b, bb: overlap}, MassConfig{
+				Resolution:     cfg.Resolution,
+				EvalBufferSize: cfg.EvalBufferSize,
+			}, userData)
+			if err != nil {
+				return Interference{}, err
+			}
+			result.Intersects = true
+			result.Volume = mp.Volume
+			result.VolumeError = mp.VolumeError
+			result.WitnessA = p
+			result.WitnessB = p
+			return result, nil
+		}
+	}
+	// For exact distance fields the sum of distances is the length of the shortest
+	// path between surfaces through a point, which is minimized on the segment between the closest points.
+	clearance, p, err := search.minimize(ba.Union(bb), func(da, db float32) float32 { return da + db })
+	if err != nil {
+		return Interference{}, err
+	}
+	result.Clearance = max(0, clearance)
+	result.WitnessA, err = search.project(a, p)
+	if err != nil {
+		return Interference{}, err
+	}
+	result.WitnessB, err = search.project(b, p)
+	if err != nil {
+		return Interference{}, err
+	}
+	return result, nil
+}
+
+// pairSearch minimizes functions of the distances to two shapes using branch and bound over an octree.
+type pairSearch struct {
+	a, b     SDF3
+	ia, ib   SDF3Interval
+	res      float32
+	pos      []ms3.Vec
+	distA    []float32
+	distB    []float32
+	boxes    []ms3.Box
+	loA      []float32
+	loB      []float32
+	hi       []float32
+	userData any
+}
+
+// minimize returns the minimum of combine evaluated at cell centers of an octree covering domain and the
+// position at which it was found. combine must be non-decreasing in both arguments so that combining
+// lower bounds of the distances yields a lower bound of the combined distance.
+func (ps *pairSearch) minimize(domain ms3.Box, combine func(da, db float32) float32) (best float32, bestPos ms3.Vec, err error) {
+	n := len(ps.pos)
+	side := domain.Size().Max()
+	rootSize := ps.res
+	for rootSize < side {
+		rootSize *= 2
+	}
+	best = math.MaxFloat32
+	cells := []massCell{{center: domain.Center(), size: rootSize}}
+	var next []massCell
+	for len(cells) > 0 {
+		next = next[:0]
+		for start := 0; start < len(cells); start += n {
+			chunk := cells[start:min(start+n, len(cells))]
+			for i, cell := range chunk {
+				ps.pos[i] = cell.center
+			}
+			err = ps.a.Evaluate(ps.pos[:len(chunk)], ps.distA[:len(chunk)], ps.userData)
+			if err != nil {
+				return best, bestPos, err
+			}
+			err = ps.b.Evaluate(ps.pos[:len(chunk)], ps.distB[:len(chunk)], ps.userData)
+			if err != nil {
+				return best, bestPos, err
+			}
+			if ps.ia != nil {
+				for i, cell := range chunk {
+					ps.boxes[i] = cell.box()
+				}
+				err = ps.ia.EvaluateInterval(ps.boxes[:len(chunk)], ps.loA[:len(chunk)], ps.hi[:len(chunk)], ps.userData)
+				if err != nil {
+					return best, bestPos, err
+				}
+				err = ps.ib.EvaluateInterval(ps.boxes[:len(chunk)], ps.loB[:len(chunk)], ps.hi[:len(chunk)], ps.userData)
+				if err != nil {
+					return best, bestPos, err
+				}
+			}
+			for i, cell := range chunk {
+				v := combine(ps.distA[i], ps.distB[i])
+				if v < best {
+					best = v
+					bestPos = cell.center
+				}
+				var lower float32
+				if ps.ia != nil {
+					lower = combine(ps.loA[i], ps.loB[i])
+				} else {
+					r := cell.size * (sqrt3 / 2)
+					lower = combine(ps.distA[i]-r, ps.distB[i]-r)
+				}
+				if lower < best && cell.size > ps.res {
+					next = cell.appendOctants(next)
+				}
+			}
+		}
+		cells, next = next, cells
+	}
+	return best, bestPos, nil
+}
+
+// project moves p onto the surface of s along the distance field gradient.
+func (ps *pairSearch) project(s SDF3, p ms3.Vec) (ms3.Vec, error) {
+	h := ps.res / 4
+	pos := ps.pos[:7]
+	dist := ps.distA[:7]
+	for iter := 0; iter < 4; iter++ {
+		pos[0] = p
+		pos[1], pos[2] = ms3.Add(p, ms3.Vec{X: h}), ms3.Sub(p, ms3.Vec{X: h})
+		pos[3], pos[4] = ms3.Add(p, ms3.Vec{Y: h}), ms3.Sub(p, ms3.Vec{Y: h})
+		pos[5], pos[6] = ms3.Add(p, ms3.Vec{Z: h}), ms3.Sub(p, ms3.Vec{Z: h})
+		err := s.Evaluate(pos, dist, ps.userData)
+		if err != nil {
+			return p, err
+		}
+		grad := ms3.Vec{X: dist[1] - dist[2], Y: dist[3] - dist[4], Z: dist[5] - dist[6]}
+		norm := ms3.Norm(grad)
+		if norm == 0 || math32.IsNaN(norm) {
+			break
+		}
+		p = ms3.Sub(p, ms3.Scale(dist[0]/norm, grad))
+	}
+	return p, nil
+}
+
+// intervalOf returns s as a [SDF3Interval] if it implements interval evaluation over bb. Returns nil otherwise.
+func intervalOf(s SDF3, bb ms3.Box, userData any) SDF3Interval {
+	ival, ok := s.(SDF3Interval)
+	if !ok {
+		return nil
+	}
+	// SDFs which wrap others may implement the interface and still fail if the wrapped SDF
+	// does not support interval evaluation. Do a test evaluation to catch this early.
+	var lo, hi [1]float32
+	if ival.EvaluateInterval([]ms3.Box{bb}, lo[:], hi[:], userData) != nil {
+		return nil
+	}
+	return ival
+}
+
+// intersectSDF3 is the intersection of two SDFs over a given bounding box.
+type intersectSDF3 struct {
+	a, b  SDF3
+	bb    ms3.Box
+	auxlo []float32
+	auxhi []float32
+}
+
+func (s *intersectSDF3) Bounds() ms3.Box { return s.bb }
+
+func (s *intersectSDF3) Evaluate(pos []ms3.Vec, dist []float32, userData any) error {
+	err := s.a.Evaluate(pos, dist, userData)
+	if err != nil {
+		return err
+	}
+	s.auxlo = slices.Grow(s.auxlo[:0], len(dist))
+	aux := s.auxlo[:len(dist)]
+	err = s.b.Evaluate(pos, aux, userData)
+	if err != nil {
+		return err
+	}
+	for i, d := range aux {
+		dist[i] = max(dist[i], d)
+	}
+	return nil
+}
+
+func (s *intersectSDF3) EvaluateInterval(boxes []ms3.Box, lo, hi []float32, userData any) error {
+	ia, err := AssertSDF3Interval(s.a)
+	if err != nil {
+		return err
+	}
+	ib, err := AssertSDF3Interval(s.b)
+	if err != nil {
+		return err
+	}
+	err = ia.EvaluateInterval(boxes, lo, hi, userData)
+	if err != nil {
+		return err
+	}
+	s.auxlo = slices.Grow(s.auxlo[:0], len(lo))
+	s.auxhi = slices.Grow(s.auxhi[:0], len(hi))
+	auxlo, auxhi := s.auxlo[:len(lo)], s.auxhi[:len(hi)]
+	err = ib.EvaluateInterval(boxes, auxlo, auxhi, userData)
+	if err != nil {
+		return err
+	}
+	for i := range lo {
+		lo[i] = max(lo[i], auxlo[i])
+		hi[i] = max(hi[i], auxhi[i])
+	}
+	return nil
+}
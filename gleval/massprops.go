@@ -0,0 +1,238 @@
+package gleval
+
+import (
+	"errors"
+
+	"github.com/chewxy/math32"
+	"github.com/soypat/geometry/ms3"
+)
+
+// MassConfig configures [ComputeMassProperties]. The zero value is ready for use.
+type MassConfig struct {
+	// Density is the mass per unit volume of the solid. Defaults to 1.
+	Density float32
+	// Resolution is the edge length of the smallest cells the bounds are subdivided into.
+	// Smaller resolutions give more accurate results at the cost of more evaluations.
+	// Defaults to 1/128th of the largest bounds dimension.
+	Resolution float32
+	// EvalBufferSize is the amount of cells evaluated per SDF evaluation call. Defaults to 4096.
+	EvalBufferSize int
+}
+
+// MassProperties contains the results of [ComputeMassProperties].
+type MassProperties struct {
+	// Volume is the estimated volume of the solid.
+	Volume float32
+	// VolumeError is an upper bound of the absolute error in Volume, given the distance field is
+	// a true SDF or implements [SDF3Interval]. It is the contribution of cells intersecting the surface,
+	// which decreases linearly with the resolution.
+	VolumeError float32
+	// Area is the estimated area of the solid's surface.
+	Area float32
+	// Mass is the volume times the density.
+	Mass float32
+	// Centroid is the center of mass of the solid.
+	Centroid ms3.Vec
+	// Inertia is the inertia tensor of the solid about its centroid given the density.
+	// Diagonal elements are the moments of inertia and off-diagonal elements are the products of inertia.
+	Inertia ms3.Mat3
+	// Evaluations is the number of cells evaluated.
+	Evaluations int
+}
+
+const sqrt3 = 1.7320508075688772935274463415058723669428052538103806280558069794
+
+// massCell is an axis aligned cube of the adaptive subdivision used to integrate mass properties.
+type massCell struct {
+	center ms3.Vec
+	size   float32
+}
+
+// appendOctants appends the 8 cells resulting from subdividing c to dst.
+func (c massCell) appendOctants(dst []massCell) []massCell {
+	q := c.size / 4
+	for j := 0; j < 8; j++ {
+		off := ms3.Vec{X: -q, Y: -q, Z: -q}
+		if j&1 != 0 {
+			off.X = q
+		}
+		if j&2 != 0 {
+			off.Y = q
+		}
+		if j&4 != 0 {
+			off.Z = q
+		}
+		dst = append(dst, massCell{center: ms3.Add(c.center, off), size: c.size / 2})
+	}
+	return dst
+}
+
+// box returns the axis aligned box spanned by the cell.
+func (c massCell) box() ms3.Box {
+	half := c.size / 2
+	return ms3.Box{Min: ms3.AddScalar(-half, c.center), Max: ms3.AddScalar(half, c.center)}
+}
+
+// ComputeMassProperties integrates the volume, surface area, centroid and inertia tensor of the solid
+// defined by sdf over its bounds. The bounds are subdivided adaptively like an octree: cells that are
+// fully inside or outside the surface are integrated whole and cells intersecting the surface are
+// subdivided down to the configured resolution, where the fraction of the cell inside the surface
+// is estimated from the distance at the cell center.
+//
+// If sdf implements [SDF3Interval] cells larger than the resolution are classified with interval evaluation
+// instead of point evaluation, which remains valid for distance fields which are not exact.
+// userData is passed to the SDF evaluations.
+func ComputeMassProperties(sdf SDF3, cfg MassConfig, userData any) (MassProperties, error) {
+	if sdf == nil {
+		return MassProperties{}, errors.New("nil SDF3")
+	}
+	bb := sdf.Bounds()
+	side := bb.Size().Max()
+	if cfg.Density == 0 {
+		cfg.Density = 1
+	}
+	if cfg.Resolution == 0 {
+		cfg.Resolution = side / 128
+	}
+	if cfg.EvalBufferSize == 0 {
+		cfg.EvalBufferSize = 4096
+	}
+	if cfg.Density < 0 || math32.IsNaN(cfg.Density) {
+		return MassProperties{}, errors.New("invalid mass density")
+	} else if cfg.Resolution <= 0 || math32.IsNaN(cfg.Resolution) || math32.IsInf(cfg.Resolution, 0) {
+		return MassProperties{}, errors.New("invalid mass resolution")
+	} else if cfg.EvalBufferSize < 8 {
+		return MassProperties{}, errors.New("mass evaluation buffer size must be at least 8")
+	} else if side <= 0 || math32.IsNaN(side) || math32.IsInf(side, 0) {
+		return MassProperties{}, errors.New("invalid SDF3 bounds")
+	}
+	n := cfg.EvalBufferSize
+	pos := make([]ms3.Vec, n)
+	dist := make([]float32, n)
+	var boxes []ms3.Box
+	var lo, hi []float32
+	ival := intervalOf(sdf, bb, userData)
+	if ival != nil {
+		boxes, lo, hi = make([]ms3.Box, n), make([]float32, n), make([]float32, n)
+	}
+
+	// Accumulate in float64 since many small contributions are summed.
+	var vol, volErr, area, mx, my, mz, sxx, syy, szz, sxy, sxz, syz float64
+	integrate := func(c ms3.Vec, size, fraction float32) {
+		cx, cy, cz := float64(c.X), float64(c.Y), float64(c.Z)
+		s := float64(size)
+		w := float64(fraction) * s * s * s
+		// A cube's second moment about its center is V*s²/12 along each axis.
+		dev := s * s / 12
+		vol += w
+		mx += w * cx
+		my += w * cy
+		mz += w * cz
+		sxx += w * (cx*cx + dev)
+		syy += w * (cy*cy + dev)
+		szz += w * (cz*cz + dev)
+		sxy += w * cx * cy
+		sxz += w * cx * cz
+		syz += w * cy * cz
+	}
+	evals := 0
+	// Root cell is sized so that leaf cells have the resolution size and there is a layer
+	// of leaf cells outside the bounds to sample surfaces which lie on the bounds.
+	rootSize := cfg.Resolution
+	for rootSize < side+2*cfg.Resolution {
+		rootSize *= 2
+	}
+	cells := []massCell{{center: bb.Center(), size: rootSize}}
+	var next []massCell
+	for len(cells) > 0 {
+		next = next[:0]
+		for start := 0; start < len(cells); start += n {
+			chunk := cells[start:min(start+n, len(cells))]
+			// All cells of a level have the same size. Interval evaluation classifies cells above the leaf level;
+			// leaf cells need point distances to estimate the volume fraction and area.
+			useInterval := ival != nil && chunk[0].size > cfg.Resolution
+			var err error
+			if useInterval {
+				for i, cell := range chunk {
+					boxes[i] = cell.box()
+				}
+				err = ival.EvaluateInterval(boxes[:len(chunk)], lo[:len(chunk)], hi[:len(chunk)], userData)
+			} else {
+				for i, cell := range chunk {
+					pos[i] = cell.center
+				}
+				err = sdf.Evaluate(pos[:len(chunk)], dist[:len(chunk)], userData)
+			}
+			if err != nil {
+				return MassProperties{}, err
+			}
+			evals += len(chunk)
+			for i, cell := range chunk {
+				d := dist[i]
+				// Cells are integrated whole only if they are further than the resolution from the
+				// surface so that all cells near the surface contribute to the area estimate.
+				var outside, inside, farOutside, farInside bool
+				margin := cfg.Resolution
+				if useInterval {
+					outside, inside = lo[i] > 0, hi[i] < 0
+					farOutside, farInside = lo[i] > margin, hi[i] < -margin
+				} else {
+					r := cell.size * (sqrt3 / 2)
+					outside, inside = d > r, d < -r
+					farOutside, farInside = d > r+margin, d < -r-margin
+				}
+				switch {
+				case cell.size <= cfg.Resolution:
+					if d > -cell.size && d < cell.size {
+						// Distances to the surface at cell centers of a uniform grid weighted by a tent function
+						// of width equal to the cell size sum up to the surface area, exactly so for flat faces.
+						area += float64(cell.size * (cell.size - math32.Abs(d)))
+					}
+					if outside {
+						break
+					} else if inside {
+						integrate(cell.center, cell.size, 1)
+						break
+					}
+					// Fraction is exact for a plane parallel to a cell face.
+					f := min(1, max(0, 0.5-d/cell.size))
+					integrate(cell.center, cell.size, f)
+					volErr += float64(max(f, 1-f)) * float64(cell.size*cell.size*cell.size)
+				case farOutside:
+					// Cell contributes nothing.
+				case farInside:
+					integrate(cell.center, cell.size, 1)
+				default:
+					next = cell.appendOctants(next)
+				}
+			}
+		}
+		cells, next = next, cells
+	}
+	if vol <= 0 {
+		return MassProperties{VolumeError: float32(volErr), Area: float32(area), Evaluations: evals}, nil
+	}
+	gx, gy, gz := mx/vol, my/vol, mz/vol
+	// Central second moments via the parallel axis theorem.
+	cxx := sxx - vol*gx*gx
+	cyy := syy - vol*gy*gy
+	czz := szz - vol*gz*gz
+	cxy := sxy - vol*gx*gy
+	cxz := sxz - vol*gx*gz
+	cyz := syz - vol*gy*gz
+	rho := float64(cfg.Density)
+	inertia := [9]float32{
+		float32(rho * (cyy + czz)), float32(-rho * cxy), float32(-rho * cxz),
+		float32(-rho * cxy), float32(rho * (cxx + czz)), float32(-rho * cyz),
+		float32(-rho * cxz), float32(-rho * cyz), float32(rho * (cxx + cyy)),
+	}
+	return MassProperties{
+		Volume:      float32(vol),
+		VolumeError: float32(volErr),
+		Area:        float32(area),
+		Mass:        float32(vol * rho),
+		Centroid:    ms3.Vec{X: float32(gx), Y: float32(gy), Z: float32(gz)},
+		Inertia:     ms3.NewMat3(inertia[:]),
+		Evaluations: evals,
+	}, nil
+}
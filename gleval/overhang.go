@@ -0,0 +1,143 @@
+package gleval
+
+import (
+	"errors"
+
+	"github.com/chewxy/math32"
+	"github.com/soypat/geometry/ms3"
+)
+
+// OverhangConfig configures [ComputeOverhang] and [BestOrientation]. The zero value is ready for use.
+type OverhangConfig struct {
+	// Resolution is the approximate spacing between surface samples, each of which represents Resolution² of area.
+	// Defaults to 1/64th of the largest bounds dimension.
+	Resolution float32
+	// MaxAngle is the largest angle in radians a downward facing surface may be tilted from the vertical
+	// before requiring supports. Defaults to π/4 (45°).
+	MaxAngle float32
+	// EvalBufferSize is the amount of positions evaluated per SDF evaluation call. Defaults to 4096.
+	EvalBufferSize int
+}
+
+// Overhang contains the results of an overhang analysis for a build direction.
+type Overhang struct {
+	// Up is the build direction in the SDF's coordinates, pointing away from the build plate.
+	Up ms3.Vec
+	// Rotation orients the SDF so that Up points along +Z. It can be used with Builder.Transform.
+	Rotation ms3.Mat4
+	// OverhangArea is the area of surfaces which require support.
+	// Surfaces resting on the build plate do not require support.
+	OverhangArea float32
+	// PlateArea is the area of surfaces resting on the build plate.
+	PlateArea float32
+	// SurfaceArea is the total surface area.
+	SurfaceArea float32
+}
+
+// ComputeOverhang estimates the area of surfaces of sdf which overhang more than the configured
+// angle when printed with build direction up. Surface normals are calculated with [NormalsCentralDiff].
+//
+// userData is passed to SDF evaluations and must contain a [VecPool] for normal calculation.
+// If userData is nil a new VecPool is used.
+func ComputeOverhang(sdf SDF3, up ms3.Vec, cfg OverhangConfig, userData any) (Overhang, error) {
+	best, _, err := BestOrientation(sdf, []ms3.Vec{up}, cfg, userData)
+	return best, err
+}
+
+// BestOrientation computes the overhang of sdf for each candidate build direction and returns the one
+// with the least overhang area along with the results of all candidates in the same order.
+// Ties are broken in favor of the larger area resting on the build plate.
+// If candidates is nil the 26 directions to the faces, edges and corners of a cube are used.
+// See [ComputeOverhang].
+func BestOrientation(sdf SDF3, candidates []ms3.Vec, cfg OverhangConfig, userData any) (best Overhang, all []Overhang, err error) {
+	if sdf == nil {
+		return best, nil, errors.New("nil SDF3")
+	}
+	if cfg.Resolution == 0 {
+		cfg.Resolution = sdf.Bounds().Size().Max() / 64
+	}
+	if cfg.MaxAngle == 0 {
+		cfg.MaxAngle = math32.Pi / 4
+	}
+	if cfg.EvalBufferSize == 0 {
+		cfg.EvalBufferSize = 4096
+	}
+	if cfg.MaxAngle < 0 || cfg.MaxAngle > math32.Pi/2 {
+		return best, nil, errors.New("overhang angle must be between 0 and π/2")
+	} else if cfg.EvalBufferSize < 8 {
+		return best, nil, errors.New("overhang evaluation buffer size must be at least 8")
+	}
+	if candidates == nil {
+		for x := -1; x <= 1; x++ {
+			for y := -1; y <= 1; y++ {
+				for z := -1; z <= 1; z++ {
+					if x != 0 || y != 0 || z != 0 {
+						candidates = append(candidates, ms3.Vec{X: float32(x), Y: float32(y), Z: float32(z)})
+					}
+				}
+			}
+		}
+	} else if len(candidates) == 0 {
+		return best, nil, errors.New("no candidate orientations")
+	}
+	for _, up := range candidates {
+		if ms3.Norm(up) == 0 || math32.IsNaN(ms3.Norm(up)) {
+			return best, nil, errors.New("invalid build direction")
+		}
+	}
+	if userData == nil {
+		userData = new(VecPool)
+	}
+	pos, normals, err := appendSurfacePoints(nil, nil, sdf, cfg.Resolution, cfg.EvalBufferSize, userData)
+	if err != nil {
+		return best, nil, err
+	} else if len(pos) == 0 {
+		return best, nil, errors.New("no surface found within SDF3 bounds")
+	}
+	sampleArea := cfg.Resolution * cfg.Resolution
+	// Overhanging surfaces have normals pointing downwards further than the max angle from horizontal.
+	maxDot := -math32.Sin(cfg.MaxAngle)
+	all = make([]Overhang, len(candidates))
+	for i, up := range candidates {
+		up = ms3.Unit(up)
+		minHeight := float32(math32.MaxFloat32)
+		for _, p := range pos {
+			minHeight = min(minHeight, ms3.Dot(p, up))
+		}
+		var overhangs, onPlate int
+		for j, n := range normals {
+			if ms3.Dot(pos[j], up) < minHeight+cfg.Resolution {
+				onPlate++
+			} else if ms3.Dot(n, up) < maxDot {
+				overhangs++
+			}
+		}
+		all[i] = Overhang{
+			Up:           up,
+			Rotation:     rotationToZ(up),
+			OverhangArea: float32(overhangs) * sampleArea,
+			PlateArea:    float32(onPlate) * sampleArea,
+			SurfaceArea:  float32(len(pos)) * sampleArea,
+		}
+		o := all[i]
+		if i == 0 || o.OverhangArea < best.OverhangArea || (o.OverhangArea == best.OverhangArea && o.PlateArea > best.PlateArea) {
+			best = o
+		}
+	}
+	return best, all, nil
+}
+
+// rotationToZ returns a rotation matrix which rotates unit vector v onto +Z.
+func rotationToZ(v ms3.Vec) ms3.Mat4 {
+	z := ms3.Vec{Z: 1}
+	axis := ms3.Cross(v, z)
+	if ms3.Norm(axis) < 1e-6 {
+		if v.Z > 0 {
+			return ms3.IdentityMat4()
+		}
+		// Opposite vectors have no unique rotation axis between them, any perpendicular axis works.
+		axis = ms3.Vec{X: 1}
+	}
+	angle := math32.Acos(min(1, max(-1, ms3.Dot(v, z))))
+	return ms3.RotationMat4(angle, axis)
+}
@@ -0,0 +1,194 @@
+package gleval
+
+import (
+	"errors"
+
+	"github.com/chewxy/math32"
+	"github.com/soypat/geometry/ms3"
+)
+
+// RayConfig configures [RayCast]. The zero value is ready for use.
+type RayConfig struct {
+	// Tolerance is the distance to the surface below which a ray is considered to hit it.
+	// Defaults to 1e-5 times the bounds diagonal.
+	Tolerance float32
+	// StepFactor scales sphere tracing steps. Values below 1 such as 0.5 are required for distance
+	// fields which overestimate distances, such as twisted shapes, to avoid stepping over surfaces. Defaults to 1.
+	StepFactor float32
+	// MaxSteps is the largest number of sphere tracing steps per ray before it is considered a miss. Defaults to 512.
+	MaxSteps int
+}
+
+// RayHit is the result of casting a ray. See [RayCast].
+type RayHit struct {
+	// Hit is true if the ray hit a surface.
+	Hit bool
+	// Distance is the distance along the ray from its origin to the hit.
+	Distance float32
+	// Point is the position of the hit.
+	Point ms3.Vec
+	// Normal is the outward unit normal of the surface at Point.
+	Normal ms3.Vec
+}
+
+// RayCast casts rays starting at origins along dirs and stores the first surface hit of each ray within maxDist
+// in hits. origins, dirs and hits must be of same length. dirs need not be normalized. Rays starting inside
+// the shape hit the surface where they exit it, which can be used to probe where a drill would exit.
+//
+// Rays are sphere traced with all rays evaluated together in each step. Rays start at the entry to the
+// SDF's bounds and are misses once they exit the bounds, so empty space outside the bounds is skipped.
+// userData is passed to the SDF evaluations and must contain a [VecPool] for buffers and normal
+// calculation. If userData is nil a new VecPool is used.
+func RayCast(sdf SDF3, origins, dirs []ms3.Vec, maxDist float32, hits []RayHit, cfg RayConfig, userData any) error {
+	if sdf == nil {
+		return errors.New("nil SDF3")
+	} else if len(origins) != len(dirs) || len(origins) != len(hits) {
+		return errors.New("length of origins, directions and hits must match")
+	} else if len(origins) == 0 {
+		return errEmptyBuffers
+	} else if maxDist <= 0 || math32.IsNaN(maxDist) {
+		return errors.New("invalid ray max distance")
+	}
+	bb := sdf.Bounds()
+	if cfg.Tolerance == 0 {
+		cfg.Tolerance = 1e-5 * bb.Diagonal()
+	}
+	if cfg.StepFactor == 0 {
+		cfg.StepFactor = 1
+	}
+	if cfg.MaxSteps == 0 {
+		cfg.MaxSteps = 512
+	}
+	if cfg.Tolerance <= 0 || math32.IsNaN(cfg.Tolerance) {
+		return errors.New("invalid ray tolerance")
+	} else if cfg.StepFactor <= 0 || cfg.StepFactor > 1 {
+		return errors.New("ray step factor must be in (0, 1]")
+	} else if cfg.MaxSteps < 1 {
+		return errors.New("ray max steps must be positive")
+	}
+	if userData == nil {
+		userData = new(VecPool)
+	}
+	vp, err := GetVecPool(userData)
+	if err != nil {
+		return err
+	}
+	n := len(origins)
+	unitDirs := vp.V3.Acquire(n)
+	defer vp.V3.Release(unitDirs)
+	pos := vp.V3.Acquire(n)
+	defer vp.V3.Release(pos)
+	dist := vp.Float.Acquire(n)
+	defer vp.Float.Release(dist)
+	t := vp.Float.Acquire(n)
+	defer vp.Float.Release(t)
+	tExit := vp.Float.Acquire(n)
+	defer vp.Float.Release(tExit)
+	prevT := vp.Float.Acquire(n)
+	defer vp.Float.Release(prevT)
+	prevD := vp.Float.Acquire(n)
+	defer vp.Float.Release(prevD)
+
+	// Pad bounds so that surfaces on the bounds are not missed by rays grazing them.
+	pad := 2 * cfg.Tolerance
+	bbPad := ms3.Box{Min: ms3.AddScalar(-pad, bb.Min), Max: ms3.AddScalar(pad, bb.Max)}
+	active := make([]int, 0, n)
+	for i, d := range dirs {
+		norm := ms3.Norm(d)
+		if norm == 0 || math32.IsNaN(norm) || math32.IsInf(norm, 0) {
+			return errors.New("invalid ray direction")
+		}
+		hits[i] = RayHit{}
+		unitDirs[i] = ms3.Scale(1/norm, d)
+		enter, exit, ok := rayBox(bbPad, origins[i], unitDirs[i])
+		exit = min(exit, maxDist)
+		if !ok || enter > exit {
+			continue
+		}
+		t[i], tExit[i] = enter, exit
+		prevT[i] = math32.NaN() // First step has no previous distance to compare sign with.
+		active = append(active, i)
+	}
+	for step := 0; step < cfg.MaxSteps && len(active) > 0; step++ {
+		for j, i := range active {
+			pos[j] = ms3.Add(origins[i], ms3.Scale(t[i], unitDirs[i]))
+		}
+		err = sdf.Evaluate(pos[:len(active)], dist[:len(active)], userData)
+		if err != nil {
+			return err
+		}
+		remaining := active[:0]
+		for j, i := range active {
+			d := dist[j]
+			hitT := t[i]
+			hit := math32.Abs(d) < cfg.Tolerance
+			if !hit && !math32.IsNaN(prevT[i]) && (d < 0) != (prevD[i] < 0) {
+				// Stepped over the surface, interpolate the crossing.
+				hit = true
+				hitT = prevT[i] + (t[i]-prevT[i])*prevD[i]/(prevD[i]-d)
+			}
+			if hit {
+				hits[i] = RayHit{Hit: true, Distance: hitT, Point: ms3.Add(origins[i], ms3.Scale(hitT, unitDirs[i]))}
+				continue
+			} else if math32.IsNaN(d) {
+				continue
+			}
+			prevT[i], prevD[i] = t[i], d
+			t[i] += max(cfg.StepFactor*math32.Abs(d), cfg.Tolerance)
+			if t[i] <= tExit[i] {
+				remaining = append(remaining, i)
+			}
+		}
+		active = remaining
+	}
+
+	// Calculate normals of hits.
+	nhits := 0
+	for i := range hits {
+		if hits[i].Hit {
+			pos[nhits] = hits[i].Point
+			nhits++
+		}
+	}
+	if nhits == 0 {
+		return nil
+	}
+	normals := vp.V3.Acquire(nhits)
+	defer vp.V3.Release(normals)
+	err = NormalsCentralDiff(sdf, pos[:nhits], normals, 4*cfg.Tolerance, userData)
+	if err != nil {
+		return err
+	}
+	j := 0
+	for i := range hits {
+		if hits[i].Hit {
+			hits[i].Normal = ms3.Unit(normals[j])
+			j++
+		}
+	}
+	return nil
+}
+
+// rayBox returns the distances along a ray with unit direction dir at which it enters and
+// exits the box. Distances are clamped to start at the origin. ok is false if the ray misses the box.
+func rayBox(box ms3.Box, origin, dir ms3.Vec) (enter, exit float32, ok bool) {
+	enter, exit = 0, math32.Inf(1)
+	o, d := origin.Array(), dir.Array()
+	lo, hi := box.Min.Array(), box.Max.Array()
+	for axis := 0; axis < 3; axis++ {
+		if d[axis] == 0 {
+			if o[axis] < lo[axis] || o[axis] > hi[axis] {
+				return 0, 0, false
+			}
+			continue
+		}
+		inv := 1 / d[axis]
+		t0, t1 := (lo[axis]-o[axis])*inv, (hi[axis]-o[axis])*inv
+		if t0 > t1 {
+			t0, t1 = t1, t0
+		}
+		enter = max(enter, t0)
+		exit = min(exit, t1)
+	}
+	return enter, exit, enter <= exit
+}
@@ -0,0 +1,140 @@
+package gleval
+
+import (
+	"errors"
+	"math/rand"
+
+	"github.com/chewxy/math32"
+	"github.com/soypat/geometry/ms3"
+)
+
+// SampleConfig configures [SampleSurface]. The zero value is ready for use.
+type SampleConfig struct {
+	// MinSpacing is the minimum distance between sampled points. Zero samples points independently
+	// and uniformly over the surface. A positive value enforces Poisson-disk spacing.
+	MinSpacing float32
+	// Resolution is the size of the octree cells used to find the surface. Surface features smaller
+	// than Resolution may be missed. Defaults to 1/64th of the largest bounds dimension.
+	Resolution float32
+	// Seed seeds the random number generator. Equal seeds yield equal samples.
+	Seed int64
+	// MaxAttempts is the largest number of candidate points tried per requested point before
+	// sampling stops with fewer points than requested. Defaults to 32.
+	MaxAttempts int
+	// EvalBufferSize is the amount of positions evaluated per SDF evaluation call. Defaults to 4096.
+	EvalBufferSize int
+}
+
+// SampleSurface appends n points uniformly distributed over the surface of sdf and their unit normals
+// to dstPos and dstNormals. Octree cells of size cfg.Resolution which contain the surface are found first,
+// pruning cells with interval evaluation if sdf implements [SDF3Interval]. Candidate points are randomly
+// placed in uniformly chosen cells and are then projected onto the surface with Newton steps along the
+// distance gradient.
+//
+// If cfg.MinSpacing is positive candidates closer than MinSpacing to an accepted point are rejected,
+// producing a Poisson-disk sampling. Fewer than n points are returned if the surface cannot fit n points
+// at the requested spacing. userData is passed to the SDF evaluations and must contain a [VecPool]
+// for normal calculation. If userData is nil a new VecPool is used.
+func SampleSurface(dstPos, dstNormals []ms3.Vec, sdf SDF3, n int, cfg SampleConfig, userData any) (_, _ []ms3.Vec, err error) {
+	if sdf == nil {
+		return dstPos, dstNormals, errors.New("nil SDF3")
+	} else if n <= 0 {
+		return dstPos, dstNormals, errors.New("number of samples must be positive")
+	}
+	if cfg.Resolution == 0 {
+		cfg.Resolution = sdf.Bounds().Size().Max() / 64
+	}
+	if cfg.MaxAttempts == 0 {
+		cfg.MaxAttempts = 32
+	}
+	if cfg.EvalBufferSize == 0 {
+		cfg.EvalBufferSize = 4096
+	}
+	if cfg.MinSpacing < 0 || math32.IsNaN(cfg.MinSpacing) {
+		return dstPos, dstNormals, errors.New("invalid sample spacing")
+	} else if cfg.MaxAttempts < 1 {
+		return dstPos, dstNormals, errors.New("sample max attempts must be positive")
+	} else if cfg.EvalBufferSize < 8 {
+		return dstPos, dstNormals, errors.New("sample evaluation buffer size must be at least 8")
+	}
+	if userData == nil {
+		userData = new(VecPool)
+	}
+	res := cfg.Resolution
+	// Cells are sampled when their center is within Resolution/2 of the surface so the number of cells
+	// is proportional to the surface area regardless of surface orientation.
+	cells, _, err := appendSurfacePoints(nil, nil, sdf, res, cfg.EvalBufferSize, userData)
+	if err != nil {
+		return dstPos, dstNormals, err
+	} else if len(cells) == 0 {
+		return dstPos, dstNormals, errors.New("no surface found within SDF3 bounds")
+	}
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	grid := spacingGrid{cellSize: cfg.MinSpacing, cells: make(map[[3]int32][]ms3.Vec)}
+	bufSize := min(cfg.EvalBufferSize, 2*n)
+	pos := make([]ms3.Vec, bufSize)
+	normals := make([]ms3.Vec, bufSize)
+	origin := make([]int, bufSize)
+	dist := make([]float32, bufSize)
+	accepted := 0
+	for attempts := 0; accepted < n && attempts < cfg.MaxAttempts*n; attempts += bufSize {
+		for i := range pos {
+			origin[i] = rng.Intn(len(cells))
+			pos[i] = ms3.Add(cells[origin[i]], ms3.Vec{
+				X: res * (rng.Float32() - 0.5),
+				Y: res * (rng.Float32() - 0.5),
+				Z: res * (rng.Float32() - 0.5),
+			})
+		}
+		err = projectToSurface(sdf, pos, normals, dist, res/8, userData)
+		if err != nil {
+			return dstPos, dstNormals, err
+		}
+		for i, p := range pos {
+			// Reject points which did not converge or moved to surface in another cell, which would
+			// oversample the surface there.
+			if math32.Abs(dist[i]) > res/8 || ms3.Norm(ms3.Sub(p, cells[origin[i]])) > res {
+				continue
+			} else if cfg.MinSpacing > 0 && !grid.insert(p) {
+				continue
+			}
+			dstPos = append(dstPos, p)
+			dstNormals = append(dstNormals, normals[i])
+			accepted++
+			if accepted == n {
+				break
+			}
+		}
+	}
+	return dstPos, dstNormals, nil
+}
+
+// spacingGrid is a spatial hash of points with cells of the minimum spacing between points.
+type spacingGrid struct {
+	cellSize float32
+	cells    map[[3]int32][]ms3.Vec
+}
+
+// insert adds p to the grid if no point in the grid is closer than the grid cell size and reports whether it was added.
+func (g *spacingGrid) insert(p ms3.Vec) bool {
+	key := [3]int32{
+		int32(math32.Floor(p.X / g.cellSize)),
+		int32(math32.Floor(p.Y / g.cellSize)),
+		int32(math32.Floor(p.Z / g.cellSize)),
+	}
+	r2 := g.cellSize * g.cellSize
+	for dx := int32(-1); dx <= 1; dx++ {
+		for dy := int32(-1); dy <= 1; dy++ {
+			for dz := int32(-1); dz <= 1; dz++ {
+				for _, q := range g.cells[[3]int32{key[0] + dx, key[1] + dy, key[2] + dz}] {
+					d := ms3.Sub(p, q)
+					if ms3.Dot(d, d) < r2 {
+						return false
+					}
+				}
+			}
+		}
+	}
+	g.cells[key] = append(g.cells[key], p)
+	return true
+}
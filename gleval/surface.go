@@ -0,0 +1,124 @@
+package gleval
+
+import (
+	"errors"
+
+	"github.com/chewxy/math32"
+	"github.com/soypat/geometry/ms3"
+)
+
+// appendSurfacePoints appends approximately one point on the surface of sdf and its unit normal for
+// every cell of size res of an octree over the SDF bounds that intersects the surface. Octree cells are pruned
+// with interval evaluation if sdf implements [SDF3Interval]. Points are projected onto the surface with
+// Newton steps along the distance gradient. userData must contain a [VecPool] for normal calculation.
+func appendSurfacePoints(dstPos, dstNormals []ms3.Vec, sdf SDF3, res float32, bufSize int, userData any) (_, _ []ms3.Vec, err error) {
+	bb := sdf.Bounds()
+	side := bb.Size().Max()
+	if res <= 0 || math32.IsNaN(res) || math32.IsInf(res, 0) {
+		return dstPos, dstNormals, errors.New("invalid surface resolution")
+	} else if side <= 0 || math32.IsNaN(side) || math32.IsInf(side, 0) {
+		return dstPos, dstNormals, errors.New("invalid SDF3 bounds")
+	}
+	n := bufSize
+	pos := make([]ms3.Vec, n)
+	dist := make([]float32, n)
+	var boxes []ms3.Box
+	var lo, hi []float32
+	ival := intervalOf(sdf, bb, userData)
+	if ival != nil {
+		boxes, lo, hi = make([]ms3.Box, n), make([]float32, n), make([]float32, n)
+	}
+	// Pad root so that surfaces lying on the bounds are sampled.
+	rootSize := res
+	for rootSize < side+4*res {
+		rootSize *= 2
+	}
+	// Offset root so that faces on the bounds, which are commonly aligned with the bounds center,
+	// do not lie on cell faces where rounding error may exclude them from both neighboring cells.
+	const offset = 0.38196601125 // Golden ratio conjugate complement.
+	center := ms3.AddScalar(offset*res, bb.Center())
+	start := len(dstPos)
+	cells := []massCell{{center: center, size: rootSize}}
+	var next []massCell
+	for len(cells) > 0 {
+		next = next[:0]
+		for i0 := 0; i0 < len(cells); i0 += n {
+			chunk := cells[i0:min(i0+n, len(cells))]
+			for i, cell := range chunk {
+				pos[i] = cell.center
+			}
+			err = sdf.Evaluate(pos[:len(chunk)], dist[:len(chunk)], userData)
+			if err != nil {
+				return dstPos, dstNormals, err
+			}
+			if ival != nil {
+				for i, cell := range chunk {
+					boxes[i] = cell.box()
+				}
+				err = ival.EvaluateInterval(boxes[:len(chunk)], lo[:len(chunk)], hi[:len(chunk)], userData)
+				if err != nil {
+					return dstPos, dstNormals, err
+				}
+			}
+			for i, cell := range chunk {
+				d := dist[i]
+				var noSurface bool
+				if ival != nil {
+					noSurface = lo[i] > 0 || hi[i] < 0
+				} else {
+					r := cell.size * (sqrt3 / 2)
+					noSurface = d > r || d < -r
+				}
+				switch {
+				case noSurface:
+				case cell.size <= res:
+					// Half open range so surfaces lying on cell faces are sampled once.
+					if d >= -cell.size/2 && d < cell.size/2 {
+						dstPos = append(dstPos, cell.center)
+					}
+				default:
+					next = cell.appendOctants(next)
+				}
+			}
+		}
+		cells, next = next, cells
+	}
+	dstNormals = append(dstNormals, make([]ms3.Vec, len(dstPos)-start)...)
+	for i0 := start; i0 < len(dstPos); i0 += n {
+		i1 := min(i0+n, len(dstPos))
+		err = projectToSurface(sdf, dstPos[i0:i1], dstNormals[i0:i1], dist[:i1-i0], res/8, userData)
+		if err != nil {
+			return dstPos, dstNormals, err
+		}
+	}
+	return dstPos, dstNormals, nil
+}
+
+// projectToSurface moves pos onto the surface of sdf with Newton steps and stores the unit normals at the
+// projected positions in normals. dist is used as a scratch buffer. step is the central difference step.
+func projectToSurface(sdf SDF3, pos, normals []ms3.Vec, dist []float32, step float32, userData any) error {
+	const iterations = 3
+	for iter := 0; iter < iterations; iter++ {
+		err := sdf.Evaluate(pos, dist, userData)
+		if err != nil {
+			return err
+		}
+		err = NormalsCentralDiff(sdf, pos, normals, step, userData)
+		if err != nil {
+			return err
+		}
+		for i, g := range normals {
+			// NormalsCentralDiff returns distance differences, convert to gradient.
+			g = ms3.Scale(1/step, g)
+			norm := ms3.Norm(g)
+			if norm == 0 || math32.IsNaN(norm) {
+				continue
+			}
+			normals[i] = ms3.Scale(1/norm, g)
+			if iter < iterations-1 {
+				pos[i] = ms3.Sub(pos[i], ms3.Scale(dist[i]/norm, normals[i]))
+			}
+		}
+	}
+	return nil
+}
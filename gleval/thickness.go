@@ -0,0 +1,168 @@
+package gleval
+
+import (
+	"errors"
+	"math"
+
+	"github.com/chewxy/math32"
+	"github.com/soypat/geometry/ms3"
+)
+
+// ThicknessConfig configures [ComputeWallThickness]. The zero value is ready for use.
+type ThicknessConfig struct {
+	// Resolution is the approximate spacing between surface samples.
+	// Defaults to 1/64th of the largest bounds dimension.
+	Resolution float32
+	// Threshold is the thickness below which samples are reported as thin. Zero reports no thin samples.
+	Threshold float32
+	// MaxThickness is the largest thickness measured, samples with thicker walls report MaxThickness.
+	// Defaults to the bounds diagonal.
+	MaxThickness float32
+	// HistogramBins is the number of histogram bins. Defaults to 16.
+	HistogramBins int
+	// EvalBufferSize is the amount of positions evaluated per SDF evaluation call. Defaults to 4096.
+	EvalBufferSize int
+}
+
+// ThicknessSample is the wall thickness measured at a point on a surface.
+type ThicknessSample struct {
+	// Pos is the position on the surface.
+	Pos ms3.Vec
+	// Normal is the outward unit normal of the surface at Pos.
+	Normal ms3.Vec
+	// Thickness is the length of the inward ray from Pos along -Normal until it exits the solid.
+	Thickness float32
+}
+
+// WallThickness contains the results of [ComputeWallThickness].
+//
+// Samples can be exported for inspection with glrender.WritePLY by coloring
+// them according to their thickness.
+type WallThickness struct {
+	// Samples contains all surface samples.
+	Samples []ThicknessSample
+	// Min is the thinnest sample.
+	Min ThicknessSample
+	// Histogram counts samples by thickness. Bin i counts samples with thickness
+	// in [i*BinWidth, (i+1)*BinWidth). The last bin also counts samples with MaxThickness.
+	Histogram []int
+	BinWidth  float32
+	// Thin contains samples with thickness below the configured threshold.
+	Thin []ThicknessSample
+}
+
+// ComputeWallThickness estimates the local wall thickness of the solid defined by sdf at points sampled
+// over its surface. Thickness is measured by marching from each surface point inward along the negative
+// surface normal until the distance changes sign, meaning the ray exited the solid.
+// Marching steps are the distance to the surface so a non-exact SDF which overestimates
+// distances may step over thin walls.
+//
+// userData is passed to SDF evaluations and must contain a [VecPool] for normal calculation.
+// If userData is nil a new VecPool is used.
+func ComputeWallThickness(sdf SDF3, cfg ThicknessConfig, userData any) (WallThickness, error) {
+	if sdf == nil {
+		return WallThickness{}, errors.New("nil SDF3")
+	}
+	bb := sdf.Bounds()
+	if cfg.Resolution == 0 {
+		cfg.Resolution = bb.Size().Max() / 64
+	}
+	if cfg.MaxThickness == 0 {
+		cfg.MaxThickness = bb.Diagonal()
+	}
+	if cfg.HistogramBins == 0 {
+		cfg.HistogramBins = 16
+	}
+	if cfg.EvalBufferSize == 0 {
+		cfg.EvalBufferSize = 4096
+	}
+	if cfg.MaxThickness <= 0 || math32.IsNaN(cfg.MaxThickness) || math32.IsInf(cfg.MaxThickness, 0) {
+		return WallThickness{}, errors.New("invalid max thickness")
+	} else if cfg.Threshold < 0 {
+		return WallThickness{}, errors.New("negative thickness threshold")
+	} else if cfg.HistogramBins < 1 {
+		return WallThickness{}, errors.New("thickness histogram requires at least one bin")
+	} else if cfg.EvalBufferSize < 8 {
+		return WallThickness{}, errors.New("thickness evaluation buffer size must be at least 8")
+	}
+	if userData == nil {
+		userData = new(VecPool)
+	}
+	surfPos, normals, err := appendSurfacePoints(nil, nil, sdf, cfg.Resolution, cfg.EvalBufferSize, userData)
+	if err != nil {
+		return WallThickness{}, err
+	}
+	if len(surfPos) == 0 {
+		return WallThickness{}, errors.New("no surface found within SDF3 bounds")
+	}
+	thick := make([]float32, len(surfPos))
+	for i0 := 0; i0 < len(surfPos); i0 += cfg.EvalBufferSize {
+		i1 := min(i0+cfg.EvalBufferSize, len(surfPos))
+		err = marchInward(sdf, surfPos[i0:i1], normals[i0:i1], thick[i0:i1], cfg.Resolution/8, cfg.MaxThickness, userData)
+		if err != nil {
+			return WallThickness{}, err
+		}
+	}
+
+	result := WallThickness{
+		Samples:   make([]ThicknessSample, len(surfPos)),
+		Histogram: make([]int, cfg.HistogramBins),
+		BinWidth:  cfg.MaxThickness / float32(cfg.HistogramBins),
+		Min:       ThicknessSample{Thickness: math.MaxFloat32},
+	}
+	for i, p := range surfPos {
+		sample := ThicknessSample{Pos: p, Normal: normals[i], Thickness: thick[i]}
+		result.Samples[i] = sample
+		if sample.Thickness < result.Min.Thickness {
+			result.Min = sample
+		}
+		bin := min(int(sample.Thickness/result.BinWidth), cfg.HistogramBins-1)
+		result.Histogram[bin]++
+		if sample.Thickness < cfg.Threshold {
+			result.Thin = append(result.Thin, sample)
+		}
+	}
+	return result, nil
+}
+
+// marchInward stores in thick the length of the rays starting at pos and marching
+// along -normals until exiting the solid, limited to maxThick. minStep is the smallest step taken.
+func marchInward(sdf SDF3, pos, normals []ms3.Vec, thick []float32, minStep, maxThick float32, userData any) error {
+	n := len(pos)
+	active := make([]int, n)
+	for i := range active {
+		active[i] = i
+		thick[i] = minStep
+	}
+	q := make([]ms3.Vec, n)
+	dist := make([]float32, n)
+	for len(active) > 0 {
+		for j, i := range active {
+			q[j] = ms3.Sub(pos[i], ms3.Scale(thick[i], normals[i]))
+		}
+		err := sdf.Evaluate(q[:len(active)], dist[:len(active)], userData)
+		if err != nil {
+			return err
+		}
+		remaining := active[:0]
+		for j, i := range active {
+			d := dist[j]
+			switch {
+			case d >= 0:
+				// Ray exited the solid. The surface is at least d away from the sample so the exit is at least d behind.
+				thick[i] = max(minStep, thick[i]-d)
+			case math32.IsNaN(d):
+				thick[i] = maxThick
+			default:
+				thick[i] += max(-d, minStep)
+				if thick[i] >= maxThick {
+					thick[i] = maxThick
+				} else {
+					remaining = append(remaining, i)
+				}
+			}
+		}
+		active = remaining
+	}
+	return nil
+}
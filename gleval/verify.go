@@ -0,0 +1,251 @@
+package gleval
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/chewxy/math32"
+	"github.com/soypat/geometry/ms2"
+	"github.com/soypat/geometry/ms3"
+	"github.com/soypat/gsdf/glbuild"
+)
+
+// VerifyConfig configures the sampling done by [VerifyShader]. The zero value is ready for use.
+type VerifyConfig struct {
+	// Divisions is the number of samples along each axis of a node's sampling box. Defaults to 32.
+	Divisions int
+	// Padding is the fraction of the node's bounds size added on each side of its bounds
+	// to obtain the sampling box, so that distances outside of the bounds are verified. Defaults to 0.25.
+	Padding float32
+	// Tolerance is the fraction by which gradient magnitudes may exceed 1 before being reported,
+	// which accounts for floating point error. Defaults to 1e-3.
+	Tolerance float32
+}
+
+// NodeReport contains the results of verifying a single node of a shape tree. See [VerifyShader].
+// Positions of 2D nodes are stored with a zero Z component.
+type NodeReport struct {
+	// Shader is the verified node.
+	Shader glbuild.Shader
+	// Depth is the depth of the node in the tree, zero for the root.
+	Depth int
+	// Samples is the number of positions evaluated.
+	Samples int
+	// MaxGradient is the largest gradient magnitude estimated between neighboring samples,
+	// found at MaxGradientPos. Gradient magnitudes above 1 mean the distance is overestimated,
+	// which causes renderers to skip surfaces.
+	MaxGradient    float32
+	MaxGradientPos ms3.Vec
+	// GradientViolations is the number of neighboring sample pairs with gradient magnitude above 1.
+	GradientViolations int
+	// OutsideNegative is the number of samples outside of the node's bounds with negative distance.
+	// A non-zero value means the bounds do not contain the whole shape. OutsideNegativePos is the first such sample.
+	OutsideNegative    int
+	OutsideNegativePos ms3.Vec
+	// NonFinite is the number of samples which evaluated to NaN or Inf. NonFinitePos is the first such sample.
+	NonFinite    int
+	NonFinitePos ms3.Vec
+}
+
+// OK reports whether no problems were found in the node.
+func (r NodeReport) OK() bool {
+	return r.GradientViolations == 0 && r.OutsideNegative == 0 && r.NonFinite == 0
+}
+
+// String returns a human readable summary of the node's problems.
+func (r NodeReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s(depth=%d)", glbuild.Inspect(r.Shader).Kind, r.Depth)
+	if r.OK() {
+		fmt.Fprintf(&b, " ok, max gradient %.4g", r.MaxGradient)
+		return b.String()
+	}
+	if r.GradientViolations > 0 {
+		fmt.Fprintf(&b, " %d/%d gradients above 1 (max %.4g at %v);", r.GradientViolations, r.Samples, r.MaxGradient, r.MaxGradientPos)
+	}
+	if r.OutsideNegative > 0 {
+		fmt.Fprintf(&b, " %d/%d negative distances outside bounds (at %v);", r.OutsideNegative, r.Samples, r.OutsideNegativePos)
+	}
+	if r.NonFinite > 0 {
+		fmt.Fprintf(&b, " %d/%d NaN or Inf distances (at %v);", r.NonFinite, r.Samples, r.NonFinitePos)
+	}
+	return strings.TrimSuffix(b.String(), ";")
+}
+
+// VerifyShader samples every node of the tree rooted at root on a grid around the node's bounds and reports
+// per node gradient magnitudes above 1, negative distances outside of the bounds and NaN or Inf distances.
+// Nodes which appear more than once in the tree are reported once. All nodes must implement CPU evaluation.
+//
+// Gradients are estimated from the distance difference between neighboring samples so problems
+// which occur at a smaller scale than the sample spacing may go undetected.
+func VerifyShader(root glbuild.Shader, cfg VerifyConfig) ([]NodeReport, error) {
+	if cfg.Divisions == 0 {
+		cfg.Divisions = 32
+	}
+	if cfg.Padding == 0 {
+		cfg.Padding = 0.25
+	}
+	if cfg.Tolerance == 0 {
+		cfg.Tolerance = 1e-3
+	}
+	if cfg.Divisions < 2 {
+		return nil, errors.New("verify divisions must be at least 2")
+	} else if cfg.Padding < 0 || cfg.Tolerance < 0 {
+		return nil, errors.New("negative verify padding or tolerance")
+	}
+	var vp VecPool
+	var reports []NodeReport
+	visited := make(map[glbuild.Shader]bool)
+	err := glbuild.Walk(root, func(s glbuild.Shader, depth int) error {
+		if visited[s] {
+			return glbuild.SkipChildren
+		}
+		visited[s] = true
+		r := NodeReport{Shader: s, Depth: depth}
+		var err error
+		switch s := s.(type) {
+		case glbuild.Shader3D:
+			err = verify3(&r, s, cfg, &vp)
+		case glbuild.Shader2D:
+			err = verify2(&r, s, cfg, &vp)
+		default:
+			err = fmt.Errorf("%T is neither Shader3D nor Shader2D", s)
+		}
+		if err == nil {
+			err = vp.AssertAllReleased()
+		}
+		if err != nil {
+			return err
+		}
+		reports = append(reports, r)
+		return nil
+	})
+	return reports, err
+}
+
+func verify3(r *NodeReport, s glbuild.Shader3D, cfg VerifyConfig, vp *VecPool) error {
+	sdf, err := AssertSDF3(s)
+	if err != nil {
+		return err
+	}
+	bb := s.Bounds()
+	sz := bb.Size()
+	pad := ms3.Scale(cfg.Padding, sz)
+	domain := ms3.Box{Min: ms3.Sub(bb.Min, pad), Max: ms3.Add(bb.Max, pad)}
+	n := cfg.Divisions
+	pos := ms3.AppendGrid(vp.V3.Acquire(n * n * n)[:0], domain, n, n, n)
+	defer vp.V3.Release(pos)
+	dist := vp.Float.Acquire(len(pos))
+	defer vp.Float.Release(dist)
+	err = sdf.Evaluate(pos, dist, vp)
+	if err != nil {
+		return err
+	}
+	r.Samples = len(pos)
+	// Samples outside bounds closer than eps to them are not checked to avoid reporting floating point error.
+	eps := 1e-5 * sz.Max()
+	for i, d := range dist {
+		p := pos[i]
+		if math32.IsNaN(d) || math32.IsInf(d, 0) {
+			if r.NonFinite == 0 {
+				r.NonFinitePos = p
+			}
+			r.NonFinite++
+		} else if d < 0 && boxDist3(bb, p) > eps {
+			if r.OutsideNegative == 0 {
+				r.OutsideNegativePos = p
+			}
+			r.OutsideNegative++
+		}
+	}
+	step := ms3.Scale(1/float32(n-1), domain.Size())
+	strides := [3]int{1, n, n * n}
+	steps := step.Array()
+	for i, d := range dist {
+		ijk := [3]int{i % n, (i / n) % n, i / (n * n)}
+		for axis, stride := range strides {
+			if ijk[axis] == n-1 || steps[axis] == 0 {
+				continue
+			}
+			r.checkGradient(d, dist[i+stride], steps[axis], pos[i], cfg.Tolerance)
+		}
+	}
+	return nil
+}
+
+func verify2(r *NodeReport, s glbuild.Shader2D, cfg VerifyConfig, vp *VecPool) error {
+	sdf, err := AssertSDF2(s)
+	if err != nil {
+		return err
+	}
+	bb := s.Bounds()
+	sz := bb.Size()
+	pad := ms2.Scale(cfg.Padding, sz)
+	domain := ms2.Box{Min: ms2.Sub(bb.Min, pad), Max: ms2.Add(bb.Max, pad)}
+	n := cfg.Divisions
+	pos := ms2.AppendGrid(vp.V2.Acquire(n * n)[:0], domain, n, n)
+	defer vp.V2.Release(pos)
+	dist := vp.Float.Acquire(len(pos))
+	defer vp.Float.Release(dist)
+	err = sdf.Evaluate(pos, dist, vp)
+	if err != nil {
+		return err
+	}
+	r.Samples = len(pos)
+	eps := 1e-5 * sz.Max()
+	for i, d := range dist {
+		p := pos[i]
+		if math32.IsNaN(d) || math32.IsInf(d, 0) {
+			if r.NonFinite == 0 {
+				r.NonFinitePos = ms3.Vec{X: p.X, Y: p.Y}
+			}
+			r.NonFinite++
+		} else if d < 0 && boxDist2(bb, p) > eps {
+			if r.OutsideNegative == 0 {
+				r.OutsideNegativePos = ms3.Vec{X: p.X, Y: p.Y}
+			}
+			r.OutsideNegative++
+		}
+	}
+	step := ms2.Scale(1/float32(n-1), domain.Size())
+	strides := [2]int{1, n}
+	steps := [2]float32{step.X, step.Y}
+	for i, d := range dist {
+		ij := [2]int{i % n, i / n}
+		for axis, stride := range strides {
+			if ij[axis] == n-1 || steps[axis] == 0 {
+				continue
+			}
+			r.checkGradient(d, dist[i+stride], steps[axis], ms3.Vec{X: pos[i].X, Y: pos[i].Y}, cfg.Tolerance)
+		}
+	}
+	return nil
+}
+
+// checkGradient estimates the gradient magnitude between two samples separated by step and records it in r.
+func (r *NodeReport) checkGradient(d1, d2, step float32, pos ms3.Vec, tol float32) {
+	g := math32.Abs(d2-d1) / step
+	if math32.IsNaN(g) || math32.IsInf(g, 0) {
+		return // Already reported as non-finite.
+	}
+	if g > r.MaxGradient {
+		r.MaxGradient = g
+		r.MaxGradientPos = pos
+	}
+	if g > 1+tol {
+		r.GradientViolations++
+	}
+}
+
+// boxDist3 returns the distance from p to the closest point of box, zero if p is inside box.
+func boxDist3(box ms3.Box, p ms3.Vec) float32 {
+	q := ms3.MaxElem(ms3.Sub(box.Min, p), ms3.Sub(p, box.Max))
+	return ms3.Norm(ms3.MaxElem(q, ms3.Vec{}))
+}
+
+// boxDist2 returns the distance from p to the closest point of box, zero if p is inside box.
+func boxDist2(box ms2.Box, p ms2.Vec) float32 {
+	q := ms2.MaxElem(ms2.Sub(box.Min, p), ms2.Sub(p, box.Max))
+	return ms2.Norm(ms2.MaxElem(q, ms2.Vec{}))
+}
@@ -0,0 +1,908 @@
+package gleval
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/chewxy/math32"
+	"github.com/soypat/geometry/ms2"
+	"github.com/soypat/geometry/ms3"
+	"github.com/soypat/gsdf/glbuild"
+)
+
+// vmChunkLen is the number of positions evaluated per pass over the instruction tape.
+// Chosen so that the working set of registers of typical trees fits in L1/L2 cache.
+const vmChunkLen = 256
+
+// NewVMSDF3 compiles the shape tree rooted at root into a flat instruction tape and returns
+// a [*SDF3VM] which evaluates it. Operations such as unions, transforms and extrusions and common primitives such as
+// spheres, boxes and cylinders known by the compiler are lowered into instructions over chunks of positions and distances.
+// Translations of lowered primitives are fused into the primitive's instruction. Shapes unknown to the compiler
+// are evaluated by calling their own Evaluate method, so all shapes in the tree must implement [SDF3] or [SDF2].
+// Shapes are recognized by the constructor name they report via [glbuild.Describer]. Use NumFallbacks
+// to check how many shapes of the tree are evaluated by their own Evaluate method.
+//
+// Like [NewCPUSDF3] it runs a single test evaluation on construction.
+// Pass nil as userData to Evaluate — the returned [*SDF3VM] manages its own [VecPool].
+func NewVMSDF3(root glbuild.Shader3D) (*SDF3VM, error) {
+	if root == nil {
+		return nil, errors.New("nil root shader")
+	}
+	var c vmCompiler
+	c.p3.alloc() // Register 0 holds input positions.
+	d, err := c.sdf3(root, 0)
+	if err != nil {
+		return nil, err
+	}
+	sdf := SDF3VM{bb: root.Bounds()}
+	sdf.vm.init(&c, d)
+	bb := sdf.Bounds()
+	err = sdf.Evaluate([]ms3.Vec{bb.Min}, []float32{0}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &sdf, nil
+}
+
+// NewVMSDF2 is the 2D counterpart of [NewVMSDF3].
+func NewVMSDF2(root glbuild.Shader2D) (*SDF2VM, error) {
+	if root == nil {
+		return nil, errors.New("nil root shader")
+	}
+	var c vmCompiler
+	c.p2.alloc() // Register 0 holds input positions.
+	d, err := c.sdf2(root, 0)
+	if err != nil {
+		return nil, err
+	}
+	sdf := SDF2VM{bb: root.Bounds()}
+	sdf.vm.init(&c, d)
+	bb := sdf.Bounds()
+	err = sdf.Evaluate([]ms2.Vec{bb.Min}, []float32{0}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &sdf, nil
+}
+
+// SDF3VM evaluates a compiled shape tree. Construct with [NewVMSDF3].
+// SDF3VM holds evaluation state and is not safe for concurrent use.
+type SDF3VM struct {
+	vm    vm
+	bb    ms3.Box
+	vp    VecPool
+	evals uint64
+}
+
+// Evaluate computes the SDF distance for each position in pos, writing results into dist.
+// pos and dist must have equal non-zero length. userData is passed to the Evaluate methods of
+// shapes not lowered into instructions, see [SDF3CPU.Evaluate].
+func (sdf *SDF3VM) Evaluate(pos []ms3.Vec, dist []float32, userData any) error {
+	if len(pos) != len(dist) {
+		return errMismatchBufferLength
+	} else if len(dist) == 0 {
+		return errEmptyBuffers
+	}
+	useOwnVecPool := userData == nil
+	if useOwnVecPool {
+		userData = &sdf.vp
+	}
+	var err error
+	for start := 0; start < len(pos) && err == nil; start += vmChunkLen {
+		end := min(start+vmChunkLen, len(pos))
+		sdf.vm.p3[0] = pos[start:end]
+		err = sdf.vm.run(dist[start:end], userData)
+	}
+	sdf.vm.p3[0] = nil
+	if useOwnVecPool {
+		err2 := sdf.vp.AssertAllReleased()
+		if err2 != nil && err != nil {
+			return fmt.Errorf("VecPool leak: %s\nSDF3 error: %s", err2, err)
+		} else if err2 != nil {
+			return err2
+		}
+	}
+	if err != nil {
+		return err
+	}
+	sdf.evals += uint64(len(pos))
+	return nil
+}
+
+// Bounds returns the bounding box of the compiled shape.
+func (sdf *SDF3VM) Bounds() ms3.Box { return sdf.bb }
+
+// Evaluations returns the total number of positions successfully evaluated over the lifetime of sdf.
+func (sdf *SDF3VM) Evaluations() uint64 { return sdf.evals }
+
+// VecPool returns the internal pool used by shapes evaluated outside of the instruction tape.
+func (sdf *SDF3VM) VecPool() *VecPool { return &sdf.vp }
+
+// NumInstructions returns the length of the compiled instruction tape.
+func (sdf *SDF3VM) NumInstructions() int { return len(sdf.vm.tape) }
+
+// NumFallbacks returns the number of shapes which are not lowered into instructions and are
+// instead evaluated by calling their own Evaluate method.
+func (sdf *SDF3VM) NumFallbacks() int { return len(sdf.vm.sdf3s) + len(sdf.vm.sdf2s) }
+
+// SDF2VM evaluates a compiled 2D shape tree. Construct with [NewVMSDF2].
+// SDF2VM holds evaluation state and is not safe for concurrent use.
+type SDF2VM struct {
+	vm    vm
+	bb    ms2.Box
+	vp    VecPool
+	evals uint64
+}
+
+// Evaluate computes the SDF distance for each position in pos, writing results into dist.
+// pos and dist must have equal non-zero length. See [SDF3VM.Evaluate].
+func (sdf *SDF2VM) Evaluate(pos []ms2.Vec, dist []float32, userData any) error {
+	if len(pos) != len(dist) {
+		return errMismatchBufferLength
+	} else if len(dist) == 0 {
+		return errEmptyBuffers
+	}
+	useOwnVecPool := userData == nil
+	if useOwnVecPool {
+		userData = &sdf.vp
+	}
+	var err error
+	for start := 0; start < len(pos) && err == nil; start += vmChunkLen {
+		end := min(start+vmChunkLen, len(pos))
+		sdf.vm.p2[0] = pos[start:end]
+		err = sdf.vm.run(dist[start:end], userData)
+	}
+	sdf.vm.p2[0] = nil
+	if useOwnVecPool {
+		err2 := sdf.vp.AssertAllReleased()
+		if err2 != nil && err != nil {
+			return fmt.Errorf("VecPool leak: %s\nSDF2 error: %s", err2, err)
+		} else if err2 != nil {
+			return err2
+		}
+	}
+	if err != nil {
+		return err
+	}
+	sdf.evals += uint64(len(pos))
+	return nil
+}
+
+// Bounds returns the bounding box of the compiled shape.
+func (sdf *SDF2VM) Bounds() ms2.Box { return sdf.bb }
+
+// Evaluations returns the total number of positions successfully evaluated over the lifetime of sdf.
+func (sdf *SDF2VM) Evaluations() uint64 { return sdf.evals }
+
+// VecPool returns the internal pool used by shapes evaluated outside of the instruction tape.
+func (sdf *SDF2VM) VecPool() *VecPool { return &sdf.vp }
+
+// NumInstructions returns the length of the compiled instruction tape.
+func (sdf *SDF2VM) NumInstructions() int { return len(sdf.vm.tape) }
+
+// NumFallbacks returns the number of shapes which are not lowered into instructions and are
+// instead evaluated by calling their own Evaluate method.
+func (sdf *SDF2VM) NumFallbacks() int { return len(sdf.vm.sdf3s) + len(sdf.vm.sdf2s) }
+
+type vmOp uint8
+
+// Instructions read registers a and b and write register dst. Distance registers are
+// named d, 3D position registers p3 and 2D position registers p2. k indexes the constant pool.
+const (
+	_ vmOp = iota
+	// Distance instructions.
+	opEval3           // d[dst] = sdf3[k].Evaluate(p3[a])
+	opEval2           // d[dst] = sdf2[k].Evaluate(p2[a])
+	opMin             // d[dst] = min(d[a], d[b])
+	opMax             // d[dst] = max(d[a], d[b])
+	opDiff            // d[dst] = max(d[a], -d[b])
+	opXor             // d[dst] = max(min(d[a], d[b]), -max(d[a], d[b]))
+	opSmoothUnion     // d[dst] = smin(d[a], d[b]) with k in consts[k]
+	opSmoothDiff      // d[dst] = smax(d[a], -d[b]) with k in consts[k]
+	opSmoothIntersect // d[dst] = smax(d[a], d[b]) with k in consts[k]
+	opAdd             // d[dst] = d[a] + d[b]
+	opAddConst        // d[dst] = d[a] + consts[k]
+	opMulConst        // d[dst] = d[a] * consts[k]
+	opShell           // d[dst] = consts[k] * (|d[a]| - consts[k])
+	opAnnulus         // d[dst] = |d[a]| - consts[k]
+	opExtrude         // d[dst] = extrusion of d[a] along Z of p3[b] with half height consts[k]
+	// Primitive instructions. Positions are translated by -consts[k:k+3] in 3D and -consts[k:k+2] in 2D
+	// before evaluation. Primitive parameters follow the translation in the constant pool.
+	opSphere   // d[dst] = sphere at p3[a] with radius consts[k+3]
+	opBox      // d[dst] = box at p3[a] with half dimensions consts[k+3:k+6] and rounding consts[k+6]
+	opCylinder // d[dst] = cylinder at p3[a] with radius consts[k+3], half height consts[k+4] and rounding consts[k+5]
+	opHex      // d[dst] = hexagonal prism at p3[a] with side consts[k+3] and half height consts[k+4]
+	opCircle   // d[dst] = circle at p2[a] with radius consts[k+2]
+	opRect     // d[dst] = rectangle at p2[a] with half dimensions consts[k+2:k+4]
+	// 3D position instructions.
+	opTranslate3 // p3[dst] = p3[a] - consts[k:k+3]
+	opTransform3 // p3[dst] = mat4[k] * p3[a]
+	opScale3     // p3[dst] = consts[k] * p3[a]
+	opSymmetry3  // p3[dst] = p3[a] with absolute value applied to axes flagged by bits of k
+	opElongate3  // p3[dst] = max(|p3[a]|-consts[k:k+3], 0); d[b] = min(max component of |p3[a]|-consts[k:k+3], 0)
+	opTwist3     // p3[dst] = p3[a] rotated around Z by consts[k]*p3[a].Z
+	opProject2   // p2[dst] = p3[a].XY
+	opRevolve2   // p2[dst] = (hypot(p3[a].X, p3[a].Z) - consts[k], p3[a].Y)
+	// 2D position instructions.
+	opTranslate2 // p2[dst] = p2[a] - consts[k:k+2]
+	opRotate2    // p2[dst] = mat2[k] * p2[a]
+	opScale2     // p2[dst] = consts[k] * p2[a]
+	opSymmetry2  // p2[dst] = p2[a] with absolute value applied to axes flagged by bits of k
+	opElongate2  // p2[dst] = max(|p2[a]|-consts[k:k+2], 0); d[b] = min(max component of |p2[a]|-consts[k:k+2], 0)
+)
+
+type vmInstr struct {
+	op        vmOp
+	dst, a, b uint16
+	k         uint32
+}
+
+// vmRegisters allocates registers of a single register file during compilation.
+type vmRegisters struct {
+	free []uint16
+	n    int
+}
+
+func (r *vmRegisters) alloc() (uint16, error) {
+	if len(r.free) > 0 {
+		reg := r.free[len(r.free)-1]
+		r.free = r.free[:len(r.free)-1]
+		return reg, nil
+	} else if r.n > 0xffff {
+		return 0, errors.New("shape tree too large for VM: ran out of registers")
+	}
+	r.n++
+	return uint16(r.n - 1), nil
+}
+
+func (r *vmRegisters) release(reg uint16) { r.free = append(r.free, reg) }
+
+// vmCompiler lowers a shape tree into an instruction tape. Compilation is done depth-first: the
+// instructions computing a node's position register are emitted before the node's children and the
+// instructions combining children distances after them.
+type vmCompiler struct {
+	tape   []vmInstr
+	consts []float32
+	mat4   []ms3.Mat4
+	mat2   []ms2.Mat2
+	sdf3s  []SDF3
+	sdf2s  []SDF2
+	d      vmRegisters
+	p3     vmRegisters
+	p2     vmRegisters
+}
+
+func (c *vmCompiler) emit(op vmOp, dst, a, b uint16, k int) {
+	c.tape = append(c.tape, vmInstr{op: op, dst: dst, a: a, b: b, k: uint32(k)})
+}
+
+func (c *vmCompiler) addConsts(v ...float32) int {
+	c.consts = append(c.consts, v...)
+	return len(c.consts) - len(v)
+}
+
+// sdf3 emits the instructions which evaluate s at the positions in register p and returns the
+// distance register holding the result.
+func (c *vmCompiler) sdf3(s glbuild.Shader3D, p uint16) (d uint16, err error) {
+	fn, params := glbuild.Describe(s)
+	switch fn {
+	case "Union":
+		shapes, _ := params[0].Value.([]glbuild.Shader3D)
+		if len(shapes) == 0 {
+			break
+		}
+		d, err = c.sdf3(shapes[0], p)
+		for i := 1; i < len(shapes) && err == nil; i++ {
+			err = c.binary3(opMin, d, shapes[i], p, 0)
+		}
+		return d, err
+
+	case "Intersection", "Difference", "Xor":
+		op := map[string]vmOp{"Intersection": opMax, "Difference": opDiff, "Xor": opXor}[fn]
+		d, err = c.sdf3(params[0].Value.(glbuild.Shader3D), p)
+		if err != nil {
+			return 0, err
+		}
+		return d, c.binary3(op, d, params[1].Value.(glbuild.Shader3D), p, 0)
+
+	case "SmoothUnion", "SmoothDifference", "SmoothIntersect":
+		op := map[string]vmOp{"SmoothUnion": opSmoothUnion, "SmoothDifference": opSmoothDiff, "SmoothIntersect": opSmoothIntersect}[fn]
+		k := c.addConsts(params[0].Value.(float32))
+		d, err = c.sdf3(params[1].Value.(glbuild.Shader3D), p)
+		if err != nil {
+			return 0, err
+		}
+		return d, c.binary3(op, d, params[2].Value.(glbuild.Shader3D), p, k)
+
+	case "Offset":
+		d, err = c.sdf3(params[0].Value.(glbuild.Shader3D), p)
+		c.emit(opAddConst, d, d, 0, c.addConsts(params[1].Value.(float32)))
+		return d, err
+
+	case "Translate":
+		child := params[0].Value.(glbuild.Shader3D)
+		off := ms3.Vec{X: params[1].Value.(float32), Y: params[2].Value.(float32), Z: params[3].Value.(float32)}
+		if d, ok, err := c.primitive3(child, p, off); ok {
+			return d, err
+		}
+		return c.transformed3(opTranslate3, child, p, c.addConsts(off.X, off.Y, off.Z))
+
+	case "Transform":
+		c.mat4 = append(c.mat4, params[1].Value.(ms3.Mat4).Inverse())
+		return c.transformed3(opTransform3, params[0].Value.(glbuild.Shader3D), p, len(c.mat4)-1)
+
+	case "Scale":
+		factor := params[1].Value.(float32)
+		d, err = c.transformed3(opScale3, params[0].Value.(glbuild.Shader3D), p, c.addConsts(1./factor))
+		c.emit(opMulConst, d, d, 0, c.addConsts(factor))
+		return d, err
+
+	case "Shell":
+		thick := params[1].Value.(float32)
+		d, err = c.transformed3(opScale3, params[0].Value.(glbuild.Shader3D), p, c.addConsts(1/thick))
+		c.emit(opShell, d, d, 0, c.addConsts(thick))
+		return d, err
+
+	case "Symmetry":
+		var bits int
+		for i := 0; i < 3; i++ {
+			if params[i+1].Value.(bool) {
+				bits |= 1 << i
+			}
+		}
+		return c.transformed3(opSymmetry3, params[0].Value.(glbuild.Shader3D), p, bits)
+
+	case "Twist":
+		return c.transformed3(opTwist3, params[0].Value.(glbuild.Shader3D), p, c.addConsts(params[1].Value.(float32)))
+
+	case "Elongate":
+		h := ms3.Scale(0.5, ms3.Vec{X: params[1].Value.(float32), Y: params[2].Value.(float32), Z: params[3].Value.(float32)})
+		q, err := c.p3.alloc()
+		if err != nil {
+			return 0, err
+		}
+		aux, err := c.d.alloc()
+		if err != nil {
+			return 0, err
+		}
+		c.emit(opElongate3, q, p, aux, c.addConsts(h.X, h.Y, h.Z))
+		d, err = c.sdf3(params[0].Value.(glbuild.Shader3D), q)
+		c.p3.release(q)
+		c.emit(opAdd, d, d, aux, 0)
+		c.d.release(aux)
+		return d, err
+
+	case "Extrude", "Revolve":
+		q, err := c.p2.alloc()
+		if err != nil {
+			return 0, err
+		}
+		if fn == "Extrude" {
+			c.emit(opProject2, q, p, 0, 0)
+		} else {
+			c.emit(opRevolve2, q, p, 0, c.addConsts(params[1].Value.(float32)))
+		}
+		d, err = c.sdf2(params[0].Value.(glbuild.Shader2D), q)
+		c.p2.release(q)
+		if fn == "Extrude" {
+			c.emit(opExtrude, d, d, p, c.addConsts(params[1].Value.(float32)/2))
+		}
+		return d, err
+	}
+	if d, ok, err := c.primitive3(s, p, ms3.Vec{}); ok {
+		return d, err
+	}
+	sdf, err := AssertSDF3(s)
+	if err != nil {
+		return 0, err
+	}
+	d, err = c.d.alloc()
+	c.sdf3s = append(c.sdf3s, sdf)
+	c.emit(opEval3, d, p, 0, len(c.sdf3s)-1)
+	return d, err
+}
+
+// primitive3 emits the instruction which evaluates the primitive s translated by off at p.
+// ok is false if s is not a primitive known by the compiler, in which case nothing is emitted.
+func (c *vmCompiler) primitive3(s glbuild.Shader3D, p uint16, off ms3.Vec) (d uint16, ok bool, err error) {
+	fn, params := glbuild.Describe(s)
+	var op vmOp
+	k := len(c.consts)
+	switch fn {
+	case "NewSphere":
+		op = opSphere
+		c.addConsts(off.X, off.Y, off.Z, params[0].Value.(float32))
+	case "NewBox":
+		op = opBox
+		x, y, z := params[0].Value.(float32), params[1].Value.(float32), params[2].Value.(float32)
+		c.addConsts(off.X, off.Y, off.Z, x/2, y/2, z/2, params[3].Value.(float32))
+	case "NewCylinder":
+		op = opCylinder
+		r, h, round := params[0].Value.(float32), params[1].Value.(float32), params[2].Value.(float32)
+		c.addConsts(off.X, off.Y, off.Z, r, (h-2*round)/2, round)
+	case "NewHexagonalPrism":
+		op = opHex
+		c.addConsts(off.X, off.Y, off.Z, params[0].Value.(float32), params[1].Value.(float32))
+	default:
+		return 0, false, nil
+	}
+	d, err = c.d.alloc()
+	c.emit(op, d, p, 0, k)
+	return d, true, err
+}
+
+// binary3 emits the instructions which evaluate s at p and combine the result into register d with op.
+func (c *vmCompiler) binary3(op vmOp, d uint16, s glbuild.Shader3D, p uint16, k int) error {
+	d2, err := c.sdf3(s, p)
+	if err != nil {
+		return err
+	}
+	c.emit(op, d, d, d2, k)
+	c.d.release(d2)
+	return nil
+}
+
+// transformed3 emits the instructions which evaluate s at the positions p transformed with op.
+func (c *vmCompiler) transformed3(op vmOp, s glbuild.Shader3D, p uint16, k int) (uint16, error) {
+	q, err := c.p3.alloc()
+	if err != nil {
+		return 0, err
+	}
+	c.emit(op, q, p, 0, k)
+	d, err := c.sdf3(s, q)
+	c.p3.release(q)
+	return d, err
+}
+
+// sdf2 is the 2D counterpart of sdf3.
+func (c *vmCompiler) sdf2(s glbuild.Shader2D, p uint16) (d uint16, err error) {
+	fn, params := glbuild.Describe(s)
+	switch fn {
+	case "Union2D":
+		shapes, _ := params[0].Value.([]glbuild.Shader2D)
+		if len(shapes) == 0 {
+			break
+		}
+		d, err = c.sdf2(shapes[0], p)
+		for i := 1; i < len(shapes) && err == nil; i++ {
+			err = c.binary2(opMin, d, shapes[i], p)
+		}
+		return d, err
+
+	case "Intersection2D", "Difference2D", "Xor2D":
+		op := map[string]vmOp{"Intersection2D": opMax, "Difference2D": opDiff, "Xor2D": opXor}[fn]
+		d, err = c.sdf2(params[0].Value.(glbuild.Shader2D), p)
+		if err != nil {
+			return 0, err
+		}
+		return d, c.binary2(op, d, params[1].Value.(glbuild.Shader2D), p)
+
+	case "Offset2D":
+		d, err = c.sdf2(params[0].Value.(glbuild.Shader2D), p)
+		c.emit(opAddConst, d, d, 0, c.addConsts(params[1].Value.(float32)))
+		return d, err
+
+	case "Annulus":
+		d, err = c.sdf2(params[0].Value.(glbuild.Shader2D), p)
+		c.emit(opAnnulus, d, d, 0, c.addConsts(params[1].Value.(float32)))
+		return d, err
+
+	case "Translate2D":
+		child := params[0].Value.(glbuild.Shader2D)
+		off := ms2.Vec{X: params[1].Value.(float32), Y: params[2].Value.(float32)}
+		if d, ok, err := c.primitive2(child, p, off); ok {
+			return d, err
+		}
+		return c.transformed2(opTranslate2, child, p, c.addConsts(off.X, off.Y))
+
+	case "Rotate2D":
+		c.mat2 = append(c.mat2, ms2.RotationMat2(params[1].Value.(float32)).Inverse())
+		return c.transformed2(opRotate2, params[0].Value.(glbuild.Shader2D), p, len(c.mat2)-1)
+
+	case "Scale2D":
+		factor := params[1].Value.(float32)
+		d, err = c.transformed2(opScale2, params[0].Value.(glbuild.Shader2D), p, c.addConsts(1./factor))
+		c.emit(opMulConst, d, d, 0, c.addConsts(factor))
+		return d, err
+
+	case "Symmetry2D":
+		var bits int
+		for i := 0; i < 2; i++ {
+			if params[i+1].Value.(bool) {
+				bits |= 1 << i
+			}
+		}
+		return c.transformed2(opSymmetry2, params[0].Value.(glbuild.Shader2D), p, bits)
+
+	case "Elongate2D":
+		h := ms2.Scale(0.5, ms2.Vec{X: params[1].Value.(float32), Y: params[2].Value.(float32)})
+		q, err := c.p2.alloc()
+		if err != nil {
+			return 0, err
+		}
+		aux, err := c.d.alloc()
+		if err != nil {
+			return 0, err
+		}
+		c.emit(opElongate2, q, p, aux, c.addConsts(h.X, h.Y))
+		d, err = c.sdf2(params[0].Value.(glbuild.Shader2D), q)
+		c.p2.release(q)
+		c.emit(opAdd, d, d, aux, 0)
+		c.d.release(aux)
+		return d, err
+	}
+	if d, ok, err := c.primitive2(s, p, ms2.Vec{}); ok {
+		return d, err
+	}
+	sdf, err := AssertSDF2(s)
+	if err != nil {
+		return 0, err
+	}
+	d, err = c.d.alloc()
+	c.sdf2s = append(c.sdf2s, sdf)
+	c.emit(opEval2, d, p, 0, len(c.sdf2s)-1)
+	return d, err
+}
+
+// primitive2 is the 2D counterpart of primitive3.
+func (c *vmCompiler) primitive2(s glbuild.Shader2D, p uint16, off ms2.Vec) (d uint16, ok bool, err error) {
+	fn, params := glbuild.Describe(s)
+	var op vmOp
+	k := len(c.consts)
+	switch fn {
+	case "NewCircle":
+		op = opCircle
+		c.addConsts(off.X, off.Y, params[0].Value.(float32))
+	case "NewRectangle":
+		op = opRect
+		c.addConsts(off.X, off.Y, params[0].Value.(float32)/2, params[1].Value.(float32)/2)
+	default:
+		return 0, false, nil
+	}
+	d, err = c.d.alloc()
+	c.emit(op, d, p, 0, k)
+	return d, true, err
+}
+
+func (c *vmCompiler) binary2(op vmOp, d uint16, s glbuild.Shader2D, p uint16) error {
+	d2, err := c.sdf2(s, p)
+	if err != nil {
+		return err
+	}
+	c.emit(op, d, d, d2, 0)
+	c.d.release(d2)
+	return nil
+}
+
+func (c *vmCompiler) transformed2(op vmOp, s glbuild.Shader2D, p uint16, k int) (uint16, error) {
+	q, err := c.p2.alloc()
+	if err != nil {
+		return 0, err
+	}
+	c.emit(op, q, p, 0, k)
+	d, err := c.sdf2(s, q)
+	c.p2.release(q)
+	return d, err
+}
+
+// vm executes a compiled instruction tape over a chunk of positions.
+type vm struct {
+	tape   []vmInstr
+	consts []float32
+	mat4   []ms3.Mat4
+	mat2   []ms2.Mat2
+	sdf3s  []SDF3
+	sdf2s  []SDF2
+	// Registers. Register 0 of the position register file of the root's dimension
+	// is set to the input positions before running the tape.
+	d  [][]float32
+	p3 [][]ms3.Vec
+	p2 [][]ms2.Vec
+	// out is the distance register holding the result.
+	out uint16
+}
+
+func (m *vm) init(c *vmCompiler, out uint16) {
+	*m = vm{
+		tape:   c.tape,
+		consts: c.consts,
+		mat4:   c.mat4,
+		mat2:   c.mat2,
+		sdf3s:  c.sdf3s,
+		sdf2s:  c.sdf2s,
+		d:      makeRegisters[float32](c.d.n),
+		p3:     makeRegisters[ms3.Vec](c.p3.n),
+		p2:     makeRegisters[ms2.Vec](c.p2.n),
+		out:    out,
+	}
+}
+
+// makeRegisters allocates n registers of vmChunkLen elements from a single contiguous buffer.
+func makeRegisters[T any](n int) [][]T {
+	regs := make([][]T, n)
+	buf := make([]T, n*vmChunkLen)
+	for i := range regs {
+		regs[i] = buf[i*vmChunkLen : (i+1)*vmChunkLen : (i+1)*vmChunkLen]
+	}
+	return regs
+}
+
+// run executes the tape over the input position register and stores the resulting distances in dist.
+// The length of dist is the number of positions in the chunk.
+func (m *vm) run(dist []float32, userData any) (err error) {
+	n := len(dist)
+	for _, ins := range m.tape {
+		k := ins.k
+		switch ins.op {
+		case opEval3:
+			err = m.sdf3s[k].Evaluate(m.p3[ins.a][:n], m.d[ins.dst][:n], userData)
+		case opEval2:
+			err = m.sdf2s[k].Evaluate(m.p2[ins.a][:n], m.d[ins.dst][:n], userData)
+		case opMin:
+			dst, a, b := m.d[ins.dst][:n], m.d[ins.a][:n], m.d[ins.b][:n]
+			for i := range dst {
+				dst[i] = min(a[i], b[i])
+			}
+		case opMax:
+			dst, a, b := m.d[ins.dst][:n], m.d[ins.a][:n], m.d[ins.b][:n]
+			for i := range dst {
+				dst[i] = max(a[i], b[i])
+			}
+		case opDiff:
+			dst, a, b := m.d[ins.dst][:n], m.d[ins.a][:n], m.d[ins.b][:n]
+			for i := range dst {
+				dst[i] = max(a[i], -b[i])
+			}
+		case opXor:
+			dst, a, b := m.d[ins.dst][:n], m.d[ins.a][:n], m.d[ins.b][:n]
+			for i := range dst {
+				dst[i] = max(min(a[i], b[i]), -max(a[i], b[i]))
+			}
+		case opSmoothUnion:
+			dst, a, b, k := m.d[ins.dst][:n], m.d[ins.a][:n], m.d[ins.b][:n], m.consts[k]
+			for i := range dst {
+				h := clamp(0.5+0.5*(b[i]-a[i])/k, 0, 1)
+				dst[i] = mix(b[i], a[i], h) - k*h*(1-h)
+			}
+		case opSmoothDiff:
+			dst, a, b, k := m.d[ins.dst][:n], m.d[ins.a][:n], m.d[ins.b][:n], m.consts[k]
+			for i := range dst {
+				h := clamp(0.5-0.5*(b[i]+a[i])/k, 0, 1)
+				dst[i] = mix(a[i], -b[i], h) + k*h*(1-h)
+			}
+		case opSmoothIntersect:
+			dst, a, b, k := m.d[ins.dst][:n], m.d[ins.a][:n], m.d[ins.b][:n], m.consts[k]
+			for i := range dst {
+				h := clamp(0.5-0.5*(b[i]-a[i])/k, 0, 1)
+				dst[i] = mix(b[i], a[i], h) + k*h*(1-h)
+			}
+		case opAdd:
+			dst, a, b := m.d[ins.dst][:n], m.d[ins.a][:n], m.d[ins.b][:n]
+			for i := range dst {
+				dst[i] = a[i] + b[i]
+			}
+		case opAddConst:
+			dst, a, c := m.d[ins.dst][:n], m.d[ins.a][:n], m.consts[k]
+			for i := range dst {
+				dst[i] = a[i] + c
+			}
+		case opMulConst:
+			dst, a, c := m.d[ins.dst][:n], m.d[ins.a][:n], m.consts[k]
+			for i := range dst {
+				dst[i] = a[i] * c
+			}
+		case opShell:
+			dst, a, c := m.d[ins.dst][:n], m.d[ins.a][:n], m.consts[k]
+			for i := range dst {
+				dst[i] = c * (abs(a[i]) - c)
+			}
+		case opAnnulus:
+			dst, a, c := m.d[ins.dst][:n], m.d[ins.a][:n], m.consts[k]
+			for i := range dst {
+				dst[i] = abs(a[i]) - c
+			}
+		case opExtrude:
+			dst, a, p, h := m.d[ins.dst][:n], m.d[ins.a][:n], m.p3[ins.b][:n], m.consts[k]
+			for i := range dst {
+				d := a[i]
+				wy := abs(p[i].Z) - h
+				ox, oy := max(d, 0), max(wy, 0)
+				dst[i] = min(0, max(d, wy)) + sqrtf(ox*ox+oy*oy)
+			}
+
+		case opSphere:
+			dst, a := m.d[ins.dst][:n], m.p3[ins.a][:n]
+			t, r := m.const3(k), m.consts[k+3]
+			for i, p := range a {
+				p = ms3.Sub(p, t)
+				dst[i] = sqrtf(p.X*p.X+p.Y*p.Y+p.Z*p.Z) - r
+			}
+		case opBox:
+			dst, a := m.d[ins.dst][:n], m.p3[ins.a][:n]
+			t, h, r := m.const3(k), m.const3(k+3), m.consts[k+6]
+			for i, p := range a {
+				p = ms3.Sub(p, t)
+				qx := abs(p.X) - h.X + r
+				qy := abs(p.Y) - h.Y + r
+				qz := abs(p.Z) - h.Z + r
+				ox, oy, oz := max(qx, 0), max(qy, 0), max(qz, 0)
+				dst[i] = sqrtf(ox*ox+oy*oy+oz*oz) + min(max(qx, qy, qz), 0) - r
+			}
+		case opCylinder:
+			dst, a := m.d[ins.dst][:n], m.p3[ins.a][:n]
+			t, r, h, round := m.const3(k), m.consts[k+3], m.consts[k+4], m.consts[k+5]
+			for i, p := range a {
+				p = ms3.Sub(p, t)
+				dx := sqrtf(p.X*p.X+p.Y*p.Y) - r + round
+				dy := abs(p.Z) - h
+				ox, oy := max(dx, 0), max(dy, 0)
+				dst[i] = min(max(dx, dy), 0) + sqrtf(ox*ox+oy*oy) - round
+			}
+		case opHex:
+			const k1, k2, k3 = -0.8660254037844386, 0.5, 0.57735
+			dst, a := m.d[ins.dst][:n], m.p3[ins.a][:n]
+			t, h1, h2 := m.const3(k), m.consts[k+3], m.consts[k+4]
+			clm := k3 * h1
+			for i, p := range a {
+				p = ms3.AbsElem(ms3.Sub(p, t))
+				pm := min(k1*p.X+k2*p.Y, 0)
+				p.X -= 2 * k1 * pm
+				p.Y -= 2 * k2 * pm
+				dx, dy := p.X-clamp(p.X, -clm, clm), p.Y-h1
+				d1 := sqrtf(dx*dx + dy*dy)
+				if dy < 0 {
+					d1 = -d1
+				}
+				d2 := p.Z - h2
+				o1, o2 := max(d1, 0), max(d2, 0)
+				dst[i] = min(max(d1, d2), 0) + sqrtf(o1*o1+o2*o2)
+			}
+		case opCircle:
+			dst, a := m.d[ins.dst][:n], m.p2[ins.a][:n]
+			t, r := m.const2(k), m.consts[k+2]
+			for i, p := range a {
+				p = ms2.Sub(p, t)
+				dst[i] = sqrtf(p.X*p.X+p.Y*p.Y) - r
+			}
+		case opRect:
+			dst, a := m.d[ins.dst][:n], m.p2[ins.a][:n]
+			t, h := m.const2(k), m.const2(k+2)
+			for i, p := range a {
+				p = ms2.Sub(p, t)
+				qx, qy := abs(p.X)-h.X, abs(p.Y)-h.Y
+				ox, oy := max(qx, 0), max(qy, 0)
+				dst[i] = sqrtf(ox*ox+oy*oy) + min(max(qx, qy), 0)
+			}
+
+		case opTranslate3:
+			dst, a := m.p3[ins.dst][:n], m.p3[ins.a][:n]
+			t := ms3.Vec{X: m.consts[k], Y: m.consts[k+1], Z: m.consts[k+2]}
+			for i := range dst {
+				dst[i] = ms3.Sub(a[i], t)
+			}
+		case opTransform3:
+			dst, a, t := m.p3[ins.dst][:n], m.p3[ins.a][:n], m.mat4[k]
+			for i := range dst {
+				dst[i] = t.MulPosition(a[i])
+			}
+		case opScale3:
+			dst, a, c := m.p3[ins.dst][:n], m.p3[ins.a][:n], m.consts[k]
+			for i := range dst {
+				dst[i] = ms3.Scale(c, a[i])
+			}
+		case opSymmetry3:
+			dst, a := m.p3[ins.dst][:n], m.p3[ins.a][:n]
+			for i, p := range a {
+				if k&1 != 0 {
+					p.X = abs(p.X)
+				}
+				if k&2 != 0 {
+					p.Y = abs(p.Y)
+				}
+				if k&4 != 0 {
+					p.Z = abs(p.Z)
+				}
+				dst[i] = p
+			}
+		case opElongate3:
+			dst, a, aux := m.p3[ins.dst][:n], m.p3[ins.a][:n], m.d[ins.b][:n]
+			h := ms3.Vec{X: m.consts[k], Y: m.consts[k+1], Z: m.consts[k+2]}
+			for i, p := range a {
+				q := ms3.Sub(ms3.AbsElem(p), h)
+				aux[i] = min(q.Max(), 0)
+				dst[i] = ms3.MaxElem(q, ms3.Vec{})
+			}
+		case opTwist3:
+			dst, a, c := m.p3[ins.dst][:n], m.p3[ins.a][:n], m.consts[k]
+			for i, p := range a {
+				cos := math32.Cos(c * p.Z)
+				sin := math32.Sin(c * p.Z)
+				dst[i] = ms3.Vec{X: cos*p.X - sin*p.Y, Y: sin*p.X + cos*p.Y, Z: p.Z}
+			}
+		case opProject2:
+			dst, a := m.p2[ins.dst][:n], m.p3[ins.a][:n]
+			for i, p := range a {
+				dst[i] = ms2.Vec{X: p.X, Y: p.Y}
+			}
+		case opRevolve2:
+			dst, a, o := m.p2[ins.dst][:n], m.p3[ins.a][:n], m.consts[k]
+			for i, p := range a {
+				dst[i] = ms2.Vec{X: sqrtf(p.X*p.X+p.Z*p.Z) - o, Y: p.Y}
+			}
+
+		case opTranslate2:
+			dst, a := m.p2[ins.dst][:n], m.p2[ins.a][:n]
+			t := ms2.Vec{X: m.consts[k], Y: m.consts[k+1]}
+			for i := range dst {
+				dst[i] = ms2.Sub(a[i], t)
+			}
+		case opRotate2:
+			dst, a, t := m.p2[ins.dst][:n], m.p2[ins.a][:n], m.mat2[k]
+			for i := range dst {
+				dst[i] = ms2.MulMatVec(t, a[i])
+			}
+		case opScale2:
+			dst, a, c := m.p2[ins.dst][:n], m.p2[ins.a][:n], m.consts[k]
+			for i := range dst {
+				dst[i] = ms2.Scale(c, a[i])
+			}
+		case opSymmetry2:
+			dst, a := m.p2[ins.dst][:n], m.p2[ins.a][:n]
+			for i, p := range a {
+				if k&1 != 0 {
+					p.X = abs(p.X)
+				}
+				if k&2 != 0 {
+					p.Y = abs(p.Y)
+				}
+				dst[i] = p
+			}
+		case opElongate2:
+			dst, a, aux := m.p2[ins.dst][:n], m.p2[ins.a][:n], m.d[ins.b][:n]
+			h := ms2.Vec{X: m.consts[k], Y: m.consts[k+1]}
+			for i, p := range a {
+				q := ms2.Sub(ms2.AbsElem(p), h)
+				aux[i] = min(q.Max(), 0)
+				dst[i] = ms2.MaxElem(q, ms2.Vec{})
+			}
+		default:
+			err = fmt.Errorf("invalid VM instruction %d", ins.op)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	copy(dist, m.d[m.out][:n])
+	return nil
+}
+
+func (m *vm) const3(k uint32) ms3.Vec {
+	return ms3.Vec{X: m.consts[k], Y: m.consts[k+1], Z: m.consts[k+2]}
+}
+
+func (m *vm) const2(k uint32) ms2.Vec {
+	return ms2.Vec{X: m.consts[k], Y: m.consts[k+1]}
+}
+
+// sqrtf is faster than math32.Sqrt since the float64 square root is a compiler intrinsic.
+func sqrtf(v float32) float32 {
+	return float32(math.Sqrt(float64(v)))
+}
+
+func abs(v float32) float32 {
+	return math32.Float32frombits(math32.Float32bits(v) &^ (1 << 31))
+}
+
+func clamp(v, Min, Max float32) float32 {
+	if v < Min {
+		return Min
+	} else if v > Max {
+		return Max
+	}
+	return v
+}
+
+func mix(x, y, a float32) float32 {
+	return x*(1-a) + y*a
+}
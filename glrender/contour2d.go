@@ -0,0 +1,226 @@
+package glrender
+
+import (
+	"errors"
+
+	"github.com/chewxy/math32"
+	"github.com/soypat/geometry/ms2"
+	"github.com/soypat/gsdf/gleval"
+)
+
+// maxContourCells limits the amount of grid cells evaluated by [ContoursSDF2] to prevent runaway memory usage.
+const maxContourCells = 1 << 26
+
+// ContoursSDF2 extracts the zero level set of a 2D SDF as closed polylines using marching squares
+// on a grid of square cells of side tolerance spanning the SDF bounds. The closing vertex of each polyline is not repeated.
+// Polylines are oriented so that the interior of the SDF lies to their left: outer contours are counter-clockwise and holes are clockwise.
+// It uses userData as an argument to all [gleval.SDF2.Evaluate] calls.
+func ContoursSDF2(sdf gleval.SDF2, tolerance float32, userData any) ([][]ms2.Vec, error) {
+	if tolerance <= 0 || math32.IsNaN(tolerance) || math32.IsInf(tolerance, 0) {
+		return nil, errors.New("invalid contour tolerance")
+	}
+	bb := sdf.Bounds()
+	sz := bb.Size()
+	if bb.Empty() || sz.X <= 0 || sz.Y <= 0 {
+		return nil, errors.New("empty SDF2 bounds")
+	}
+	// Pad grid by one cell on each side so that the grid boundary lies outside the shape and all contours are closed.
+	nx := int(math32.Ceil(sz.X/tolerance)) + 2
+	ny := int(math32.Ceil(sz.Y/tolerance)) + 2
+	if nx*ny > maxContourCells {
+		return nil, errors.New("contour tolerance too small for SDF2 bounds")
+	}
+	origin := ms2.Sub(bb.Min, ms2.Vec{X: tolerance, Y: tolerance})
+	ms := marchingSquares{
+		nx:     nx,
+		ny:     ny,
+		res:    tolerance,
+		origin: origin,
+		next:   make(map[int]int),
+		verts:  make(map[int]ms2.Vec),
+	}
+	pos := make([]ms2.Vec, nx+1)
+	prev := make([]float32, nx+1)
+	curr := make([]float32, nx+1)
+	for j := 0; j <= ny; j++ {
+		y := origin.Y + float32(j)*tolerance
+		for i := range pos {
+			pos[i] = ms2.Vec{X: origin.X + float32(i)*tolerance, Y: y}
+		}
+		err := sdf.Evaluate(pos, curr, userData)
+		if err != nil {
+			return nil, err
+		}
+		if j > 0 {
+			ms.processRow(j-1, prev, curr)
+		}
+		prev, curr = curr, prev
+	}
+	return ms.contours(tolerance / 64)
+}
+
+// marchingSquares accumulates oriented contour segments. Segment endpoints lie on grid
+// edges and are identified by a unique edge index so that neighboring cells share vertices.
+type marchingSquares struct {
+	nx, ny int
+	res    float32
+	origin ms2.Vec
+	// next maps a segment's start edge to its end edge.
+	next   map[int]int
+	starts []int // starts keeps segment start edges in insertion order for deterministic output.
+	verts  map[int]ms2.Vec
+}
+
+// processRow generates segments for cell row j given distances at the row's bottom and top grid corners.
+func (ms *marchingSquares) processRow(j int, bottom, top []float32) {
+	for i := 0; i < ms.nx; i++ {
+		// Corners and edges in counter-clockwise order starting at bottom left corner.
+		// Edge k goes from corner k to corner k+1.
+		d := [4]float32{bottom[i], bottom[i+1], top[i+1], top[i]}
+		var leaving, entering [2]int // Edges leaving and entering the interior when traversing the cell counter-clockwise.
+		var n, m int
+		for k := range d {
+			inK, inNext := d[k] < 0, d[(k+1)%4] < 0
+			if inK && !inNext {
+				leaving[n] = k
+				n++
+			} else if !inK && inNext {
+				entering[m] = k
+				m++
+			}
+		}
+		switch n {
+		case 0:
+			continue
+		case 1:
+			ms.addSegment(i, j, &d, leaving[0], entering[0])
+		case 2:
+			// Saddle cell. Average of corners decides if interior corners are connected through cell center.
+			centerInside := d[0]+d[1]+d[2]+d[3] < 0
+			for _, k := range leaving {
+				end := (k + 3) % 4 // Cut off the interior corner k.
+				if centerInside {
+					end = (k + 1) % 4 // Cut off the exterior corner k+1.
+				}
+				ms.addSegment(i, j, &d, k, end)
+			}
+		}
+	}
+}
+
+// addSegment adds a segment in cell (i,j) that goes from edge k0 to edge k1, leaving the interior to its left.
+func (ms *marchingSquares) addSegment(i, j int, d *[4]float32, k0, k1 int) {
+	e0 := ms.cellEdge(i, j, k0)
+	e1 := ms.cellEdge(i, j, k1)
+	for _, k := range [2]int{k0, k1} {
+		e := ms.cellEdge(i, j, k)
+		if _, ok := ms.verts[e]; ok {
+			continue
+		}
+		// Interpolate from lower-left to upper-right corner of edge so shared edges yield identical vertices.
+		a, b := k, (k+1)%4
+		if k >= 2 {
+			a, b = b, a
+		}
+		t := d[a] / (d[a] - d[b])
+		pa, pb := ms.corner(i, j, a), ms.corner(i, j, b)
+		ms.verts[e] = ms2.InterpElem(pa, pb, ms2.Vec{X: t, Y: t})
+	}
+	ms.next[e0] = e1
+	ms.starts = append(ms.starts, e0)
+}
+
+// corner returns the position of corner k of cell (i,j).
+func (ms *marchingSquares) corner(i, j, k int) ms2.Vec {
+	switch k {
+	case 1:
+		i++
+	case 2:
+		i++
+		j++
+	case 3:
+		j++
+	}
+	return ms2.Vec{X: ms.origin.X + float32(i)*ms.res, Y: ms.origin.Y + float32(j)*ms.res}
+}
+
+// cellEdge returns the index of edge k of cell (i,j).
+func (ms *marchingSquares) cellEdge(i, j, k int) int {
+	switch k {
+	case 0:
+		return ms.hedge(i, j)
+	case 1:
+		return ms.vedge(i+1, j)
+	case 2:
+		return ms.hedge(i, j+1)
+	default:
+		return ms.vedge(i, j)
+	}
+}
+
+// contours chains segments into closed polylines and removes vertices that deviate less than simplifyTol from a straight line.
+func (ms *marchingSquares) contours(simplifyTol float32) ([][]ms2.Vec, error) {
+	var polys [][]ms2.Vec
+	visited := make(map[int]bool, len(ms.next))
+	for _, start := range ms.starts {
+		if visited[start] {
+			continue
+		}
+		var poly []ms2.Vec
+		e := start
+		for !visited[e] {
+			visited[e] = true
+			poly = append(poly, ms.verts[e])
+			next, ok := ms.next[e]
+			if !ok {
+				return nil, errors.New("open contour found, SDF2 may be negative at its bounds")
+			}
+			e = next
+		}
+		if e != start {
+			return nil, errors.New("malformed contour")
+		}
+		poly = simplifyClosedPolyline(poly, simplifyTol)
+		if len(poly) >= 3 {
+			polys = append(polys, poly)
+		}
+	}
+	return polys, nil
+}
+
+// simplifyClosedPolyline removes vertices of a closed polyline that lie within tol of the line joining their neighbors.
+func simplifyClosedPolyline(poly []ms2.Vec, tol float32) []ms2.Vec {
+	if len(poly) < 4 {
+		return poly
+	}
+	out := poly[:1]
+	for i := 1; i < len(poly); i++ {
+		a := out[len(out)-1]
+		b := poly[i]
+		c := poly[(i+1)%len(poly)]
+		if distToLine(a, c, b) > tol {
+			out = append(out, b)
+		}
+	}
+	// Check the first vertex which was unconditionally kept.
+	if len(out) > 3 && distToLine(out[len(out)-1], out[1], out[0]) <= tol {
+		out = out[1:]
+	}
+	return out
+}
+
+// distToLine returns the distance from p to the line passing through a and b.
+func distToLine(a, b, p ms2.Vec) float32 {
+	ab := ms2.Sub(b, a)
+	l := ms2.Norm(ab)
+	if l == 0 {
+		return ms2.Norm(ms2.Sub(p, a))
+	}
+	return math32.Abs(ms2.Cross(ab, ms2.Sub(p, a))) / l
+}
+
+// hedge returns the index of the horizontal edge starting at grid corner (i,j).
+func (ms *marchingSquares) hedge(i, j int) int { return j*ms.nx + i }
+
+// vedge returns the index of the vertical edge starting at grid corner (i,j).
+func (ms *marchingSquares) vedge(i, j int) int { return ms.nx*(ms.ny+1) + j*(ms.nx+1) + i }
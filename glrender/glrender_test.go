@@ -4,14 +4,19 @@ import (
 	"bytes"
 	"errors"
 	"image"
+	"image/color"
 	"image/png"
+	"io"
 	"math"
 	"os"
+	"reflect"
 	"testing"
 
 	"github.com/soypat/geometry/i3"
+	"github.com/soypat/geometry/ms2"
 	"github.com/soypat/geometry/ms3"
 	"github.com/soypat/gsdf"
+	"github.com/soypat/gsdf/forge/dxfsdf"
 	"github.com/soypat/gsdf/forge/threads"
 	"github.com/soypat/gsdf/glbuild"
 	"github.com/soypat/gsdf/gleval"
@@ -123,6 +128,92 @@ func TestOctree(t *testing.T) {
 	}
 }
 
+func TestOctreeIntervalPruning(t *testing.T) {
+	// Twisted shapes are not exact SDFs and are over-pruned by point distance pruning.
+	shapes := []glbuild.Shader3D{
+		bld.Twist(bld.NewBox(3, 0.3, 2, 0), 4),
+		bld.Twist(bld.Elongate(bld.NewSphere(0.3), 3, 0, 1), 3),
+	}
+	for _, shape := range shapes {
+		sdf, err := gleval.NewCPUSDF3(shape)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res := shape.Bounds().Size().Max() / 64
+		var flat FlatRenderer
+		err = flat.Reset(sdf, res, 1<<12, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := testRenderer(t, &flat, nil)
+		oct, err := NewOctreeRenderer(sdf, res, 1<<12)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := testRenderer(t, oct, nil)
+		if oct.TotalPruned() == 0 {
+			t.Error("expected octree to prune cubes")
+		}
+		if len(got) != len(want) {
+			t.Errorf("want %d triangles as rendered without pruning, got %d", len(want), len(got))
+		}
+	}
+}
+
+func TestMarchingCubesVsDualContour(t *testing.T) {
+	for _, test := range []struct {
+		shape glbuild.Shader3D
+		sharp bool // Dual contouring preserves sharp features better than marching cubes.
+	}{
+		{shape: bld.NewSphere(1)},
+		{shape: bld.NewBox(1, 2, 0.5, 0), sharp: true},
+		{shape: bld.Union(bld.NewSphere(0.4), bld.Translate(bld.NewSphere(0.3), 0, 0, 0.45))},
+	} {
+		shape := test.shape
+		sdf, err := gleval.NewCPUSDF3(shape)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res := shape.Bounds().Size().Max() / 32
+		oct, err := NewOctreeRenderer(sdf, res, 1<<12)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mc := testMeshDeviation(t, sdf, testRenderer(t, oct, nil))
+		var dcr DualContourRenderer
+		var vp gleval.VecPool
+		err = dcr.Reset(sdf, res, &DualContourLeastSquares{}, &vp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tris, err := dcr.RenderAll(nil, &vp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dc := testMeshDeviation(t, sdf, tris)
+		name := string(shape.AppendShaderName(nil))
+		t.Logf("%s res=%.4f\n\tMC: max=%.5f mean=%.5f rms=%.5f\n\tDC: max=%.5f mean=%.5f rms=%.5f",
+			name, res, mc.Max, mc.Mean, mc.RMS, dc.Max, dc.Mean, dc.RMS)
+		// Both renderers must place surfaces within a cell of the true surface.
+		if mc.Max > res || dc.Max > res {
+			t.Errorf("%s: deviation above resolution %f: MC %f, DC %f", name, res, mc.Max, dc.Max)
+		}
+		if test.sharp && dc.Max >= mc.Max {
+			t.Errorf("%s: want dual contouring max deviation %f below marching cubes %f", name, dc.Max, mc.Max)
+		}
+	}
+}
+
+// testMeshDeviation returns the deviation of triangles from the surface of sdf.
+func testMeshDeviation(t *testing.T, sdf gleval.SDF3, triangles []ms3.Triangle) gleval.Deviation {
+	t.Helper()
+	dev, err := gleval.MeshDeviation(sdf, triangles, gleval.DeviationConfig{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dev
+}
+
 func testRenderer(t *testing.T, oct Renderer, userData any) []ms3.Triangle {
 	triangles, err := RenderAll(oct, userData)
 	if err != nil {
@@ -173,6 +264,243 @@ func TestRenderImage(t *testing.T) {
 	png.Encode(fp, img)
 }
 
+func TestContoursSDF2(t *testing.T) {
+	const r, rhole, tol = 1.0, 0.4, 0.02
+	s := bld.Difference2D(bld.NewCircle(r), bld.NewCircle(rhole))
+	sdf, err := gleval.NewCPUSDF2(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contours, err := ContoursSDF2(sdf, tol, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(contours) != 2 {
+		t.Fatalf("expected 2 contours, got %d", len(contours))
+	}
+	var outer, hole float32
+	for _, contour := range contours {
+		area := signedArea(contour)
+		if area > 0 {
+			outer = area
+		} else {
+			hole = area
+		}
+	}
+	const areaTol = 0.01
+	if math.Abs(float64(outer)-math.Pi*r*r) > areaTol {
+		t.Errorf("outer contour area want %f, got %f", math.Pi*r*r, outer)
+	}
+	if math.Abs(float64(hole)+math.Pi*rhole*rhole) > areaTol {
+		t.Errorf("hole contour area want %f, got %f", -math.Pi*rhole*rhole, hole)
+	}
+	var buf bytes.Buffer
+	_, err = WriteSVG(&buf, contours, UnitMillimeter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := bytes.Count(buf.Bytes(), []byte("Z")); got != len(contours) {
+		t.Errorf("expected %d closed SVG subpaths, got %d", len(contours), got)
+	}
+	buf.Reset()
+	_, err = WriteDXF(&buf, contours, UnitMillimeter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dxf := buf.Bytes()
+	if !bytes.Contains(dxf, []byte("9\n$ACADVER\n1\nAC1015\n")) || !bytes.Contains(dxf, []byte("9\n$INSUNITS\n70\n4\n")) {
+		t.Error("expected AC1015 version and millimeter units in DXF header")
+	}
+	if got := bytes.Count(dxf, []byte("0\nLWPOLYLINE\n")); got != len(contours) {
+		t.Errorf("expected %d DXF polylines, got %d", len(contours), got)
+	}
+	if got := bytes.Count(dxf, []byte("100\nAcDbPolyline\n")); got != len(contours) {
+		t.Errorf("expected %d DXF polyline subclass markers, got %d", len(contours), got)
+	}
+	// Handles must be unique.
+	lines := bytes.Split(dxf, []byte("\n"))
+	handles := make(map[string]bool)
+	for i := 0; i+1 < len(lines); i += 2 {
+		if string(lines[i]) == "5" {
+			if handles[string(lines[i+1])] {
+				t.Errorf("duplicate DXF handle %s", lines[i+1])
+			}
+			handles[string(lines[i+1])] = true
+		}
+	}
+	// Decoding yields the same shape.
+	decoded, err := dxfsdf.Decode(bytes.NewReader(dxf), dxfsdf.Config{Builder: &bld, Unit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dsdf, err := gleval.NewCPUSDF2(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pos := []ms2.Vec{{}, {X: (r + rhole) / 2}, {X: 2 * r}}
+	dist := make([]float32, len(pos))
+	err = dsdf.Evaluate(pos, dist, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dist[0] <= 0 || dist[1] >= 0 || dist[2] <= 0 {
+		t.Errorf("decoded DXF: expected hole and outside positive and ring negative, got %v", dist)
+	}
+}
+
+func TestWritePLY(t *testing.T) {
+	points := []ms3.Vec{{X: 1}, {Y: 1}, {Z: 1}}
+	normals := []ms3.Vec{{X: 1}, {Y: 1}, {Z: 1}}
+	colors := []color.Color{color.White, color.Black, color.RGBA{R: 255, A: 255}}
+	for _, test := range []struct {
+		normals    []ms3.Vec
+		colors     []color.Color
+		vertexSize int
+	}{
+		{vertexSize: 12},
+		{normals: normals, vertexSize: 24},
+		{colors: colors, vertexSize: 15},
+		{normals: normals, colors: colors, vertexSize: 27},
+	} {
+		var buf bytes.Buffer
+		n, err := WritePLY(&buf, points, test.normals, test.colors)
+		if err != nil {
+			t.Fatal(err)
+		} else if n != buf.Len() {
+			t.Errorf("wrote %d bytes, reported %d", buf.Len(), n)
+		}
+		header, body, ok := bytes.Cut(buf.Bytes(), []byte("end_header\n"))
+		if !ok {
+			t.Fatal("PLY header not terminated")
+		}
+		if !bytes.Contains(header, []byte("element vertex 3\n")) {
+			t.Errorf("bad vertex count in header:\n%s", header)
+		}
+		if len(body) != test.vertexSize*len(points) {
+			t.Errorf("want %d body bytes, got %d", test.vertexSize*len(points), len(body))
+		}
+	}
+	_, err := WritePLY(io.Discard, points, normals[:1], nil)
+	if err == nil {
+		t.Error("expected error for mismatched normals length")
+	}
+}
+
+func TestWriteXYZ(t *testing.T) {
+	points := []ms3.Vec{{X: 1}, {Y: 1.5}, {Z: -1}}
+	normals := []ms3.Vec{{X: 1}, {Y: 1}, {Z: -1}}
+	var buf bytes.Buffer
+	n, err := WriteXYZ(&buf, points, normals)
+	if err != nil {
+		t.Fatal(err)
+	} else if n != buf.Len() {
+		t.Errorf("wrote %d bytes, reported %d", buf.Len(), n)
+	}
+	const want = "1 0 0 1 0 0\n0 1.5 0 0 1 0\n0 0 -1 0 0 -1\n"
+	if buf.String() != want {
+		t.Errorf("want XYZ output\n%s\ngot\n%s", want, buf.String())
+	}
+	_, err = WriteXYZ(io.Discard, points, normals[:1])
+	if err == nil {
+		t.Error("expected error for mismatched normals length")
+	}
+}
+
+func TestVolume(t *testing.T) {
+	const r, spacing = 1, 0.05
+	sdf, err := gleval.NewCPUSDF3(bld.NewSphere(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vol, err := SampleVolume(sdf, spacing, 1024, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, idx := range [][3]int{{0, 0, 0}, {vol.Nx / 2, vol.Ny / 2, vol.Nz / 2}, {vol.Nx - 1, 3, vol.Nz - 2}} {
+		i, j, k := idx[0], idx[1], idx[2]
+		want := ms3.Norm(vol.Pos(i, j, k)) - r
+		if got := vol.At(i, j, k); math.Abs(float64(got-want)) > 1e-5 {
+			t.Errorf("sample %v: want %f, got %f", idx, want, got)
+		}
+	}
+	if vol.Origin.X > -r || vol.Pos(vol.Nx-1, 0, 0).X < r {
+		t.Errorf("volume does not enclose sphere bounds: origin %v, size %d", vol.Origin, vol.Nx)
+	}
+
+	var header, data, nrrd bytes.Buffer
+	err = WriteRawVolume(&header, &data, vol)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadRawVolume(&header, &data)
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(got, vol) {
+		t.Error("raw volume round trip mismatch")
+	}
+	n, err := WriteNRRD(&nrrd, vol)
+	if err != nil {
+		t.Fatal(err)
+	} else if n != nrrd.Len() {
+		t.Errorf("wrote %d bytes, reported %d", nrrd.Len(), n)
+	}
+	got, err = ReadNRRD(&nrrd)
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(got, vol) {
+		t.Error("NRRD volume round trip mismatch")
+	}
+
+	const band = 2 * spacing
+	sparse, err := SampleSparseVolume(sdf, spacing, band, 4, 1024, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if active := sparse.ActiveBricks(); active == 0 || active == len(sparse.Bricks) {
+		t.Errorf("want narrow band to store some bricks, got %d/%d", active, len(sparse.Bricks))
+	}
+	if sparse.Origin != vol.Origin || sparse.Bx*sparse.BrickSize < vol.Nx {
+		t.Fatalf("sparse grid does not match dense grid")
+	}
+	for k := 0; k < vol.Nz; k++ {
+		for j := 0; j < vol.Ny; j++ {
+			for i := 0; i < vol.Nx; i++ {
+				want := vol.At(i, j, k)
+				got := sparse.At(i, j, k)
+				if math.Abs(float64(want)) > band {
+					want = float32(math.Copysign(band, float64(want)))
+					got = float32(math.Copysign(band, float64(got)))
+				}
+				if got != want {
+					t.Fatalf("sparse sample (%d,%d,%d): want %f, got %f", i, j, k, want, got)
+				}
+			}
+		}
+	}
+	var sparseBuf bytes.Buffer
+	n, err = WriteSparseVolume(&sparseBuf, sparse)
+	if err != nil {
+		t.Fatal(err)
+	} else if n != sparseBuf.Len() {
+		t.Errorf("wrote %d bytes, reported %d", sparseBuf.Len(), n)
+	} else if n >= 4*len(vol.Data) {
+		t.Errorf("sparse volume file of %d bytes not smaller than dense data", n)
+	}
+	gotSparse, err := ReadSparseVolume(&sparseBuf)
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(gotSparse, sparse) {
+		t.Error("sparse volume round trip mismatch")
+	}
+}
+
+func signedArea(poly []ms2.Vec) (area float32) {
+	for i := range poly {
+		area += ms2.Cross(poly[i], poly[(i+1)%len(poly)])
+	}
+	return area / 2
+}
+
 func makeBolt(t *testing.T) glbuild.Shader3D {
 	const L, shank = 8, 3
 	threader := threads.ISO{D: 3, P: 0.5, Ext: true}
@@ -3,6 +3,7 @@ package glrender
 import (
 	"errors"
 	"io"
+	"slices"
 
 	"github.com/chewxy/math32"
 	"github.com/soypat/geometry/i3"
@@ -13,9 +14,14 @@ import (
 const minPrunableLvl = 3
 
 // Octree is a marching-triangles Octree implementation with sub-cube pruning.
+// If the SDF implements [gleval.SDF3Interval] cubes are pruned using the guaranteed
+// distance range over the cube, which is safe for SDFs which are not exact. Otherwise
+// cubes are pruned using the distance at their center which assumes the SDF is exact.
 type Octree struct {
 	s   gleval.SDF3
 	oct ms3.Octree
+	// ival is set if s supports interval evaluation.
+	ival gleval.SDF3Interval
 
 	bounds ms3.Box
 	// cubes stores cubes decomposed in a depth first search(DFS). It's length is chosen such that
@@ -37,6 +43,10 @@ type Octree struct {
 	posbuf []ms3.Vec
 	// distbuf is set to the calculated distances for posbuf. length==capacity always.
 	distbuf []float32
+	// boxbuf and hibuf store cube boxes and upper distance bounds during interval pruning.
+	// Lower distance bounds are stored in distbuf.
+	boxbuf []ms3.Box
+	hibuf  []float32
 	// pruned statistics: quantity of minimum resolution cubes pruned from octree and their calculations omitted (x8).
 	pruned uint64
 }
@@ -114,6 +124,7 @@ func (oc *Octree) Reset(s gleval.SDF3, cubeResolution float32) error {
 
 	*oc = Octree{
 		s:          s,
+		ival:       intervalSDF(s, bb),
 		oct:        ms3.Octree{Resolution: cubeResolution, Origin: origin},
 		bounds:     bb,
 		cubes:      oc.cubes[:1],
@@ -122,6 +133,8 @@ func (oc *Octree) Reset(s gleval.SDF3, cubeResolution float32) error {
 		// Reuse distbuf and posbuf.
 		distbuf: oc.distbuf,
 		posbuf:  oc.posbuf[:0],
+		boxbuf:  oc.boxbuf,
+		hibuf:   oc.hibuf,
 	}
 
 	oc.cubes[0] = topCube // Start cube.
@@ -184,6 +197,15 @@ func (oc *Octree) prune(userData any) (err error) {
 	if len(pos) < len(oc.prunecubes) {
 		return nil
 	}
+	if oc.ival != nil {
+		n := len(oc.prunecubes)
+		oc.boxbuf = slices.Grow(oc.boxbuf[:0], n)[:n]
+		oc.hibuf = slices.Grow(oc.hibuf[:0], n)[:n]
+		unpruned, smallestPruned, err := octreePruneInterval(oc.ival, oc.prunecubes, oc.oct.Origin, oc.oct.Resolution, oc.boxbuf, oc.distbuf[:n], oc.hibuf, userData)
+		oc.prunecubes = unpruned
+		oc.pruned += smallestPruned
+		return err
+	}
 	unpruned, smallestPruned, err := octreePrunea(oc.s, oc.prunecubes, oc.oct.Origin, oc.oct.Resolution, pos, oc.distbuf[:len(pos)], userData, szDistMult, false)
 	oc.prunecubes = unpruned
 	oc.pruned += smallestPruned
@@ -217,6 +239,24 @@ func (oc *Octree) done() bool {
 	return len(oc.cubes) == 0 && len(oc.posbuf) == 0 && len(oc.prunecubes) == 0
 }
 
+// intervalSDF returns s as a [gleval.SDF3Interval] if it implements interval evaluation
+// over its bounds bb. Returns nil otherwise.
+func intervalSDF(s gleval.SDF3, bb ms3.Box) gleval.SDF3Interval {
+	ival, ok := s.(gleval.SDF3Interval)
+	if !ok {
+		return nil
+	}
+	// SDFs which wrap others may implement the interface and still fail if the wrapped SDF
+	// does not support interval evaluation. Do a test evaluation to catch this early.
+	var vp gleval.VecPool
+	var lo, hi [1]float32
+	err := ival.EvaluateInterval([]ms3.Box{bb}, lo[:], hi[:], &vp)
+	if err != nil {
+		return nil
+	}
+	return ival
+}
+
 // This file contains basic low level algorithms regarding Octrees.
 
 func makeICube(bb ms3.Box, minResolution float32) (topCube i3.Cube, origin ms3.Vec, err error) {
@@ -282,3 +322,42 @@ func octreePrunea(s gleval.SDF3, toPrune []i3.Cube, origin ms3.Vec, res float32,
 	toPrune = toPrune[:runningIdx]
 	return toPrune, smallestPruned, nil
 }
+
+// octreePruneInterval discards cubes in toPrune over which the distance range of s does not contain zero, and thus contain no surface.
+// It returns the modified prune buffer containing unpruned cubes and the calculated number of smallest-level cubes pruned in the process.
+func octreePruneInterval(s gleval.SDF3Interval, toPrune []i3.Cube, origin ms3.Vec, res float32, boxBuf []ms3.Box, lobuf, hibuf []float32, userData any) (unpruned []i3.Cube, smallestPruned uint64, err error) {
+	if len(toPrune) == 0 {
+		return toPrune, 0, nil
+	} else if len(boxBuf) < len(toPrune) || len(lobuf) < len(toPrune) || len(hibuf) < len(toPrune) {
+		return toPrune, 0, errors.New("interval buffers length must be greater than prune cubes length")
+	}
+	oct := ms3.Octree{
+		Resolution: res,
+		Origin:     origin,
+	}
+	boxBuf = boxBuf[:len(toPrune)]
+	lobuf = lobuf[:len(toPrune)]
+	hibuf = hibuf[:len(toPrune)]
+	for i, p := range toPrune {
+		size := oct.CubeSize(p)
+		cubeOrigin := oct.CubeOrigin(p, size)
+		boxBuf[i] = ms3.Box{Min: cubeOrigin, Max: ms3.AddScalar(size, cubeOrigin)}
+	}
+	err = s.EvaluateInterval(boxBuf, lobuf, hibuf, userData)
+	if err != nil {
+		return toPrune, 0, err
+	}
+	// Move cubes which may contain surface to front and prune the rest.
+	runningIdx := 0
+	for i, p := range toPrune {
+		isPrunable := lobuf[i] > 0 || hibuf[i] < 0
+		if !isPrunable {
+			toPrune[runningIdx] = p
+			runningIdx++
+		} else {
+			smallestPruned += p.DecomposesTo(1)
+		}
+	}
+	toPrune = toPrune[:runningIdx]
+	return toPrune, smallestPruned, nil
+}
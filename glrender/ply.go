@@ -0,0 +1,68 @@
+package glrender
+
+import (
+	"encoding/binary"
+	"errors"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/soypat/geometry/ms3"
+)
+
+// WritePLY writes a point cloud to a writer in binary little endian PLY file format.
+// normals and colors are optional and are omitted from the file if nil.
+// If not nil they must be of same length as points.
+func WritePLY(w io.Writer, points, normals []ms3.Vec, colors []color.Color) (int, error) {
+	if len(points) == 0 {
+		return 0, errors.New("empty point slice")
+	} else if normals != nil && len(normals) != len(points) {
+		return 0, errors.New("length of normals must match length of points")
+	} else if colors != nil && len(colors) != len(points) {
+		return 0, errors.New("length of colors must match length of points")
+	}
+	b := make([]byte, 0, 512)
+	b = append(b, "ply\nformat binary_little_endian 1.0\nelement vertex "...)
+	b = strconv.AppendInt(b, int64(len(points)), 10)
+	b = append(b, "\nproperty float x\nproperty float y\nproperty float z\n"...)
+	if normals != nil {
+		b = append(b, "property float nx\nproperty float ny\nproperty float nz\n"...)
+	}
+	if colors != nil {
+		b = append(b, "property uchar red\nproperty uchar green\nproperty uchar blue\n"...)
+	}
+	b = append(b, "end_header\n"...)
+	n, err := w.Write(b)
+	if err != nil {
+		return n, err
+	}
+	const bufPoints = 1024
+	for i0 := 0; i0 < len(points); i0 += bufPoints {
+		b = b[:0]
+		for i := i0; i < min(i0+bufPoints, len(points)); i++ {
+			b = appendVecLE(b, points[i])
+			if normals != nil {
+				b = appendVecLE(b, normals[i])
+			}
+			if colors != nil {
+				c := color.RGBAModel.Convert(colors[i]).(color.RGBA)
+				b = append(b, c.R, c.G, c.B)
+			}
+		}
+		ngot, err := w.Write(b)
+		n += ngot
+		if err != nil {
+			return n, err
+		} else if ngot != len(b) {
+			return n, io.ErrShortWrite
+		}
+	}
+	return n, nil
+}
+
+func appendVecLE(b []byte, v ms3.Vec) []byte {
+	b = binary.LittleEndian.AppendUint32(b, math.Float32bits(v.X))
+	b = binary.LittleEndian.AppendUint32(b, math.Float32bits(v.Y))
+	return binary.LittleEndian.AppendUint32(b, math.Float32bits(v.Z))
+}
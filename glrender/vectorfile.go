@@ -0,0 +1,580 @@
+package glrender
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/soypat/geometry/ms2"
+)
+
+// Unit is a length unit for vector file formats. Contour coordinates are written unchanged by all writers.
+// [WriteSVG] sets the document width and height in the unit, using centimeters for [UnitMeter] since SVG has
+// no meter unit. [WriteDXF] records the unit in the $INSUNITS header variable.
+type Unit uint8
+
+const (
+	UnitNone Unit = iota
+	UnitMillimeter
+	UnitCentimeter
+	UnitMeter
+	UnitInch
+)
+
+// svg returns the SVG length unit suffix and the factor to convert a length in u to the suffix unit.
+func (u Unit) svg() (suffix string, factor float32) {
+	switch u {
+	case UnitMillimeter:
+		return "mm", 1
+	case UnitCentimeter:
+		return "cm", 1
+	case UnitMeter:
+		return "cm", 100 // SVG has no meter unit.
+	case UnitInch:
+		return "in", 1
+	}
+	return "", 1
+}
+
+// dxf returns the DXF $INSUNITS header value.
+func (u Unit) dxf() int {
+	switch u {
+	case UnitMillimeter:
+		return 4
+	case UnitCentimeter:
+		return 5
+	case UnitMeter:
+		return 6
+	case UnitInch:
+		return 1
+	}
+	return 0
+}
+
+// WriteSVG writes closed polylines as a single filled SVG path. Polylines are expected to be oriented
+// as returned by [ContoursSDF2] so that holes are correctly rendered with the nonzero fill rule.
+// The Y axis is flipped so that the SVG looks the same as the SDF in a Y-up coordinate system.
+func WriteSVG(w io.Writer, contours [][]ms2.Vec, units Unit) (int, error) {
+	bb, err := contoursBounds(contours)
+	if err != nil {
+		return 0, err
+	}
+	sz := bb.Size()
+	suffix, factor := units.svg()
+	b := make([]byte, 0, 1024)
+	b = append(b, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<svg xmlns=\"http://www.w3.org/2000/svg\" width=\""...)
+	b = appendFloat(b, sz.X*factor)
+	b = append(b, suffix...)
+	b = append(b, "\" height=\""...)
+	b = appendFloat(b, sz.Y*factor)
+	b = append(b, suffix...)
+	b = append(b, "\" viewBox=\""...)
+	b = appendFloat(b, bb.Min.X)
+	b = append(b, ' ')
+	b = appendFloat(b, -bb.Max.Y)
+	b = append(b, ' ')
+	b = appendFloat(b, sz.X)
+	b = append(b, ' ')
+	b = appendFloat(b, sz.Y)
+	b = append(b, "\">\n<path fill=\"black\" fill-rule=\"nonzero\" d=\""...)
+	for i, contour := range contours {
+		if i > 0 {
+			b = append(b, ' ')
+		}
+		for j, v := range contour {
+			if j == 0 {
+				b = append(b, 'M')
+			} else {
+				b = append(b, ' ')
+			}
+			b = appendFloat(b, v.X)
+			b = append(b, ',')
+			b = appendFloat(b, -v.Y)
+		}
+		b = append(b, 'Z')
+	}
+	b = append(b, "\"/>\n</svg>\n"...)
+	return w.Write(b)
+}
+
+// WriteDXF writes closed polylines as DXF LWPOLYLINE entities on layer "0" of an AutoCAD 2000 (AC1015) file.
+// Polyline orientation is preserved so outer contours remain counter-clockwise and holes clockwise.
+func WriteDXF(w io.Writer, contours [][]ms2.Vec, units Unit) (int, error) {
+	_, err := contoursBounds(contours)
+	if err != nil {
+		return 0, err
+	}
+	b := make([]byte, 0, 4096)
+	b = append(b, "0\nSECTION\n2\nHEADER\n9\n$ACADVER\n1\nAC1015\n9\n$HANDSEED\n5\n"...)
+	b = appendHandle(b, dxfFirstEntityHandle+len(contours))
+	b = append(b, "\n9\n$INSUNITS\n70\n"...)
+	b = strconv.AppendInt(b, int64(units.dxf()), 10)
+	b = append(b, "\n0\nENDSEC\n"...)
+	b = append(b, dxfTablesAndBlocks...)
+	b = append(b, "0\nSECTION\n2\nENTITIES\n"...)
+	for i, contour := range contours {
+		b = append(b, "0\nLWPOLYLINE\n5\n"...)
+		b = appendHandle(b, dxfFirstEntityHandle+i)
+		b = append(b, "\n330\n1F\n100\nAcDbEntity\n8\n0\n100\nAcDbPolyline\n90\n"...)
+		b = strconv.AppendInt(b, int64(len(contour)), 10)
+		b = append(b, "\n70\n1\n"...) // Closed polyline flag.
+		for _, v := range contour {
+			b = append(b, "10\n"...)
+			b = appendFloat(b, v.X)
+			b = append(b, "\n20\n"...)
+			b = appendFloat(b, v.Y)
+			b = append(b, '\n')
+		}
+	}
+	b = append(b, "0\nENDSEC\n"...)
+	b = append(b, dxfObjects...)
+	b = append(b, "0\nEOF\n"...)
+	return w.Write(b)
+}
+
+// dxfFirstEntityHandle is the handle of the first entity written by [WriteDXF]. Lower handles are
+// used by the table, block and object skeleton of the file.
+const dxfFirstEntityHandle = 0x100
+
+func appendHandle(b []byte, h int) []byte {
+	return fmt.Appendf(b, "%X", h)
+}
+
+// dxfTablesAndBlocks holds the CLASSES, TABLES and BLOCKS sections required by AC1015 files.
+// Entities are owned by the *Model_Space block record with handle 1F.
+const dxfTablesAndBlocks = `0
+SECTION
+2
+CLASSES
+0
+ENDSEC
+0
+SECTION
+2
+TABLES
+0
+TABLE
+2
+VPORT
+5
+8
+330
+0
+100
+AcDbSymbolTable
+70
+0
+0
+ENDTAB
+0
+TABLE
+2
+LTYPE
+5
+5
+330
+0
+100
+AcDbSymbolTable
+70
+3
+0
+LTYPE
+5
+14
+330
+5
+100
+AcDbSymbolTableRecord
+100
+AcDbLinetypeTableRecord
+2
+ByBlock
+70
+0
+3
+
+72
+65
+73
+0
+40
+0.0
+0
+LTYPE
+5
+15
+330
+5
+100
+AcDbSymbolTableRecord
+100
+AcDbLinetypeTableRecord
+2
+ByLayer
+70
+0
+3
+
+72
+65
+73
+0
+40
+0.0
+0
+LTYPE
+5
+16
+330
+5
+100
+AcDbSymbolTableRecord
+100
+AcDbLinetypeTableRecord
+2
+Continuous
+70
+0
+3
+Solid line
+72
+65
+73
+0
+40
+0.0
+0
+ENDTAB
+0
+TABLE
+2
+LAYER
+5
+2
+330
+0
+100
+AcDbSymbolTable
+70
+1
+0
+LAYER
+5
+10
+330
+2
+100
+AcDbSymbolTableRecord
+100
+AcDbLayerTableRecord
+2
+0
+70
+0
+62
+7
+6
+Continuous
+0
+ENDTAB
+0
+TABLE
+2
+STYLE
+5
+3
+330
+0
+100
+AcDbSymbolTable
+70
+1
+0
+STYLE
+5
+11
+330
+3
+100
+AcDbSymbolTableRecord
+100
+AcDbTextStyleTableRecord
+2
+Standard
+70
+0
+40
+0.0
+41
+1.0
+50
+0.0
+71
+0
+42
+2.5
+3
+txt
+4
+
+0
+ENDTAB
+0
+TABLE
+2
+VIEW
+5
+6
+330
+0
+100
+AcDbSymbolTable
+70
+0
+0
+ENDTAB
+0
+TABLE
+2
+UCS
+5
+7
+330
+0
+100
+AcDbSymbolTable
+70
+0
+0
+ENDTAB
+0
+TABLE
+2
+APPID
+5
+9
+330
+0
+100
+AcDbSymbolTable
+70
+1
+0
+APPID
+5
+12
+330
+9
+100
+AcDbSymbolTableRecord
+100
+AcDbRegAppTableRecord
+2
+ACAD
+70
+0
+0
+ENDTAB
+0
+TABLE
+2
+DIMSTYLE
+5
+A
+330
+0
+100
+AcDbSymbolTable
+70
+0
+100
+AcDbDimStyleTable
+71
+0
+0
+ENDTAB
+0
+TABLE
+2
+BLOCK_RECORD
+5
+1
+330
+0
+100
+AcDbSymbolTable
+70
+2
+0
+BLOCK_RECORD
+5
+1F
+330
+1
+100
+AcDbSymbolTableRecord
+100
+AcDbBlockTableRecord
+2
+*Model_Space
+0
+BLOCK_RECORD
+5
+1B
+330
+1
+100
+AcDbSymbolTableRecord
+100
+AcDbBlockTableRecord
+2
+*Paper_Space
+0
+ENDTAB
+0
+ENDSEC
+0
+SECTION
+2
+BLOCKS
+0
+BLOCK
+5
+20
+330
+1F
+100
+AcDbEntity
+8
+0
+100
+AcDbBlockBegin
+2
+*Model_Space
+70
+0
+10
+0.0
+20
+0.0
+30
+0.0
+3
+*Model_Space
+1
+
+0
+ENDBLK
+5
+21
+330
+1F
+100
+AcDbEntity
+8
+0
+100
+AcDbBlockEnd
+0
+BLOCK
+5
+1C
+330
+1B
+100
+AcDbEntity
+67
+1
+8
+0
+100
+AcDbBlockBegin
+2
+*Paper_Space
+70
+0
+10
+0.0
+20
+0.0
+30
+0.0
+3
+*Paper_Space
+1
+
+0
+ENDBLK
+5
+1D
+330
+1B
+100
+AcDbEntity
+67
+1
+8
+0
+100
+AcDbBlockEnd
+0
+ENDSEC
+`
+
+// dxfObjects holds the OBJECTS section with the root dictionary required by AC1015 files.
+const dxfObjects = `0
+SECTION
+2
+OBJECTS
+0
+DICTIONARY
+5
+C
+330
+0
+100
+AcDbDictionary
+281
+1
+3
+ACAD_GROUP
+350
+D
+0
+DICTIONARY
+5
+D
+330
+C
+100
+AcDbDictionary
+281
+1
+0
+ENDSEC
+`
+
+func contoursBounds(contours [][]ms2.Vec) (ms2.Box, error) {
+	if len(contours) == 0 {
+		return ms2.Box{}, errors.New("no contours")
+	}
+	var bb ms2.Box
+	for i, contour := range contours {
+		if len(contour) < 3 {
+			return ms2.Box{}, errors.New("contour with less than 3 vertices")
+		}
+		if i == 0 {
+			bb = ms2.Box{Min: contour[0], Max: contour[0]}
+		}
+		for _, v := range contour {
+			bb = bb.IncludePoint(v)
+		}
+	}
+	return bb, nil
+}
+
+func appendFloat(b []byte, v float32) []byte {
+	return strconv.AppendFloat(b, float64(v), 'g', -1, 32)
+}
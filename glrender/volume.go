@@ -0,0 +1,234 @@
+package glrender
+
+import (
+	"errors"
+
+	"github.com/chewxy/math32"
+	"github.com/soypat/geometry/ms3"
+	"github.com/soypat/gsdf/gleval"
+)
+
+// Volume is a signed distance field sampled on a dense regular grid.
+type Volume struct {
+	// Origin is the position of the sample at index (0,0,0).
+	Origin ms3.Vec
+	// Spacing is the distance between neighboring samples along each axis.
+	Spacing float32
+	// Nx, Ny, Nz are the number of samples along each axis.
+	Nx, Ny, Nz int
+	// Data contains Nx*Ny*Nz distances ordered with x varying fastest and z slowest.
+	Data []float32
+}
+
+// At returns the distance sampled at index (i,j,k).
+func (v *Volume) At(i, j, k int) float32 {
+	return v.Data[i+v.Nx*(j+v.Ny*k)]
+}
+
+// Pos returns the position of the sample at index (i,j,k).
+func (v *Volume) Pos(i, j, k int) ms3.Vec {
+	return volumePos(v.Origin, v.Spacing, i, j, k)
+}
+
+// SparseVolume is a signed distance field sampled on a regular grid partitioned into cubic bricks of
+// samples where only bricks near the surface store samples. This narrow band representation is similar
+// to the leaf nodes and tiles of OpenVDB.
+type SparseVolume struct {
+	// Origin is the position of the sample at index (0,0,0).
+	Origin ms3.Vec
+	// Spacing is the distance between neighboring samples along each axis.
+	Spacing float32
+	// BrickSize is the number of samples along each side of a brick.
+	BrickSize int
+	// Bx, By, Bz are the number of bricks along each axis. The grid has Bx*BrickSize samples along x.
+	Bx, By, Bz int
+	// Band is the narrow band half width. Bricks with all samples farther than Band from the surface
+	// do not store samples.
+	Band float32
+	// Bricks contains Bx*By*Bz bricks ordered with x varying fastest and z slowest.
+	Bricks []Brick
+}
+
+// Brick is a cube of samples of a [SparseVolume].
+type Brick struct {
+	// Data contains BrickSize³ distances ordered with x varying fastest or is nil for bricks outside the narrow band.
+	Data []float32
+	// Value is the distance of all samples of bricks outside the narrow band, which is
+	// Band outside the surface or -Band inside the surface.
+	Value float32
+}
+
+// At returns the distance sampled at index (i,j,k), which is clamped to ±Band outside the narrow band.
+func (sv *SparseVolume) At(i, j, k int) float32 {
+	bs := sv.BrickSize
+	b := &sv.Bricks[i/bs+sv.Bx*(j/bs+sv.By*(k/bs))]
+	if b.Data == nil {
+		return b.Value
+	}
+	return b.Data[i%bs+bs*(j%bs+bs*(k%bs))]
+}
+
+// Pos returns the position of the sample at index (i,j,k).
+func (sv *SparseVolume) Pos(i, j, k int) ms3.Vec {
+	return volumePos(sv.Origin, sv.Spacing, i, j, k)
+}
+
+// ActiveBricks returns the number of bricks which store samples.
+func (sv *SparseVolume) ActiveBricks() (n int) {
+	for i := range sv.Bricks {
+		if sv.Bricks[i].Data != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// SampleVolume samples sdf on a dense regular grid with the given spacing between samples. The grid covers
+// the SDF bounds padded by one spacing so surfaces lying on the bounds are enclosed by samples.
+func SampleVolume(sdf gleval.SDF3, spacing float32, evalBufferSize int, userData any) (Volume, error) {
+	origin, nx, ny, nz, err := volumeGrid(sdf, spacing, evalBufferSize, 1)
+	if err != nil {
+		return Volume{}, err
+	}
+	v := Volume{Origin: origin, Spacing: spacing, Nx: nx, Ny: ny, Nz: nz, Data: make([]float32, nx*ny*nz)}
+	pos := make([]ms3.Vec, evalBufferSize)
+	start, n := 0, 0
+	for k := 0; k < nz; k++ {
+		for j := 0; j < ny; j++ {
+			for i := 0; i < nx; i++ {
+				pos[n] = v.Pos(i, j, k)
+				n++
+				if n == len(pos) {
+					if err = sdf.Evaluate(pos, v.Data[start:start+n], userData); err != nil {
+						return Volume{}, err
+					}
+					start += n
+					n = 0
+				}
+			}
+		}
+	}
+	if n > 0 {
+		err = sdf.Evaluate(pos[:n], v.Data[start:start+n], userData)
+	}
+	return v, err
+}
+
+// SampleSparseVolume samples sdf on a regular grid with the given spacing between samples, storing only
+// bricks of brickSize³ samples which contain samples within band of the surface. Bricks are first classified
+// by evaluating their centers, which requires sdf to not overestimate distances, and only bricks near
+// the surface are then sampled. The grid covers the SDF bounds padded by one spacing.
+func SampleSparseVolume(sdf gleval.SDF3, spacing, band float32, brickSize, evalBufferSize int, userData any) (SparseVolume, error) {
+	if band <= 0 || math32.IsNaN(band) {
+		return SparseVolume{}, errors.New("invalid narrow band width")
+	} else if brickSize < 2 {
+		return SparseVolume{}, errors.New("brick size must be at least 2")
+	}
+	origin, nx, ny, nz, err := volumeGrid(sdf, spacing, evalBufferSize, brickSize)
+	if err != nil {
+		return SparseVolume{}, err
+	}
+	sv := SparseVolume{
+		Origin:    origin,
+		Spacing:   spacing,
+		BrickSize: brickSize,
+		Bx:        nx / brickSize,
+		By:        ny / brickSize,
+		Bz:        nz / brickSize,
+		Band:      band,
+	}
+	sv.Bricks = make([]Brick, sv.Bx*sv.By*sv.Bz)
+	pos := make([]ms3.Vec, evalBufferSize)
+	dist := make([]float32, evalBufferSize)
+	// Classify bricks by distance at their centers.
+	halfSide := float32(brickSize-1) / 2
+	radius := halfSide * spacing * math32.Sqrt(3)
+	var active []int
+	for i0 := 0; i0 < len(sv.Bricks); i0 += len(pos) {
+		i1 := min(i0+len(pos), len(sv.Bricks))
+		for b := i0; b < i1; b++ {
+			bi, bj, bk := b%sv.Bx, (b/sv.Bx)%sv.By, b/(sv.Bx*sv.By)
+			corner := sv.Pos(bi*brickSize, bj*brickSize, bk*brickSize)
+			pos[b-i0] = ms3.AddScalar(halfSide*spacing, corner)
+		}
+		if err = sdf.Evaluate(pos[:i1-i0], dist[:i1-i0], userData); err != nil {
+			return SparseVolume{}, err
+		}
+		for b := i0; b < i1; b++ {
+			d := dist[b-i0]
+			sv.Bricks[b].Value = math32.Copysign(band, d)
+			if math32.Abs(d) <= band+radius {
+				active = append(active, b)
+			}
+		}
+	}
+	// Sample bricks near the surface.
+	samples := brickSize * brickSize * brickSize
+	data := make([]float32, samples)
+	for _, b := range active {
+		bi, bj, bk := b%sv.Bx, (b/sv.Bx)%sv.By, b/(sv.Bx*sv.By)
+		n, start := 0, 0
+		for k := 0; k < brickSize; k++ {
+			for j := 0; j < brickSize; j++ {
+				for i := 0; i < brickSize; i++ {
+					pos[n] = sv.Pos(bi*brickSize+i, bj*brickSize+j, bk*brickSize+k)
+					n++
+					if n == len(pos) {
+						if err = sdf.Evaluate(pos, data[start:start+n], userData); err != nil {
+							return SparseVolume{}, err
+						}
+						start += n
+						n = 0
+					}
+				}
+			}
+		}
+		if n > 0 {
+			if err = sdf.Evaluate(pos[:n], data[start:start+n], userData); err != nil {
+				return SparseVolume{}, err
+			}
+		}
+		inBand := false
+		for _, d := range data {
+			if math32.Abs(d) <= band || (d < 0) != (data[0] < 0) {
+				inBand = true
+				break
+			}
+		}
+		if inBand {
+			sv.Bricks[b] = Brick{Data: append([]float32(nil), data...)}
+		} else {
+			sv.Bricks[b].Value = math32.Copysign(band, data[0])
+		}
+	}
+	return sv, nil
+}
+
+// volumeGrid returns the origin and number of samples along each axis of a grid covering the padded
+// bounds of sdf, with the number of samples rounded up to a multiple of align.
+func volumeGrid(sdf gleval.SDF3, spacing float32, evalBufferSize, align int) (origin ms3.Vec, nx, ny, nz int, err error) {
+	if spacing <= 0 || math32.IsNaN(spacing) || math32.IsInf(spacing, 0) {
+		return origin, 0, 0, 0, errors.New("invalid volume sample spacing")
+	} else if evalBufferSize < 8 {
+		return origin, 0, 0, 0, errors.New("volume eval buffer size must be at least 8")
+	}
+	bb := sdf.Bounds()
+	sz := bb.Size()
+	count := func(length float32) int {
+		n := int(math32.Ceil(length/spacing)) + 3
+		return (n + align - 1) / align * align
+	}
+	nx, ny, nz = count(sz.X), count(sz.Y), count(sz.Z)
+	if nx*ny*nz <= 0 || nx*ny*nz > 1<<31 {
+		return origin, 0, 0, 0, errors.New("invalid volume sample count, check spacing")
+	}
+	return ms3.AddScalar(-spacing, bb.Min), nx, ny, nz, nil
+}
+
+func volumePos(origin ms3.Vec, spacing float32, i, j, k int) ms3.Vec {
+	return ms3.Vec{
+		X: origin.X + float32(i)*spacing,
+		Y: origin.Y + float32(j)*spacing,
+		Z: origin.Z + float32(k)*spacing,
+	}
+}
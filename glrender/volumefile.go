@@ -0,0 +1,408 @@
+package glrender
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/soypat/geometry/ms3"
+)
+
+// rawVolumeHeader is the JSON header of a raw volume written by [WriteRawVolume].
+type rawVolumeHeader struct {
+	Dims    [3]int     `json:"dims"`
+	Origin  [3]float32 `json:"origin"`
+	Spacing float32    `json:"spacing"`
+	Type    string     `json:"type"`
+	Endian  string     `json:"endian"`
+	Order   string     `json:"order"`
+}
+
+// WriteRawVolume writes the samples of v to data as raw little endian float32 values and
+// a JSON header describing the grid dimensions, origin, spacing and sample layout to header.
+func WriteRawVolume(header, data io.Writer, v Volume) error {
+	if err := v.validate(); err != nil {
+		return err
+	}
+	enc := json.NewEncoder(header)
+	enc.SetIndent("", "\t")
+	err := enc.Encode(rawVolumeHeader{
+		Dims:    [3]int{v.Nx, v.Ny, v.Nz},
+		Origin:  v.Origin.Array(),
+		Spacing: v.Spacing,
+		Type:    "float32",
+		Endian:  "little",
+		Order:   "x-fastest",
+	})
+	if err != nil {
+		return err
+	}
+	_, err = writeFloatsLE(data, v.Data)
+	return err
+}
+
+// ReadRawVolume reads a volume written by [WriteRawVolume] from its JSON header and raw data.
+func ReadRawVolume(header, data io.Reader) (Volume, error) {
+	var h rawVolumeHeader
+	err := json.NewDecoder(header).Decode(&h)
+	if err != nil {
+		return Volume{}, fmt.Errorf("raw volume header: %w", err)
+	} else if h.Type != "float32" || h.Order != "x-fastest" {
+		return Volume{}, errors.New("unsupported raw volume sample type or order")
+	} else if h.Endian != "little" && h.Endian != "big" {
+		return Volume{}, errors.New("unsupported raw volume endianness")
+	}
+	v := Volume{Origin: ms3.Vec{X: h.Origin[0], Y: h.Origin[1], Z: h.Origin[2]}, Spacing: h.Spacing, Nx: h.Dims[0], Ny: h.Dims[1], Nz: h.Dims[2]}
+	if err = v.validateDims(); err != nil {
+		return Volume{}, err
+	}
+	v.Data, err = readFloats(data, v.Nx*v.Ny*v.Nz, h.Endian == "big")
+	return v, err
+}
+
+// WriteNRRD writes v to w as a NRRD file with raw little endian float32 samples.
+// The sample positions are described with the space directions and space origin fields.
+func WriteNRRD(w io.Writer, v Volume) (int, error) {
+	if err := v.validate(); err != nil {
+		return 0, err
+	}
+	sp := v.Spacing
+	b := make([]byte, 0, 256)
+	b = append(b, "NRRD0004\ntype: float\ndimension: 3\nsizes: "...)
+	b = strconv.AppendInt(b, int64(v.Nx), 10)
+	b = append(b, ' ')
+	b = strconv.AppendInt(b, int64(v.Ny), 10)
+	b = append(b, ' ')
+	b = strconv.AppendInt(b, int64(v.Nz), 10)
+	b = append(b, "\nspace dimension: 3\nspace directions: "...)
+	b = appendNRRDVec(b, ms3.Vec{X: sp})
+	b = append(b, ' ')
+	b = appendNRRDVec(b, ms3.Vec{Y: sp})
+	b = append(b, ' ')
+	b = appendNRRDVec(b, ms3.Vec{Z: sp})
+	b = append(b, "\nspace origin: "...)
+	b = appendNRRDVec(b, v.Origin)
+	b = append(b, "\nendian: little\nencoding: raw\n\n"...)
+	n, err := w.Write(b)
+	if err != nil {
+		return n, err
+	}
+	ngot, err := writeFloatsLE(w, v.Data)
+	return n + ngot, err
+}
+
+// ReadNRRD reads a volume from a NRRD file with raw float samples and isotropic axis aligned
+// sample spacing such as those written by [WriteNRRD].
+func ReadNRRD(r io.Reader) (Volume, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.ReadString('\n')
+	if err != nil {
+		return Volume{}, err
+	} else if !strings.HasPrefix(magic, "NRRD000") {
+		return Volume{}, errors.New("missing NRRD magic")
+	}
+	var v Volume
+	var bigEndian, gotSizes bool
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return Volume{}, fmt.Errorf("NRRD header: %w", err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break // End of header.
+		} else if line[0] == '#' {
+			continue
+		}
+		field, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			continue // Key value pairs use ":=".
+		}
+		value = strings.TrimSpace(value)
+		switch field {
+		case "type":
+			if value != "float" && value != "float32" {
+				return Volume{}, errors.New("unsupported NRRD type " + value)
+			}
+		case "dimension":
+			if value != "3" {
+				return Volume{}, errors.New("unsupported NRRD dimension " + value)
+			}
+		case "encoding":
+			if value != "raw" {
+				return Volume{}, errors.New("unsupported NRRD encoding " + value)
+			}
+		case "endian":
+			bigEndian = value == "big"
+		case "sizes":
+			_, err = fmt.Sscan(value, &v.Nx, &v.Ny, &v.Nz)
+			gotSizes = err == nil
+		case "spacings":
+			_, err = fmt.Sscan(value, &v.Spacing)
+		case "space directions":
+			var dirs [3]ms3.Vec
+			dirs, err = parseNRRDVecs3(value)
+			if err == nil {
+				v.Spacing = dirs[0].X
+				if dirs[0] != (ms3.Vec{X: v.Spacing}) || dirs[1] != (ms3.Vec{Y: v.Spacing}) || dirs[2] != (ms3.Vec{Z: v.Spacing}) {
+					err = errors.New("only isotropic axis aligned space directions supported")
+				}
+			}
+		case "space origin":
+			var vecs []ms3.Vec
+			vecs, err = parseNRRDVecs(value)
+			if err == nil && len(vecs) != 1 {
+				err = errors.New("expected single vector")
+			} else if err == nil {
+				v.Origin = vecs[0]
+			}
+		}
+		if err != nil {
+			return Volume{}, fmt.Errorf("NRRD field %q: %w", field, err)
+		}
+	}
+	if !gotSizes {
+		return Volume{}, errors.New("missing NRRD sizes")
+	}
+	if err = v.validateDims(); err != nil {
+		return Volume{}, err
+	}
+	v.Data, err = readFloats(br, v.Nx*v.Ny*v.Nz, bigEndian)
+	return v, err
+}
+
+// sparseVolumeMagic identifies files written by [WriteSparseVolume].
+const sparseVolumeMagic = "GSDFBRK1"
+
+// WriteSparseVolume writes sv to w in a simple little endian binary sparse brick format.
+// The file starts with the 8 byte magic "GSDFBRK1" followed by the origin, spacing and band as float32
+// and the brick size and brick counts along x, y and z as uint32. Then each brick follows in order
+// as a single byte which is 1 if the brick stores samples followed by BrickSize³ float32 samples,
+// or 0 followed by the float32 brick value.
+func WriteSparseVolume(w io.Writer, sv SparseVolume) (int, error) {
+	if err := sv.validate(); err != nil {
+		return 0, err
+	}
+	b := make([]byte, 0, 64)
+	b = append(b, sparseVolumeMagic...)
+	b = appendVecLE(b, sv.Origin)
+	b = binary.LittleEndian.AppendUint32(b, math.Float32bits(sv.Spacing))
+	b = binary.LittleEndian.AppendUint32(b, math.Float32bits(sv.Band))
+	for _, u := range [4]int{sv.BrickSize, sv.Bx, sv.By, sv.Bz} {
+		b = binary.LittleEndian.AppendUint32(b, uint32(u))
+	}
+	n, err := w.Write(b)
+	if err != nil {
+		return n, err
+	}
+	for i := range sv.Bricks {
+		brick := &sv.Bricks[i]
+		b = b[:0]
+		if brick.Data == nil {
+			b = append(b, 0)
+			b = binary.LittleEndian.AppendUint32(b, math.Float32bits(brick.Value))
+		} else {
+			b = append(b, 1)
+		}
+		ngot, err := w.Write(b)
+		n += ngot
+		if err != nil {
+			return n, err
+		}
+		if brick.Data != nil {
+			ngot, err = writeFloatsLE(w, brick.Data)
+			n += ngot
+			if err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// ReadSparseVolume reads a sparse volume written by [WriteSparseVolume].
+func ReadSparseVolume(r io.Reader) (SparseVolume, error) {
+	br := bufio.NewReader(r)
+	var hdr [len(sparseVolumeMagic) + 4*5 + 4*4]byte
+	_, err := io.ReadFull(br, hdr[:])
+	if err != nil {
+		return SparseVolume{}, fmt.Errorf("sparse volume header: %w", err)
+	} else if string(hdr[:len(sparseVolumeMagic)]) != sparseVolumeMagic {
+		return SparseVolume{}, errors.New("missing sparse volume magic")
+	}
+	h := hdr[len(sparseVolumeMagic):]
+	var f [5]float32
+	for i := range f {
+		f[i] = math.Float32frombits(binary.LittleEndian.Uint32(h[4*i:]))
+	}
+	var u [4]int
+	for i := range u {
+		u[i] = int(binary.LittleEndian.Uint32(h[4*len(f)+4*i:]))
+	}
+	sv := SparseVolume{
+		Origin:    ms3.Vec{X: f[0], Y: f[1], Z: f[2]},
+		Spacing:   f[3],
+		Band:      f[4],
+		BrickSize: u[0],
+		Bx:        u[1],
+		By:        u[2],
+		Bz:        u[3],
+	}
+	if err = sv.validateDims(); err != nil {
+		return SparseVolume{}, err
+	}
+	samples := sv.BrickSize * sv.BrickSize * sv.BrickSize
+	sv.Bricks = make([]Brick, sv.Bx*sv.By*sv.Bz)
+	for i := range sv.Bricks {
+		flag, err := br.ReadByte()
+		if err != nil {
+			return SparseVolume{}, fmt.Errorf("sparse volume brick %d: %w", i, err)
+		}
+		switch flag {
+		case 0:
+			var v []float32
+			v, err = readFloats(br, 1, false)
+			if err == nil {
+				sv.Bricks[i].Value = v[0]
+			}
+		case 1:
+			sv.Bricks[i].Data, err = readFloats(br, samples, false)
+		default:
+			err = errors.New("invalid brick flag")
+		}
+		if err != nil {
+			return SparseVolume{}, fmt.Errorf("sparse volume brick %d: %w", i, err)
+		}
+	}
+	return sv, nil
+}
+
+func (v *Volume) validate() error {
+	if err := v.validateDims(); err != nil {
+		return err
+	} else if len(v.Data) != v.Nx*v.Ny*v.Nz {
+		return errors.New("volume data length does not match dimensions")
+	}
+	return nil
+}
+
+func (v *Volume) validateDims() error {
+	if v.Nx <= 0 || v.Ny <= 0 || v.Nz <= 0 || v.Nx*v.Ny*v.Nz > 1<<31 {
+		return errors.New("invalid volume dimensions")
+	} else if !(v.Spacing > 0) {
+		return errors.New("invalid volume spacing")
+	}
+	return nil
+}
+
+func (sv *SparseVolume) validate() error {
+	if err := sv.validateDims(); err != nil {
+		return err
+	} else if len(sv.Bricks) != sv.Bx*sv.By*sv.Bz {
+		return errors.New("number of bricks does not match dimensions")
+	}
+	samples := sv.BrickSize * sv.BrickSize * sv.BrickSize
+	for i := range sv.Bricks {
+		if sv.Bricks[i].Data != nil && len(sv.Bricks[i].Data) != samples {
+			return errors.New("brick data length does not match brick size")
+		}
+	}
+	return nil
+}
+
+func (sv *SparseVolume) validateDims() error {
+	if sv.BrickSize < 1 || sv.Bx <= 0 || sv.By <= 0 || sv.Bz <= 0 || sv.Bx*sv.By*sv.Bz > 1<<31 {
+		return errors.New("invalid sparse volume dimensions")
+	} else if !(sv.Spacing > 0) {
+		return errors.New("invalid sparse volume spacing")
+	}
+	return nil
+}
+
+func writeFloatsLE(w io.Writer, data []float32) (n int, err error) {
+	const bufFloats = 4096
+	b := make([]byte, 0, 4*min(bufFloats, len(data)))
+	for i0 := 0; i0 < len(data); i0 += bufFloats {
+		b = b[:0]
+		for _, f := range data[i0:min(i0+bufFloats, len(data))] {
+			b = binary.LittleEndian.AppendUint32(b, math.Float32bits(f))
+		}
+		ngot, err := w.Write(b)
+		n += ngot
+		if err != nil {
+			return n, err
+		} else if ngot != len(b) {
+			return n, io.ErrShortWrite
+		}
+	}
+	return n, nil
+}
+
+func readFloats(r io.Reader, n int, bigEndian bool) ([]float32, error) {
+	var order binary.ByteOrder = binary.LittleEndian
+	if bigEndian {
+		order = binary.BigEndian
+	}
+	const bufFloats = 4096
+	b := make([]byte, 4*min(bufFloats, n))
+	data := make([]float32, n)
+	for i0 := 0; i0 < n; i0 += bufFloats {
+		chunk := data[i0:min(i0+bufFloats, n)]
+		_, err := io.ReadFull(r, b[:4*len(chunk)])
+		if err != nil {
+			return nil, err
+		}
+		for i := range chunk {
+			chunk[i] = math.Float32frombits(order.Uint32(b[4*i:]))
+		}
+	}
+	return data, nil
+}
+
+func appendNRRDVec(b []byte, v ms3.Vec) []byte {
+	b = append(b, '(')
+	b = strconv.AppendFloat(b, float64(v.X), 'g', -1, 32)
+	b = append(b, ',')
+	b = strconv.AppendFloat(b, float64(v.Y), 'g', -1, 32)
+	b = append(b, ',')
+	b = strconv.AppendFloat(b, float64(v.Z), 'g', -1, 32)
+	return append(b, ')')
+}
+
+func parseNRRDVecs3(s string) (vecs [3]ms3.Vec, err error) {
+	v, err := parseNRRDVecs(s)
+	if err == nil && len(v) != 3 {
+		err = errors.New("expected 3 vectors")
+	}
+	copy(vecs[:], v)
+	return vecs, err
+}
+
+// parseNRRDVecs parses space separated vectors of the form (x,y,z).
+func parseNRRDVecs(s string) (vecs []ms3.Vec, err error) {
+	for _, field := range strings.Fields(s) {
+		if len(field) < 2 || field[0] != '(' || field[len(field)-1] != ')' {
+			return nil, errors.New("malformed vector " + field)
+		}
+		parts := strings.Split(field[1:len(field)-1], ",")
+		if len(parts) != 3 {
+			return nil, errors.New("expected 3 vector components in " + field)
+		}
+		var c [3]float32
+		for i, p := range parts {
+			f, err := strconv.ParseFloat(p, 32)
+			if err != nil {
+				return nil, err
+			}
+			c[i] = float32(f)
+		}
+		vecs = append(vecs, ms3.Vec{X: c[0], Y: c[1], Z: c[2]})
+	}
+	return vecs, nil
+}
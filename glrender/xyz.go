@@ -0,0 +1,49 @@
+package glrender
+
+import (
+	"errors"
+	"io"
+	"strconv"
+
+	"github.com/soypat/geometry/ms3"
+)
+
+// WriteXYZ writes a point cloud to a writer in ASCII XYZ format with one point per line.
+// normals is optional and is omitted from the file if nil. If not nil it must be of same length as points.
+func WriteXYZ(w io.Writer, points, normals []ms3.Vec) (int, error) {
+	if len(points) == 0 {
+		return 0, errors.New("empty point slice")
+	} else if normals != nil && len(normals) != len(points) {
+		return 0, errors.New("length of normals must match length of points")
+	}
+	const bufPoints = 1024
+	b := make([]byte, 0, 512)
+	n := 0
+	for i0 := 0; i0 < len(points); i0 += bufPoints {
+		b = b[:0]
+		for i := i0; i < min(i0+bufPoints, len(points)); i++ {
+			b = appendVecASCII(b, points[i])
+			if normals != nil {
+				b = append(b, ' ')
+				b = appendVecASCII(b, normals[i])
+			}
+			b = append(b, '\n')
+		}
+		ngot, err := w.Write(b)
+		n += ngot
+		if err != nil {
+			return n, err
+		} else if ngot != len(b) {
+			return n, io.ErrShortWrite
+		}
+	}
+	return n, nil
+}
+
+func appendVecASCII(b []byte, v ms3.Vec) []byte {
+	b = strconv.AppendFloat(b, float64(v.X), 'g', -1, 32)
+	b = append(b, ' ')
+	b = strconv.AppendFloat(b, float64(v.Y), 'g', -1, 32)
+	b = append(b, ' ')
+	return strconv.AppendFloat(b, float64(v.Z), 'g', -1, 32)
+}
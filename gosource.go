@@ -0,0 +1,319 @@
+package gsdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/soypat/geometry/ms2"
+	"github.com/soypat/geometry/ms3"
+	"github.com/soypat/gsdf/glbuild"
+)
+
+var (
+	vec2Type       = reflect.TypeOf(ms2.Vec{})
+	vec3Type       = reflect.TypeOf(ms3.Vec{})
+	box2Type       = reflect.TypeOf(ms2.Box{})
+	box3Type       = reflect.TypeOf(ms3.Box{})
+	fillRuleType   = reflect.TypeOf(FillRule(0))
+	gridInterpType = reflect.TypeOf(GridInterpolation(0))
+)
+
+// WriteGo writes a Go source file of package pkgName to w. The file declares a function funcName
+// which receives a *gsdf.Builder and rebuilds the shape tree rooted at s with Builder method calls.
+// All nodes in the tree must implement [glbuild.Describer] and be constructed by a [Builder] method,
+// which is the case for all shapes in this package. Shapes which appear more than once in the tree
+// are built once and reused. The generated source is formatted with go/format.
+func WriteGo(w io.Writer, s glbuild.Shader, pkgName, funcName string) error {
+	if !token.IsIdentifier(pkgName) || !token.IsIdentifier(funcName) {
+		return errors.New("invalid package or function name")
+	}
+	var retType string
+	switch s.(type) {
+	case glbuild.Shader3D:
+		retType = "glbuild.Shader3D"
+	case glbuild.Shader2D:
+		retType = "glbuild.Shader2D"
+	default:
+		return errors.New("shape is neither Shader3D nor Shader2D")
+	}
+	g := goWriter{vars: make(map[glbuild.Shader]string)}
+	result, err := g.node(s)
+	if err != nil {
+		return err
+	}
+	var src bytes.Buffer
+	src.WriteString("package " + pkgName + "\n\nimport (\n")
+	if g.imports["ms2"] {
+		src.WriteString("\t\"github.com/soypat/geometry/ms2\"\n")
+	}
+	if g.imports["ms3"] {
+		src.WriteString("\t\"github.com/soypat/geometry/ms3\"\n")
+	}
+	src.WriteString("\t\"github.com/soypat/gsdf\"\n\t\"github.com/soypat/gsdf/glbuild\"\n)\n\n")
+	fmt.Fprintf(&src, "func %s(bld *gsdf.Builder) %s {\n", funcName, retType)
+	src.Write(g.body)
+	src.WriteString("return " + result + "\n}\n")
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		return fmt.Errorf("formatting generated source: %w", err)
+	}
+	_, err = w.Write(formatted)
+	return err
+}
+
+// goWriter accumulates the statements which build a shape tree.
+type goWriter struct {
+	body    []byte
+	vars    map[glbuild.Shader]string
+	imports map[string]bool
+	nvars   int
+}
+
+// node appends the statements which build s and its children and returns the name of the variable holding s.
+func (g *goWriter) node(s glbuild.Shader) (string, error) {
+	if s == nil {
+		return "", errors.New("nil shape")
+	} else if name, ok := g.vars[s]; ok {
+		return name, nil
+	}
+	fn, params := glbuild.Describe(s)
+	if fn == "" {
+		return "", fmt.Errorf("shape %T does not describe itself", s)
+	} else if !token.IsExported(fn) || strings.IndexByte(fn, '.') >= 0 {
+		return "", fmt.Errorf("shape %s is not built by a Builder method", fn)
+	}
+	args := make([]string, len(params))
+	for i, p := range params {
+		arg, err := g.param(p.Value)
+		if err != nil {
+			return "", fmt.Errorf("%s: parameter %q: %w", fn, p.Name, err)
+		}
+		args[i] = arg
+	}
+	name := g.newVar(strings.TrimPrefix(fn, "New"))
+	g.body = fmt.Appendf(g.body, "%s := bld.%s(%s)\n", name, fn, strings.Join(args, ", "))
+	g.vars[s] = name
+	return name, nil
+}
+
+// param returns the Go expression of a parameter value, appending any statements needed to build it.
+func (g *goWriter) param(v any) (string, error) {
+	switch v := v.(type) {
+	case glbuild.Shader:
+		return g.node(v)
+	case []glbuild.Shader3D:
+		return g.nodes(v)
+	case []glbuild.Shader2D:
+		return g.nodes(v)
+	case *Path2D:
+		return g.path(v)
+	}
+	b, err := g.appendValue(nil, reflect.ValueOf(v), false)
+	return string(b), err
+}
+
+func (g *goWriter) nodes(shapes any) (string, error) {
+	rv := reflect.ValueOf(shapes)
+	names := make([]string, rv.Len())
+	for i := range names {
+		name, err := g.node(rv.Index(i).Interface().(glbuild.Shader))
+		if err != nil {
+			return "", err
+		}
+		names[i] = name
+	}
+	// Variadic arguments of Union and Union2D.
+	return strings.Join(names, ", "), nil
+}
+
+// path appends the statements which draw p and returns the expression of a pointer to it.
+func (g *goWriter) path(p *Path2D) (string, error) {
+	name := g.newVar("path")
+	g.body = fmt.Appendf(g.body, "var %s gsdf.Path2D\n", name)
+	var cur ms2.Vec
+	for i, seg := range p.segs {
+		if i == 0 || seg.p[0] != cur {
+			args, err := g.floats(seg.p[0].X, seg.p[0].Y)
+			if err != nil {
+				return "", err
+			}
+			g.body = fmt.Appendf(g.body, "%s.MoveTo(%s)\n", name, args)
+		}
+		var call, args string
+		var err error
+		switch seg.kind {
+		case pathLine:
+			call = "LineTo"
+			args, err = g.floats(seg.p[1].X, seg.p[1].Y)
+		case pathQuad:
+			call = "QuadTo"
+			args, err = g.floats(seg.p[1].X, seg.p[1].Y, seg.p[2].X, seg.p[2].Y)
+		case pathCubic:
+			call = "CubicTo"
+			args, err = g.floats(seg.p[1].X, seg.p[1].Y, seg.p[2].X, seg.p[2].Y, seg.p[3].X, seg.p[3].Y)
+		case pathArc:
+			call = "ArcTo"
+			largeArc := seg.da > math.Pi || seg.da < -math.Pi
+			clockwise := seg.da < 0
+			args, err = g.floats(seg.p[1].X, seg.p[1].Y, seg.r)
+			args += fmt.Sprintf(", %t, %t", largeArc, clockwise)
+		}
+		if err != nil {
+			return "", err
+		}
+		g.body = fmt.Appendf(g.body, "%s.%s(%s)\n", name, call, args)
+		cur = seg.end()
+	}
+	return "&" + name, nil
+}
+
+// floats returns the comma separated Go literals of v. Non-finite values have no literal and return an error.
+func (g *goWriter) floats(v ...float32) (string, error) {
+	var b []byte
+	var err error
+	for i := range v {
+		if i > 0 {
+			b = append(b, ", "...)
+		}
+		b, err = appendFloat(b, float64(v[i]))
+		if err != nil {
+			return "", err
+		}
+	}
+	return string(b), nil
+}
+
+func appendFloat(b []byte, f float64) ([]byte, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, errors.New("non-finite number")
+	}
+	return strconv.AppendFloat(b, f, 'g', -1, 32), nil
+}
+
+// appendValue appends the Go expression of a non-shape value. The type of composite
+// literals is omitted if elided is true, as is permitted for elements of slice literals.
+func (g *goWriter) appendValue(b []byte, v reflect.Value, elided bool) ([]byte, error) {
+	typ := v.Type()
+	switch {
+	case typ == fillRuleType:
+		switch FillRule(v.Uint()) {
+		case FillEvenOdd:
+			return append(b, "gsdf.FillEvenOdd"...), nil
+		case FillNonZero:
+			return append(b, "gsdf.FillNonZero"...), nil
+		}
+		return nil, errors.New("invalid fill rule")
+
+	case typ == gridInterpType:
+		switch GridInterpolation(v.Uint()) {
+		case GridLinear:
+			return append(b, "gsdf.GridLinear"...), nil
+		case GridCubic:
+			return append(b, "gsdf.GridCubic"...), nil
+		}
+		return nil, errors.New("invalid grid interpolation")
+
+	case typ == box2Type || typ == box3Type:
+		if !elided {
+			g.addImport(typ.String()[:3])
+			b = append(b, typ.String()...)
+		}
+		var err error
+		b = append(b, "{Min: "...)
+		b, err = g.appendValue(b, v.FieldByName("Min"), false)
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, ", Max: "...)
+		b, err = g.appendValue(b, v.FieldByName("Max"), false)
+		if err != nil {
+			return nil, err
+		}
+		return append(b, '}'), nil
+
+	case typ == mat4Type:
+		g.addImport("ms3")
+		m := v.Interface().(ms3.Mat4).Array()
+		elems, err := g.floats(m[:]...)
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, "ms3.NewMat4([]float32{"...)
+		b = append(b, elems...)
+		return append(b, "})"...), nil
+
+	case typ == vec2Type || typ == vec3Type:
+		if !elided {
+			g.addImport(typ.String()[:3])
+			b = append(b, typ.String()...)
+		}
+		b = append(b, '{')
+		for i := 0; i < v.NumField(); i++ {
+			if !typ.Field(i).IsExported() {
+				continue // Skip padding fields.
+			} else if i > 0 {
+				b = append(b, ", "...)
+			}
+			b = append(b, typ.Field(i).Name...)
+			b = append(b, ": "...)
+			var err error
+			b, err = appendFloat(b, v.Field(i).Float())
+			if err != nil {
+				return nil, err
+			}
+		}
+		return append(b, '}'), nil
+	}
+	switch typ.Kind() {
+	case reflect.Float32:
+		return appendFloat(b, v.Float())
+	case reflect.Int:
+		return strconv.AppendInt(b, v.Int(), 10), nil
+	case reflect.Bool:
+		return strconv.AppendBool(b, v.Bool()), nil
+	case reflect.Slice, reflect.Array:
+		if !elided {
+			elem := typ.Elem()
+			for elem.Kind() == reflect.Slice || elem.Kind() == reflect.Array {
+				elem = elem.Elem()
+			}
+			if elem == vec2Type || elem == vec3Type {
+				g.addImport(elem.String()[:3])
+			}
+			b = append(b, typ.String()...)
+		}
+		b = append(b, '{')
+		var err error
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				b = append(b, ", "...)
+			}
+			b, err = g.appendValue(b, v.Index(i), true)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return append(b, '}'), nil
+	}
+	return nil, fmt.Errorf("unsupported parameter type %s", typ)
+}
+
+func (g *goWriter) newVar(base string) string {
+	g.nvars++
+	return strings.ToLower(base[:1]) + base[1:] + strconv.Itoa(g.nvars)
+}
+
+func (g *goWriter) addImport(pkg string) {
+	if g.imports == nil {
+		g.imports = make(map[string]bool)
+	}
+	g.imports[pkg] = true
+}
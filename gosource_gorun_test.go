@@ -0,0 +1,93 @@
+//go:build gorun
+
+package gsdf_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/soypat/gsdf"
+)
+
+// TestWriteGoRun compiles and runs the source generated for all described shapes. It requires the go
+// toolchain and module resolution of this repository's dependencies so is only built with the gorun tag:
+//
+//	go test -tags gorun -run TestWriteGoRun .
+func TestWriteGoRun(t *testing.T) {
+	var bld gsdf.Builder
+	dir := t.TempDir()
+	var buf, main, wantJSON bytes.Buffer
+	main.WriteString("package main\n\nimport (\n\t\"os\"\n\n\t\"github.com/soypat/gsdf\"\n)\n\nfunc main() {\n\tvar bld gsdf.Builder\n")
+	for i, s := range testDescribedShapes(&bld) {
+		buf.Reset()
+		funcName := fmt.Sprintf("build%d", i)
+		err := gsdf.WriteGo(&buf, s, "main", funcName)
+		if err != nil {
+			t.Fatalf("%s: %s", s.AppendShaderName(nil), err)
+		}
+		err = os.WriteFile(filepath.Join(dir, funcName+".go"), buf.Bytes(), 0666)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = gsdf.EncodeJSON(&wantJSON, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintf(&main, "\tif err := gsdf.EncodeJSON(os.Stdout, %s(&bld)); err != nil {\n\t\tpanic(err)\n\t}\n", funcName)
+	}
+	main.WriteString("}\n")
+	got := goRunModule(t, dir, main.Bytes())
+	gotLines, wantLines := strings.Split(got, "\n"), strings.Split(wantJSON.String(), "\n")
+	if len(gotLines) != len(wantLines) {
+		t.Fatalf("generated program wrote %d lines, want %d", len(gotLines), len(wantLines))
+	}
+	for i := range wantLines {
+		if gotLines[i] != wantLines[i] {
+			t.Errorf("generated shape %d mismatch:\ngot  %.200s\nwant %.200s", i, gotLines[i], wantLines[i])
+		}
+	}
+}
+
+// goRunModule runs the main package in dir as a module which depends on this repository and returns its output.
+func goRunModule(t *testing.T, dir string, mainSrc []byte) string {
+	t.Helper()
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go toolchain not found:", err)
+	}
+	repo, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	gomod, err := os.ReadFile("go.mod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gosum, err := os.ReadFile("go.sum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gomod = bytes.Replace(gomod, []byte("module github.com/soypat/gsdf"), []byte("module gsdftest"), 1)
+	gomod = fmt.Appendf(gomod, "\nrequire github.com/soypat/gsdf v0.0.0\n\nreplace github.com/soypat/gsdf => %q\n", repo)
+	for name, data := range map[string][]byte{"go.mod": gomod, "go.sum": gosum, "main.go": mainSrc} {
+		err = os.WriteFile(filepath.Join(dir, name), data, 0666)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command(gobin, "run", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "CGO_ENABLED=0", "GOFLAGS=-mod=mod")
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("running generated code: %s\n%s", err, stderr.String())
+	}
+	return string(out)
+}
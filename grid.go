@@ -0,0 +1,296 @@
+package gsdf
+
+import (
+	"strconv"
+
+	"github.com/chewxy/math32"
+	"github.com/soypat/geometry/ms2"
+	"github.com/soypat/geometry/ms3"
+	"github.com/soypat/gsdf/glbuild"
+)
+
+// GridInterpolation selects how distances are interpolated between the samples of grid shapes
+// created with [Builder.NewGridSDF3] and [Builder.NewGridSDF2].
+type GridInterpolation uint8
+
+const (
+	// GridLinear interpolates linearly between neighboring samples (trilinear in 3D, bilinear in 2D).
+	// Interpolated distances are continuous and never exceed the sample range.
+	GridLinear GridInterpolation = iota
+	// GridCubic interpolates with Catmull-Rom splines over the 4 nearest samples along each axis
+	// (tricubic in 3D, bicubic in 2D). Interpolated distances are smooth and pass through the samples
+	// but may overshoot the sample range near sharp features.
+	GridCubic
+)
+
+type gridSDF3 struct {
+	data       []float32
+	nx, ny, nz int
+	bb         ms3.Box
+	interp     GridInterpolation
+}
+
+// NewGridSDF3 creates a shape from distances sampled on a regular grid, such as a precomputed distance volume.
+// data contains nx*ny*nz samples ordered with x varying fastest and z slowest. Samples are evenly spaced
+// with the first and last samples along each axis lying on the faces of bounds, so sample (i,j,k) is at
+// bounds.Min + (i/(nx-1), j/(ny-1), k/(nz-1)) * bounds.Size(). The surface should lie strictly within bounds,
+// that is, samples on the bounds faces should be positive. Outside bounds the distance is the hypotenuse of the
+// distance to bounds and the interpolated distance at the closest point of bounds, which does not overestimate
+// the distance to a surface within bounds. data is not copied and must not be modified.
+//
+// Interpolating samples of an exact distance field does not yield an exact distance field: the gradient of
+// interpolated distances may exceed 1 between samples, most noticeably on coarse grids around sharp features.
+func (bld *Builder) NewGridSDF3(data []float32, nx, ny, nz int, bounds ms3.Box, interp GridInterpolation) glbuild.Shader3D {
+	if nx < 2 || ny < 2 || nz < 2 {
+		bld.shapeErrorf("grid requires at least 2 samples along each axis")
+		return nil
+	} else if len(data) != nx*ny*nz {
+		bld.shapeErrorf("grid data length %d does not match %dx%dx%d samples", len(data), nx, ny, nz)
+		return nil
+	}
+	sz := bounds.Size()
+	if !(sz.X > 0 && sz.Y > 0 && sz.Z > 0) || bounds.Empty() {
+		bld.shapeErrorf("empty grid bounds")
+	}
+	if interp > GridCubic {
+		bld.shapeErrorf("invalid grid interpolation")
+	}
+	if badFloats(data) {
+		bld.shapeErrorf("NaN or infinite value in grid data")
+	}
+	grid := gridSDF3{data: data, nx: nx, ny: ny, nz: nz, bb: bounds, interp: interp}
+	if bld.useShaderBuffer(len(data)) {
+		return &gridSDF3SSBO{gridSDF3: grid, bufname: makeHashName(nil, "ssboGrid3D", data)}
+	}
+	return &grid
+}
+
+func (g *gridSDF3) Bounds() ms3.Box { return g.bb }
+
+func (g *gridSDF3) ForEachChild(userData any, fn func(userData any, s *glbuild.Shader3D) error) error {
+	return nil
+}
+
+func (g *gridSDF3) AppendShaderName(b []byte) []byte {
+	b = makeHashName(b, "grid3D", g.data)
+	b = glbuild.AppendFloats(b, 0, 'n', 'p', float32(g.nx), float32(g.ny), float32(g.nz), float32(g.interp))
+	b = glbuild.AppendFloats(b, 0, 'n', 'p', g.bb.Min.X, g.bb.Min.Y, g.bb.Min.Z, g.bb.Max.X, g.bb.Max.Y, g.bb.Max.Z)
+	return b
+}
+
+func (g *gridSDF3) AppendShaderBody(b []byte) []byte {
+	b = glbuild.AppendFloatSliceDecl(b, "grid", g.data)
+	return g.appendGridShader(b)
+}
+
+func (g *gridSDF3) AppendShaderObjects(objects []glbuild.ShaderObject) []glbuild.ShaderObject {
+	return objects
+}
+
+func (g *gridSDF3) appendGridShader(b []byte) []byte {
+	b = append(b, "const ivec3 n=ivec3("...)
+	b = appendInts(b, g.nx, g.ny, g.nz)
+	b = append(b, ");\n"...)
+	b = glbuild.AppendVec3Decl(b, "bmin", g.bb.Min)
+	b = glbuild.AppendVec3Decl(b, "bmax", g.bb.Max)
+	b = append(b, `vec3 q=clamp(p,bmin,bmax);
+vec3 g=(q-bmin)*vec3(n-1)/(bmax-bmin);
+ivec3 i0=min(ivec3(g),n-2);
+vec3 f=g-vec3(i0);
+`...)
+	if g.interp == GridCubic {
+		b = append(b, `vec3 f2=f*f;
+vec3 f3=f2*f;
+vec3 w[4]=vec3[4](0.5*(2.0*f2-f3-f),0.5*(3.0*f3-5.0*f2+2.0),0.5*(4.0*f2-3.0*f3+f),0.5*(f3-f2));
+float d=0.0;
+for(int k=0;k<4;k++){
+	int kk=clamp(i0.z+k-1,0,n.z-1);
+	for(int j=0;j<4;j++){
+		int row=n.x*(clamp(i0.y+j-1,0,n.y-1)+n.y*kk);
+		for(int i=0;i<4;i++){
+			d+=w[i].x*w[j].y*w[k].z*grid[row+clamp(i0.x+i-1,0,n.x-1)];
+		}
+	}
+}
+float o=length(p-q);
+return o>0.0?(d>0.0?sqrt(d*d+o*o):o):d;
+`...)
+		return b
+	}
+	b = append(b, `int sy=n.x;
+int sz=n.x*n.y;
+int c=i0.x+sy*i0.y+sz*i0.z;
+float d00=mix(grid[c],grid[c+1],f.x);
+float d10=mix(grid[c+sy],grid[c+sy+1],f.x);
+float d01=mix(grid[c+sz],grid[c+sz+1],f.x);
+float d11=mix(grid[c+sy+sz],grid[c+sy+sz+1],f.x);
+float d=mix(mix(d00,d10,f.y),mix(d01,d11,f.y),f.z);
+float o=length(p-q);
+return o>0.0?(d>0.0?sqrt(d*d+o*o):o):d;
+`...)
+	return b
+}
+
+type gridSDF3SSBO struct {
+	gridSDF3
+	bufname []byte
+}
+
+func (g *gridSDF3SSBO) AppendShaderBody(b []byte) []byte {
+	b = glbuild.AppendDefineDecl(b, "grid", string(g.bufname))
+	b = g.appendGridShader(b)
+	b = glbuild.AppendUndefineDecl(b, "grid")
+	return b
+}
+
+func (g *gridSDF3SSBO) AppendShaderObjects(objects []glbuild.ShaderObject) []glbuild.ShaderObject {
+	ssbo, err := glbuild.MakeShaderBufferReadOnly(g.bufname, g.data)
+	if err != nil {
+		panic(err)
+	}
+	return append(objects, ssbo)
+}
+
+type gridSDF2 struct {
+	data   []float32
+	nx, ny int
+	bb     ms2.Box
+	interp GridInterpolation
+}
+
+// NewGridSDF2 creates a 2D shape from distances sampled on a regular grid. data contains nx*ny samples
+// ordered with x varying fastest. See [Builder.NewGridSDF3] for the sample layout and evaluation outside bounds.
+func (bld *Builder) NewGridSDF2(data []float32, nx, ny int, bounds ms2.Box, interp GridInterpolation) glbuild.Shader2D {
+	if nx < 2 || ny < 2 {
+		bld.shapeErrorf("grid requires at least 2 samples along each axis")
+		return nil
+	} else if len(data) != nx*ny {
+		bld.shapeErrorf("grid data length %d does not match %dx%d samples", len(data), nx, ny)
+		return nil
+	}
+	sz := bounds.Size()
+	if !(sz.X > 0 && sz.Y > 0) || bounds.Empty() {
+		bld.shapeErrorf("empty grid bounds")
+	}
+	if interp > GridCubic {
+		bld.shapeErrorf("invalid grid interpolation")
+	}
+	if badFloats(data) {
+		bld.shapeErrorf("NaN or infinite value in grid data")
+	}
+	grid := gridSDF2{data: data, nx: nx, ny: ny, bb: bounds, interp: interp}
+	if bld.useShaderBuffer(len(data)) {
+		return &gridSDF2SSBO{gridSDF2: grid, bufname: makeHashName(nil, "ssboGrid2D", data)}
+	}
+	return &grid
+}
+
+func (g *gridSDF2) Bounds() ms2.Box { return g.bb }
+
+func (g *gridSDF2) ForEach2DChild(userData any, fn func(userData any, s *glbuild.Shader2D) error) error {
+	return nil
+}
+
+func (g *gridSDF2) AppendShaderName(b []byte) []byte {
+	b = makeHashName(b, "grid2D", g.data)
+	b = glbuild.AppendFloats(b, 0, 'n', 'p', float32(g.nx), float32(g.ny), float32(g.interp))
+	b = glbuild.AppendFloats(b, 0, 'n', 'p', g.bb.Min.X, g.bb.Min.Y, g.bb.Max.X, g.bb.Max.Y)
+	return b
+}
+
+func (g *gridSDF2) AppendShaderBody(b []byte) []byte {
+	b = glbuild.AppendFloatSliceDecl(b, "grid", g.data)
+	return g.appendGridShader(b)
+}
+
+func (g *gridSDF2) AppendShaderObjects(objects []glbuild.ShaderObject) []glbuild.ShaderObject {
+	return objects
+}
+
+func (g *gridSDF2) appendGridShader(b []byte) []byte {
+	b = append(b, "const ivec2 n=ivec2("...)
+	b = appendInts(b, g.nx, g.ny)
+	b = append(b, ");\n"...)
+	b = glbuild.AppendVec2Decl(b, "bmin", g.bb.Min)
+	b = glbuild.AppendVec2Decl(b, "bmax", g.bb.Max)
+	b = append(b, `vec2 q=clamp(p,bmin,bmax);
+vec2 g=(q-bmin)*vec2(n-1)/(bmax-bmin);
+ivec2 i0=min(ivec2(g),n-2);
+vec2 f=g-vec2(i0);
+`...)
+	if g.interp == GridCubic {
+		b = append(b, `vec2 f2=f*f;
+vec2 f3=f2*f;
+vec2 w[4]=vec2[4](0.5*(2.0*f2-f3-f),0.5*(3.0*f3-5.0*f2+2.0),0.5*(4.0*f2-3.0*f3+f),0.5*(f3-f2));
+float d=0.0;
+for(int j=0;j<4;j++){
+	int row=n.x*clamp(i0.y+j-1,0,n.y-1);
+	for(int i=0;i<4;i++){
+		d+=w[i].x*w[j].y*grid[row+clamp(i0.x+i-1,0,n.x-1)];
+	}
+}
+float o=length(p-q);
+return o>0.0?(d>0.0?sqrt(d*d+o*o):o):d;
+`...)
+		return b
+	}
+	b = append(b, `int c=i0.x+n.x*i0.y;
+float d0=mix(grid[c],grid[c+1],f.x);
+float d1=mix(grid[c+n.x],grid[c+n.x+1],f.x);
+float d=mix(d0,d1,f.y);
+float o=length(p-q);
+return o>0.0?(d>0.0?sqrt(d*d+o*o):o):d;
+`...)
+	return b
+}
+
+type gridSDF2SSBO struct {
+	gridSDF2
+	bufname []byte
+}
+
+func (g *gridSDF2SSBO) AppendShaderBody(b []byte) []byte {
+	b = glbuild.AppendDefineDecl(b, "grid", string(g.bufname))
+	b = g.appendGridShader(b)
+	b = glbuild.AppendUndefineDecl(b, "grid")
+	return b
+}
+
+func (g *gridSDF2SSBO) AppendShaderObjects(objects []glbuild.ShaderObject) []glbuild.ShaderObject {
+	ssbo, err := glbuild.MakeShaderBufferReadOnly(g.bufname, g.data)
+	if err != nil {
+		panic(err)
+	}
+	return append(objects, ssbo)
+}
+
+// catmullRom returns the Catmull-Rom spline weights of the 4 samples around a point at fraction t between the middle two.
+func catmullRom(t float32) [4]float32 {
+	t2 := t * t
+	t3 := t2 * t
+	return [4]float32{
+		0.5 * (2*t2 - t3 - t),
+		0.5 * (3*t3 - 5*t2 + 2),
+		0.5 * (4*t2 - 3*t3 + t),
+		0.5 * (t3 - t2),
+	}
+}
+
+func appendInts(b []byte, v ...int) []byte {
+	for i := range v {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = strconv.AppendInt(b, int64(v[i]), 10)
+	}
+	return b
+}
+
+func badFloats(data []float32) bool {
+	for _, v := range data {
+		if math32.IsNaN(v) || math32.IsInf(v, 0) {
+			return true
+		}
+	}
+	return false
+}
@@ -3,8 +3,10 @@ package gsdf
 import (
 	"bytes"
 	_ "embed"
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"reflect"
 	"unsafe"
 
 	"github.com/chewxy/math32"
@@ -63,11 +65,16 @@ func (bld *Builder) useShaderBuffer(components int) bool {
 	return useGPU || components > lim
 }
 
+// makeHashName appends name followed by a hash of the contents and element type of vec and its size in bytes to dst.
+// Equal buffers yield equal names so that generated shader code does not depend on where buffers are allocated.
 func makeHashName[T any](dst []byte, name string, vec []T) []byte {
 	var z T
-	data := unsafe.Pointer(&vec[0])
 	sz := len(vec) * int(unsafe.Sizeof(z))
-	return fmt.Appendf(dst, "%s%x_%x", name, uintptr(data), sz)
+	h := hash([]byte(reflect.TypeOf(z).String()), 0xff51afd7ed558ccd)
+	if sz > 0 {
+		h = hash(unsafe.Slice((*byte)(unsafe.Pointer(&vec[0])), sz), h)
+	}
+	return fmt.Appendf(dst, "%s%x_%x", name, h, sz)
 }
 
 func (bld *Builder) Flags() Flags {
@@ -222,30 +229,25 @@ func hashfint(f float32) float32 {
 	return float32(int(f*1000000)%1000000) / 1000000 // Keep within [0.0, 1.0)
 }
 
-// func hash(b []byte, in uint64) uint64 {
-// 	x := in
-// 	for len(b) >= 8 {
-// 		x ^= binary.LittleEndian.Uint64(b)
-// 		x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
-// 		x = (x ^ (x >> 27)) * 0x94d049bb133111eb
-// 		x ^= x >> 31
-// 		b = b[8:]
-
-// 	}
-// 	if len(b) > 0 {
-// 		var buf [8]byte
-// 		copy(buf[:], b)
-// 		x ^= binary.LittleEndian.Uint64(buf[:])
-// 		x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
-// 		x = (x ^ (x >> 27)) * 0x94d049bb133111eb
-// 		x ^= x >> 31
-// 	}
-// 	return x
-// }
-
-func hashshaderptr(s glbuild.Shader) uint64 {
-	v := *(*[2]uintptr)(unsafe.Pointer(&s))
-	return (uint64(v[0]) ^ (uint64(v[1]) << 8)) * 0xbf58476d1ce4e5b9
+func hash(b []byte, in uint64) uint64 {
+	x := in
+	for len(b) >= 8 {
+		x ^= binary.LittleEndian.Uint64(b)
+		x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+		x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+		x ^= x >> 31
+		b = b[8:]
+
+	}
+	if len(b) > 0 {
+		var buf [8]byte
+		copy(buf[:], b)
+		x ^= binary.LittleEndian.Uint64(buf[:])
+		x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+		x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+		x ^= x >> 31
+	}
+	return x
 }
 
 // appendTypicalReturnFuncCall appends a function call of the following style to the dst buffer and returns the result:
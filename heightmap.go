@@ -0,0 +1,140 @@
+package gsdf
+
+import (
+	"github.com/chewxy/math32"
+	"github.com/soypat/geometry/ms3"
+	"github.com/soypat/gsdf/glbuild"
+)
+
+type heightmap struct {
+	heights []float32
+	nx, ny  int
+	pitch   float32
+	base    float32
+	height  float32
+	// invL is the inverse of the Lipschitz constant of the distance above the top surface.
+	invL float32
+}
+
+// NewHeightmap creates a relief from a grid of heights such as the pixels of an image, useful for lithophanes
+// and embossed textures. heights contains nx*ny values between 0 and 1 ordered with x varying fastest. Each
+// value is the height of a square cell of side pitch, so the relief spans nx*pitch along x and ny*pitch along y
+// centered at the origin. The relief rests on the z=0 plane and its top surface lies at base+height*h, with
+// heights bilinearly interpolated between cell centers.
+//
+// The vertical distance to the top surface is divided by sqrt(1+L²) where L is the steepest slope of the
+// interpolated surface, so the resulting distance never overestimates the distance to the surface. Steep
+// reliefs therefore yield small distances and require more steps when ray marching. Outside the relief bounds
+// the distance is extended as described in [Builder.NewGridSDF3]. heights is not copied and must not be modified.
+func (bld *Builder) NewHeightmap(heights []float32, nx, ny int, pitch, base, height float32) glbuild.Shader3D {
+	if nx < 2 || ny < 2 {
+		bld.shapeErrorf("heightmap requires at least 2 cells along each axis")
+		return nil
+	} else if len(heights) != nx*ny {
+		bld.shapeErrorf("heightmap length %d does not match %dx%d cells", len(heights), nx, ny)
+		return nil
+	}
+	if !(pitch > 0) || math32.IsInf(pitch, 1) {
+		bld.shapeErrorf("bad heightmap pitch")
+	}
+	if !(base >= 0) || !(height >= 0) || !(base+height > 0) || math32.IsInf(base+height, 1) {
+		bld.shapeErrorf("bad heightmap base or height")
+	}
+	var dxMax, dyMax float32
+	for j := 0; j < ny; j++ {
+		row := heights[j*nx : (j+1)*nx]
+		for i, h := range row {
+			if !(h >= 0 && h <= 1) {
+				bld.shapeErrorf("heightmap value %v at (%d,%d) out of range [0,1]", h, i, j)
+				return nil
+			}
+			if i > 0 {
+				dxMax = math32.Max(dxMax, math32.Abs(h-row[i-1]))
+			}
+			if j > 0 {
+				dyMax = math32.Max(dyMax, math32.Abs(h-heights[i+(j-1)*nx]))
+			}
+		}
+	}
+	// Bilinear interpolation derivatives along each axis are convex combinations of the differences
+	// between neighboring cells along that axis.
+	slope := height / pitch * math32.Hypot(dxMax, dyMax)
+	hm := heightmap{heights: heights, nx: nx, ny: ny, pitch: pitch, base: base, height: height, invL: 1 / math32.Hypot(1, slope)}
+	if bld.useShaderBuffer(len(heights)) {
+		return &heightmapSSBO{heightmap: hm, bufname: makeHashName(nil, "ssboHeightmap", heights)}
+	}
+	return &hm
+}
+
+func (hm *heightmap) halfDims() ms3.Vec {
+	return ms3.Vec{X: 0.5 * hm.pitch * float32(hm.nx), Y: 0.5 * hm.pitch * float32(hm.ny), Z: 0.5 * (hm.base + hm.height)}
+}
+
+func (hm *heightmap) Bounds() ms3.Box {
+	h := hm.halfDims()
+	return ms3.Box{Min: ms3.Vec{X: -h.X, Y: -h.Y}, Max: ms3.Vec{X: h.X, Y: h.Y, Z: 2 * h.Z}}
+}
+
+func (hm *heightmap) ForEachChild(userData any, fn func(userData any, s *glbuild.Shader3D) error) error {
+	return nil
+}
+
+func (hm *heightmap) AppendShaderName(b []byte) []byte {
+	b = makeHashName(b, "heightmap", hm.heights)
+	b = glbuild.AppendFloats(b, 0, 'n', 'p', float32(hm.nx), float32(hm.ny), hm.pitch, hm.base, hm.height)
+	return b
+}
+
+func (hm *heightmap) AppendShaderBody(b []byte) []byte {
+	b = glbuild.AppendFloatSliceDecl(b, "hmap", hm.heights)
+	return hm.appendHeightmapShader(b)
+}
+
+func (hm *heightmap) AppendShaderObjects(objects []glbuild.ShaderObject) []glbuild.ShaderObject {
+	return objects
+}
+
+func (hm *heightmap) appendHeightmapShader(b []byte) []byte {
+	b = append(b, "const ivec2 n=ivec2("...)
+	b = appendInts(b, hm.nx, hm.ny)
+	b = append(b, ");\n"...)
+	b = glbuild.AppendVec3Decl(b, "hdims", hm.halfDims())
+	b = glbuild.AppendFloatDecl(b, "pitch", hm.pitch)
+	b = glbuild.AppendFloatDecl(b, "base", hm.base)
+	b = glbuild.AppendFloatDecl(b, "height", hm.height)
+	b = glbuild.AppendFloatDecl(b, "invL", hm.invL)
+	b = append(b, `vec3 q=clamp(p,vec3(-hdims.xy,0.0),vec3(hdims.xy,2.0*hdims.z));
+vec3 e=abs(q-vec3(0.0,0.0,hdims.z))-hdims;
+vec2 g=clamp((q.xy+hdims.xy)/pitch-0.5,vec2(0.0),vec2(n-1));
+ivec2 i0=min(ivec2(g),n-2);
+vec2 f=g-vec2(i0);
+int c=i0.x+n.x*i0.y;
+float h=mix(mix(hmap[c],hmap[c+1],f.x),mix(hmap[c+n.x],hmap[c+n.x+1],f.x),f.y);
+float top=base+height*h;
+float d=max(max(e.x,max(e.y,e.z)),(q.z-top)*invL);
+float o=length(p-q);
+d=o>0.0?(d>0.0?sqrt(d*d+o*o):o):d;
+return max(d,(p.z-top)*invL);
+`...)
+	return b
+}
+
+type heightmapSSBO struct {
+	heightmap
+	bufname []byte
+}
+
+func (hm *heightmapSSBO) AppendShaderBody(b []byte) []byte {
+	b = glbuild.AppendDefineDecl(b, "hmap", string(hm.bufname))
+	b = hm.appendHeightmapShader(b)
+	b = glbuild.AppendUndefineDecl(b, "hmap")
+	return b
+}
+
+func (hm *heightmapSSBO) AppendShaderObjects(objects []glbuild.ShaderObject) []glbuild.ShaderObject {
+	ssbo, err := glbuild.MakeShaderBufferReadOnly(hm.bufname, hm.heights)
+	if err != nil {
+		panic(err)
+	}
+	return append(objects, ssbo)
+}
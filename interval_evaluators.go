@@ -0,0 +1,1135 @@
+package gsdf
+
+import (
+	"github.com/chewxy/math32"
+	"github.com/soypat/geometry/ms2"
+	"github.com/soypat/geometry/ms3"
+	"github.com/soypat/gsdf/glbuild"
+	"github.com/soypat/gsdf/gleval"
+)
+
+// This file contains the interval evaluators of shapes which implement [gleval.SDF3Interval] and [gleval.SDF2Interval].
+// Each evaluator must return a range which contains the result of the shape's Evaluate method
+// for all positions within the box, even when the shape is not an exact distance field.
+
+// interval is a closed range of values [lo, hi].
+type interval struct {
+	lo, hi float32
+}
+
+func (a interval) addf(f float32) interval { return interval{lo: a.lo + f, hi: a.hi + f} }
+
+func (a interval) neg() interval { return interval{lo: -a.hi, hi: -a.lo} }
+
+func (a interval) scale(f float32) interval {
+	if f < 0 {
+		return interval{lo: f * a.hi, hi: f * a.lo}
+	}
+	return interval{lo: f * a.lo, hi: f * a.hi}
+}
+
+func (a interval) abs() interval {
+	if a.lo >= 0 {
+		return a
+	} else if a.hi <= 0 {
+		return a.neg()
+	}
+	return interval{lo: 0, hi: maxf(-a.lo, a.hi)}
+}
+
+func (a interval) min(b interval) interval {
+	return interval{lo: minf(a.lo, b.lo), hi: minf(a.hi, b.hi)}
+}
+
+func (a interval) max(b interval) interval {
+	return interval{lo: maxf(a.lo, b.lo), hi: maxf(a.hi, b.hi)}
+}
+
+// hypot returns the range of sqrt(a*a + b*b).
+func (a interval) hypot(b interval) interval {
+	a, b = a.abs(), b.abs()
+	return interval{lo: hypotf(a.lo, b.lo), hi: hypotf(a.hi, b.hi)}
+}
+
+func boxIntervals3(b ms3.Box) (x, y, z interval) {
+	return interval{lo: b.Min.X, hi: b.Max.X}, interval{lo: b.Min.Y, hi: b.Max.Y}, interval{lo: b.Min.Z, hi: b.Max.Z}
+}
+
+func boxIntervals2(b ms2.Box) (x, y interval) {
+	return interval{lo: b.Min.X, hi: b.Max.X}, interval{lo: b.Min.Y, hi: b.Max.Y}
+}
+
+// absBox3 returns the box containing the element-wise absolute value of all positions in b.
+func absBox3(b ms3.Box) ms3.Box {
+	x, y, z := boxIntervals3(b)
+	x, y, z = x.abs(), y.abs(), z.abs()
+	return ms3.Box{Min: ms3.Vec{X: x.lo, Y: y.lo, Z: z.lo}, Max: ms3.Vec{X: x.hi, Y: y.hi, Z: z.hi}}
+}
+
+// absBox2 returns the box containing the element-wise absolute value of all positions in b.
+func absBox2(b ms2.Box) ms2.Box {
+	x, y := boxIntervals2(b)
+	x, y = x.abs(), y.abs()
+	return ms2.Box{Min: ms2.Vec{X: x.lo, Y: y.lo}, Max: ms2.Vec{X: x.hi, Y: y.hi}}
+}
+
+// mulBox2 returns the axis aligned box containing b transformed by m.
+func mulBox2(m ms2.Mat2, b ms2.Box) ms2.Box {
+	verts := b.Vertices()
+	v := ms2.MulMatVec(m, verts[0])
+	box := ms2.Box{Min: v, Max: v}
+	for _, v := range verts[1:] {
+		box = box.IncludePoint(ms2.MulMatVec(m, v))
+	}
+	return box
+}
+
+func box2D(b ms3.Box) ms2.Box {
+	return ms2.Box{Min: ms2.Vec{X: b.Min.X, Y: b.Min.Y}, Max: ms2.Vec{X: b.Max.X, Y: b.Max.Y}}
+}
+
+func evaluateInterval3(obj bounder3, boxes []ms3.Box, lo, hi []float32, userData any) error {
+	sdf, err := gleval.AssertSDF3Interval(obj)
+	if err != nil {
+		return err
+	}
+	return sdf.EvaluateInterval(boxes, lo, hi, userData)
+}
+
+func evaluateInterval2(obj bounder2, boxes []ms2.Box, lo, hi []float32, userData any) error {
+	sdf, err := gleval.AssertSDF2Interval(obj)
+	if err != nil {
+		return err
+	}
+	return sdf.EvaluateInterval(boxes, lo, hi, userData)
+}
+
+// evaluateIntervalLipschitz3 bounds the distance of an exact SDF over boxes. The distance of an exact
+// SDF changes at most as much as the position so the distance over a box is within half of the
+// box diagonal of the distance at the box center.
+func evaluateIntervalLipschitz3(sdf gleval.SDF3, boxes []ms3.Box, lo, hi []float32, userData any) error {
+	vp, err := gleval.GetVecPool(userData)
+	if err != nil {
+		return err
+	}
+	centers := vp.V3.Acquire(len(boxes))
+	defer vp.V3.Release(centers)
+	for i, b := range boxes {
+		centers[i] = b.Center()
+	}
+	err = sdf.Evaluate(centers, lo, userData)
+	if err != nil {
+		return err
+	}
+	for i, d := range lo {
+		r := 0.5 * boxes[i].Diagonal()
+		lo[i] = d - r
+		hi[i] = d + r
+	}
+	return nil
+}
+
+// evaluateIntervalLipschitz2 is the 2D version of [evaluateIntervalLipschitz3].
+func evaluateIntervalLipschitz2(sdf gleval.SDF2, boxes []ms2.Box, lo, hi []float32, userData any) error {
+	vp, err := gleval.GetVecPool(userData)
+	if err != nil {
+		return err
+	}
+	centers := vp.V2.Acquire(len(boxes))
+	defer vp.V2.Release(centers)
+	for i, b := range boxes {
+		centers[i] = b.Center()
+	}
+	err = sdf.Evaluate(centers, lo, userData)
+	if err != nil {
+		return err
+	}
+	for i, d := range lo {
+		r := 0.5 * boxes[i].Diagonal()
+		lo[i] = d - r
+		hi[i] = d + r
+	}
+	return nil
+}
+
+// evaluateIntervalBinary3 evaluates the intervals of s1 and s2 over boxes and combines them with op.
+func evaluateIntervalBinary3(s1, s2 glbuild.Shader3D, boxes []ms3.Box, lo, hi []float32, userData any, op func(a, b interval) interval) error {
+	vp, err := gleval.GetVecPool(userData)
+	if err != nil {
+		return err
+	}
+	lo2 := vp.Float.Acquire(len(lo))
+	hi2 := vp.Float.Acquire(len(hi))
+	defer vp.Float.Release(lo2)
+	defer vp.Float.Release(hi2)
+	err = evaluateInterval3(s1, boxes, lo, hi, userData)
+	if err != nil {
+		return err
+	}
+	err = evaluateInterval3(s2, boxes, lo2, hi2, userData)
+	if err != nil {
+		return err
+	}
+	for i := range lo {
+		r := op(interval{lo: lo[i], hi: hi[i]}, interval{lo: lo2[i], hi: hi2[i]})
+		lo[i], hi[i] = r.lo, r.hi
+	}
+	return nil
+}
+
+// evaluateIntervalBinary2 evaluates the intervals of s1 and s2 over boxes and combines them with op.
+func evaluateIntervalBinary2(s1, s2 glbuild.Shader2D, boxes []ms2.Box, lo, hi []float32, userData any, op func(a, b interval) interval) error {
+	vp, err := gleval.GetVecPool(userData)
+	if err != nil {
+		return err
+	}
+	lo2 := vp.Float.Acquire(len(lo))
+	hi2 := vp.Float.Acquire(len(hi))
+	defer vp.Float.Release(lo2)
+	defer vp.Float.Release(hi2)
+	err = evaluateInterval2(s1, boxes, lo, hi, userData)
+	if err != nil {
+		return err
+	}
+	err = evaluateInterval2(s2, boxes, lo2, hi2, userData)
+	if err != nil {
+		return err
+	}
+	for i := range lo {
+		r := op(interval{lo: lo[i], hi: hi[i]}, interval{lo: lo2[i], hi: hi2[i]})
+		lo[i], hi[i] = r.lo, r.hi
+	}
+	return nil
+}
+
+// evaluateIntervalTransformed3 evaluates the interval of s over boxes transformed by fn.
+func evaluateIntervalTransformed3(s glbuild.Shader3D, boxes []ms3.Box, lo, hi []float32, userData any, fn func(ms3.Box) ms3.Box) error {
+	vp, err := gleval.GetVecPool(userData)
+	if err != nil {
+		return err
+	}
+	transformed := vp.Box3.Acquire(len(boxes))
+	defer vp.Box3.Release(transformed)
+	for i, b := range boxes {
+		transformed[i] = fn(b)
+	}
+	return evaluateInterval3(s, transformed, lo, hi, userData)
+}
+
+// evaluateIntervalTransformed2 evaluates the interval of s over boxes transformed by fn.
+func evaluateIntervalTransformed2(s glbuild.Shader2D, boxes []ms2.Box, lo, hi []float32, userData any, fn func(ms2.Box) ms2.Box) error {
+	vp, err := gleval.GetVecPool(userData)
+	if err != nil {
+		return err
+	}
+	transformed := vp.Box2.Acquire(len(boxes))
+	defer vp.Box2.Release(transformed)
+	for i, b := range boxes {
+		transformed[i] = fn(b)
+	}
+	return evaluateInterval2(s, transformed, lo, hi, userData)
+}
+
+func intersectInterval(a, b interval) interval { return a.max(b) }
+
+func diffInterval(a, b interval) interval { return a.max(b.neg()) }
+
+func xorInterval(a, b interval) interval { return a.min(b).max(a.max(b).neg()) }
+
+func (u *sphere) EvaluateInterval(boxes []ms3.Box, lo, hi []float32, userData any) error {
+	r := u.r
+	for i, b := range boxes {
+		x, y, z := boxIntervals3(b)
+		d := x.hypot(y).hypot(z).addf(-r)
+		lo[i], hi[i] = d.lo, d.hi
+	}
+	return nil
+}
+
+func (b *box) EvaluateInterval(boxes []ms3.Box, lo, hi []float32, userData any) error {
+	d := ms3.Scale(0.5, b.dims)
+	r := b.round
+	// Distance is non-decreasing with respect to q so its range is found at the limits of q.
+	dist := func(q ms3.Vec) float32 {
+		return ms3.Norm(ms3.MaxElem(q, ms3.Vec{})) + minf(q.Max(), 0.0) - r
+	}
+	for i, bb := range boxes {
+		ab := absBox3(bb)
+		lo[i] = dist(ms3.AddScalar(r, ms3.Sub(ab.Min, d)))
+		hi[i] = dist(ms3.AddScalar(r, ms3.Sub(ab.Max, d)))
+	}
+	return nil
+}
+
+func (bf *boxframe) EvaluateInterval(boxes []ms3.Box, lo, hi []float32, userData any) error {
+	return evaluateIntervalLipschitz3(bf, boxes, lo, hi, userData)
+}
+
+func (t *torus) EvaluateInterval(boxes []ms3.Box, lo, hi []float32, userData any) error {
+	t1 := t.rGreater
+	t2 := t.rLesser
+	for i, b := range boxes {
+		x, y, z := boxIntervals3(b)
+		d := x.hypot(y).addf(-t1).hypot(z).addf(-t2)
+		lo[i], hi[i] = d.lo, d.hi
+	}
+	return nil
+}
+
+func (c *cylinder) EvaluateInterval(boxes []ms3.Box, lo, hi []float32, userData any) error {
+	r, h, round := c.args()
+	// Distance is non-decreasing with respect to dx and dy.
+	dist := func(dx, dy float32) float32 {
+		return minf(maxf(dx, dy), 0) + hypotf(maxf(dx, 0), maxf(dy, 0)) - round
+	}
+	for i, b := range boxes {
+		x, y, z := boxIntervals3(b)
+		dx := x.hypot(y).addf(round - r)
+		dy := z.abs().addf(-h)
+		lo[i] = dist(dx.lo, dy.lo)
+		hi[i] = dist(dx.hi, dy.hi)
+	}
+	return nil
+}
+
+func (h *hex) EvaluateInterval(boxes []ms3.Box, lo, hi []float32, userData any) error {
+	return evaluateIntervalLipschitz3(h, boxes, lo, hi, userData)
+}
+
+// EvaluateInterval implements [gleval.SDF3Interval].
+func (u *OpUnion) EvaluateInterval(boxes []ms3.Box, lo, hi []float32, userData any) error {
+	u.mustValidate()
+	vp, err := gleval.GetVecPool(userData)
+	if err != nil {
+		return err
+	}
+	auxLo := vp.Float.Acquire(len(lo))
+	auxHi := vp.Float.Acquire(len(hi))
+	defer vp.Float.Release(auxLo)
+	defer vp.Float.Release(auxHi)
+	err = evaluateInterval3(u.joined[0], boxes, lo, hi, userData)
+	if err != nil {
+		return err
+	}
+	for _, shape := range u.joined[1:] {
+		err = evaluateInterval3(shape, boxes, auxLo, auxHi, userData)
+		if err != nil {
+			return err
+		}
+		minReduce(lo, auxLo)
+		minReduce(hi, auxHi)
+	}
+	if len(u.joined) >= unionBVHMinShapes && u.cullDist > 0 {
+		// Culled shapes may evaluate to conservative distances down to cullDist.
+		for i, d := range lo {
+			lo[i] = minf(d, u.cullDist)
+		}
+	}
+	return nil
+}
+
+func (u *intersect) EvaluateInterval(boxes []ms3.Box, lo, hi []float32, userData any) error {
+	return evaluateIntervalBinary3(u.s1, u.s2, boxes, lo, hi, userData, intersectInterval)
+}
+
+func (u *diff) EvaluateInterval(boxes []ms3.Box, lo, hi []float32, userData any) error {
+	return evaluateIntervalBinary3(u.s1, u.s2, boxes, lo, hi, userData, diffInterval)
+}
+
+func (u *xor) EvaluateInterval(boxes []ms3.Box, lo, hi []float32, userData any) error {
+	return evaluateIntervalBinary3(u.s1, u.s2, boxes, lo, hi, userData, xorInterval)
+}
+
+// Smooth booleans are non-decreasing with respect to each of their arguments (non-increasing for
+// the subtracted argument of the difference) so their range is found at the limits of the arguments.
+
+func (u *smoothUnion) EvaluateInterval(boxes []ms3.Box, lo, hi []float32, userData any) error {
+	k := u.k
+	smin := func(a, b float32) float32 {
+		h := clampf(0.5+0.5*(b-a)/k, 0, 1)
+		return mixf(b, a, h) - k*h*(1-h)
+	}
+	return evaluateIntervalBinary3(u.s1, u.s2, boxes, lo, hi, userData, func(a, b interval) interval {
+		return interval{lo: smin(a.lo, b.lo), hi: smin(a.hi, b.hi)}
+	})
+}
+
+func (u *smoothDiff) EvaluateInterval(boxes []ms3.Box, lo, hi []float32, userData any) error {
+	k := u.k
+	sdiff := func(a, b float32) float32 {
+		h := clampf(0.5-0.5*(b+a)/k, 0, 1)
+		return mixf(a, -b, h) + k*h*(1-h)
+	}
+	return evaluateIntervalBinary3(u.s1, u.s2, boxes, lo, hi, userData, func(a, b interval) interval {
+		return interval{lo: sdiff(a.lo, b.hi), hi: sdiff(a.hi, b.lo)}
+	})
+}
+
+func (u *smoothIntersect) EvaluateInterval(boxes []ms3.Box, lo, hi []float32, userData any) error {
+	k := u.k
+	smax := func(a, b float32) float32 {
+		h := clampf(0.5-0.5*(b-a)/k, 0, 1)
+		return mixf(b, a, h) + k*h*(1-h)
+	}
+	return evaluateIntervalBinary3(u.s1, u.s2, boxes, lo, hi, userData, func(a, b interval) interval {
+		return interval{lo: smax(a.lo, b.lo), hi: smax(a.hi, b.hi)}
+	})
+}
+
+func (s *scale) EvaluateInterval(boxes []ms3.Box, lo, hi []float32, userData any) error {
+	factor := s.scale
+	factorInv := 1. / s.scale
+	scaleInv := ms3.Vec{X: factorInv, Y: factorInv, Z: factorInv}
+	err := evaluateIntervalTransformed3(s.s, boxes, lo, hi, userData, func(b ms3.Box) ms3.Box {
+		return b.Scale(scaleInv)
+	})
+	if err != nil {
+		return err
+	}
+	for i := range lo {
+		d := interval{lo: lo[i], hi: hi[i]}.scale(factor)
+		lo[i], hi[i] = d.lo, d.hi
+	}
+	return nil
+}
+
+func (s *symmetry) EvaluateInterval(boxes []ms3.Box, lo, hi []float32, userData any) error {
+	xb, yb, zb := s.xyz.X(), s.xyz.Y(), s.xyz.Z()
+	return evaluateIntervalTransformed3(s.s, boxes, lo, hi, userData, func(b ms3.Box) ms3.Box {
+		ab := absBox3(b)
+		if xb {
+			b.Min.X, b.Max.X = ab.Min.X, ab.Max.X
+		}
+		if yb {
+			b.Min.Y, b.Max.Y = ab.Min.Y, ab.Max.Y
+		}
+		if zb {
+			b.Min.Z, b.Max.Z = ab.Min.Z, ab.Max.Z
+		}
+		return b
+	})
+}
+
+// EvaluateInterval implements [gleval.SDF3Interval]. Evaluate takes the minimum distance over the instance
+// closest to a position and its neighbors. The instances neighboring the box bound the distance from below
+// and the instances closest to the box bound it from above.
+func (a *array) EvaluateInterval(boxes []ms3.Box, lo, hi []float32, userData any) error {
+	vp, err := gleval.GetVecPool(userData)
+	if err != nil {
+		return err
+	}
+	translated := vp.Box3.Acquire(len(boxes))
+	defer vp.Box3.Release(translated)
+	idmin := vp.V3.Acquire(len(boxes))
+	idmax := vp.V3.Acquire(len(boxes))
+	defer vp.V3.Release(idmin)
+	defer vp.V3.Release(idmax)
+	auxLo := vp.Float.Acquire(len(lo))
+	auxHi := vp.Float.Acquire(len(hi))
+	defer vp.Float.Release(auxLo)
+	defer vp.Float.Release(auxHi)
+	s := a.d
+	n := ms3.AddScalar(-1, a.nvec3())
+	minlim := ms3.Vec{}
+	var span ms3.Vec
+	for i, b := range boxes {
+		idmin[i] = ms3.ClampElem(ms3.AddScalar(-1, ms3.RoundElem(ms3.DivElem(b.Min, s))), minlim, n)
+		idmax[i] = ms3.ClampElem(ms3.AddScalar(1, ms3.RoundElem(ms3.DivElem(b.Max, s))), minlim, n)
+		span = ms3.MaxElem(span, ms3.Sub(idmax[i], idmin[i]))
+		lo[i] = largenum
+		hi[i] = -largenum
+	}
+	var ijk ms3.Vec
+	for k := float32(0.); k <= span.Z; k++ {
+		ijk.Z = k
+		for j := float32(0.); j <= span.Y; j++ {
+			ijk.Y = j
+			for i := float32(0.); i <= span.X; i++ {
+				ijk.X = i
+				for ib, b := range boxes {
+					rid := ms3.MinElem(ms3.Add(idmin[ib], ijk), idmax[ib])
+					translated[ib] = b.Add(ms3.Scale(-1, ms3.MulElem(s, rid)))
+				}
+				err = evaluateInterval3(a.s, translated, auxLo, auxHi, userData)
+				if err != nil {
+					return err
+				}
+				for ib, b := range boxes {
+					rid := ms3.Add(idmin[ib], ijk)
+					if rid != ms3.MinElem(rid, idmax[ib]) {
+						continue // Instance outside of box neighborhood.
+					}
+					lo[ib] = minf(lo[ib], auxLo[ib])
+					closestMin := ms3.ClampElem(ms3.RoundElem(ms3.DivElem(b.Min, s)), minlim, n)
+					closestMax := ms3.ClampElem(ms3.RoundElem(ms3.DivElem(b.Max, s)), minlim, n)
+					if rid == ms3.ClampElem(rid, closestMin, closestMax) {
+						hi[ib] = maxf(hi[ib], auxHi[ib])
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (e *elongate) EvaluateInterval(boxes []ms3.Box, lo, hi []float32, userData any) error {
+	vp, err := gleval.GetVecPool(userData)
+	if err != nil {
+		return err
+	}
+	transformed := vp.Box3.Acquire(len(boxes))
+	defer vp.Box3.Release(transformed)
+	auxLo := vp.Float.Acquire(len(lo))
+	auxHi := vp.Float.Acquire(len(hi))
+	defer vp.Float.Release(auxLo)
+	defer vp.Float.Release(auxHi)
+	h := ms3.Scale(0.5, e.h)
+	for i, b := range boxes {
+		ab := absBox3(b)
+		qlo := ms3.Sub(ab.Min, h)
+		qhi := ms3.Sub(ab.Max, h)
+		auxLo[i] = math32.Min(qlo.Max(), 0)
+		auxHi[i] = math32.Min(qhi.Max(), 0)
+		transformed[i] = ms3.Box{Min: ms3.MaxElem(qlo, ms3.Vec{}), Max: ms3.MaxElem(qhi, ms3.Vec{})}
+	}
+	err = evaluateInterval3(e.s, transformed, lo, hi, userData)
+	if err != nil {
+		return err
+	}
+	for i := range lo {
+		lo[i] += auxLo[i]
+		hi[i] += auxHi[i]
+	}
+	return nil
+}
+
+func (sh *shell) EvaluateInterval(boxes []ms3.Box, lo, hi []float32, userData any) error {
+	thickness := sh.thick
+	scaleInv := ms3.Vec{X: 1 / thickness, Y: 1 / thickness, Z: 1 / thickness}
+	err := evaluateIntervalTransformed3(sh.s, boxes, lo, hi, userData, func(b ms3.Box) ms3.Box {
+		return b.Scale(scaleInv)
+	})
+	if err != nil {
+		return err
+	}
+	for i := range lo {
+		d := interval{lo: lo[i], hi: hi[i]}.abs().addf(-thickness).scale(thickness)
+		lo[i], hi[i] = d.lo, d.hi
+	}
+	return nil
+}
+
+func (r *offset) EvaluateInterval(boxes []ms3.Box, lo, hi []float32, userData any) error {
+	err := evaluateInterval3(r.s, boxes, lo, hi, userData)
+	if err != nil {
+		return err
+	}
+	radius := r.off
+	for i := range lo {
+		lo[i] += radius
+		hi[i] += radius
+	}
+	return nil
+}
+
+func (t *translate) EvaluateInterval(boxes []ms3.Box, lo, hi []float32, userData any) error {
+	T := ms3.Scale(-1, t.p)
+	return evaluateIntervalTransformed3(t.s, boxes, lo, hi, userData, func(b ms3.Box) ms3.Box {
+		return b.Add(T)
+	})
+}
+
+func (t *transform) EvaluateInterval(boxes []ms3.Box, lo, hi []float32, userData any) error {
+	Tinv := t.tInv
+	return evaluateIntervalTransformed3(t.s, boxes, lo, hi, userData, Tinv.MulBox)
+}
+
+func (e *extrusion) EvaluateInterval(boxes []ms3.Box, lo, hi []float32, userData any) error {
+	vp, err := gleval.GetVecPool(userData)
+	if err != nil {
+		return err
+	}
+	boxes2 := vp.Box2.Acquire(len(boxes))
+	defer vp.Box2.Release(boxes2)
+	for i, b := range boxes {
+		boxes2[i] = box2D(b)
+	}
+	err = evaluateInterval2(e.s, boxes2, lo, hi, userData)
+	if err != nil {
+		return err
+	}
+	h := e.h / 2
+	// Distance is non-decreasing with respect to the 2D distance d and the distance along Z wy.
+	dist := func(d, wy float32) float32 {
+		return math32.Min(0, math32.Max(d, wy)) + math32.Hypot(math32.Max(d, 0), math32.Max(wy, 0))
+	}
+	for i, b := range boxes {
+		_, _, z := boxIntervals3(b)
+		wy := z.abs().addf(-h)
+		lo[i] = dist(lo[i], wy.lo)
+		hi[i] = dist(hi[i], wy.hi)
+	}
+	return nil
+}
+
+func (e *revolution) EvaluateInterval(boxes []ms3.Box, lo, hi []float32, userData any) error {
+	o := e.off
+	vp, err := gleval.GetVecPool(userData)
+	if err != nil {
+		return err
+	}
+	boxes2 := vp.Box2.Acquire(len(boxes))
+	defer vp.Box2.Release(boxes2)
+	for i, b := range boxes {
+		x, y, z := boxIntervals3(b)
+		r := x.hypot(z).addf(-o)
+		boxes2[i] = ms2.Box{Min: ms2.Vec{X: r.lo, Y: y.lo}, Max: ms2.Vec{X: r.hi, Y: y.hi}}
+	}
+	return evaluateInterval2(e.s2d, boxes2, lo, hi, userData)
+}
+
+// EvaluateInterval implements [gleval.SDF3Interval]. Evaluate takes the minimum distance over two
+// of the instances so the range of all instances bounds the distance.
+func (c *circarray) EvaluateInterval(boxes []ms3.Box, lo, hi []float32, userData any) error {
+	vp, err := gleval.GetVecPool(userData)
+	if err != nil {
+		return err
+	}
+	rotated := vp.Box3.Acquire(len(boxes))
+	defer vp.Box3.Release(rotated)
+	auxLo := vp.Float.Acquire(len(lo))
+	auxHi := vp.Float.Acquire(len(hi))
+	defer vp.Float.Release(auxLo)
+	defer vp.Float.Release(auxHi)
+	angle := 2 * math32.Pi / float32(c.circleDiv)
+	for i := range lo {
+		lo[i] = largenum
+		hi[i] = -largenum
+	}
+	for inst := 0; inst < c.nInst; inst++ {
+		rot := ms2.RotationMat2(angle * float32(inst)).Transpose()
+		for i, b := range boxes {
+			b2 := mulBox2(rot, box2D(b))
+			rotated[i] = ms3.Box{
+				Min: ms3.Vec{X: b2.Min.X, Y: b2.Min.Y, Z: b.Min.Z},
+				Max: ms3.Vec{X: b2.Max.X, Y: b2.Max.Y, Z: b.Max.Z},
+			}
+		}
+		err = evaluateInterval3(c.s, rotated, auxLo, auxHi, userData)
+		if err != nil {
+			return err
+		}
+		for i := range lo {
+			lo[i] = minf(lo[i], auxLo[i])
+			hi[i] = maxf(hi[i], auxHi[i])
+		}
+	}
+	return nil
+}
+
+// EvaluateInterval implements [gleval.SDF3Interval]. The box is rotated by the twist angle at its center
+// and then grown by the largest displacement of its points over the rest of the angles spanned by the box.
+func (e *twist) EvaluateInterval(boxes []ms3.Box, lo, hi []float32, userData any) error {
+	k := e.k
+	return evaluateIntervalTransformed3(e.s, boxes, lo, hi, userData, func(b ms3.Box) ms3.Box {
+		x, y, _ := boxIntervals3(b)
+		R := x.hypot(y).hi
+		zc := 0.5 * (b.Min.Z + b.Max.Z)
+		halfSpan := 0.5 * absf(k) * (b.Max.Z - b.Min.Z)
+		b2 := mulBox2(ms2.RotationMat2(k*zc), box2D(b))
+		// A point rotated by an angle a moves 2*R*sin(a/2) which is less than both R*a and 2*R.
+		grow := R * minf(halfSpan, 2)
+		b2.Min = ms2.MaxElem(ms2.AddScalar(-grow, b2.Min), ms2.Vec{X: -R, Y: -R})
+		b2.Max = ms2.MinElem(ms2.AddScalar(grow, b2.Max), ms2.Vec{X: R, Y: R})
+		return ms3.Box{
+			Min: ms3.Vec{X: b2.Min.X, Y: b2.Min.Y, Z: b.Min.Z},
+			Max: ms3.Vec{X: b2.Max.X, Y: b2.Max.Y, Z: b.Max.Z},
+		}
+	})
+}
+
+func (l *line2D) EvaluateInterval(boxes []ms2.Box, lo, hi []float32, userData any) error {
+	return evaluateIntervalLipschitz2(l, boxes, lo, hi, userData)
+}
+
+func (a *arc2D) EvaluateInterval(boxes []ms2.Box, lo, hi []float32, userData any) error {
+	return evaluateIntervalLipschitz2(a, boxes, lo, hi, userData)
+}
+
+// boxDist2 returns the distance between the closest points of boxes a and b, zero if they overlap.
+func boxDist2(a, b ms2.Box) float32 {
+	gap := ms2.MaxElem(ms2.Sub(a.Min, b.Max), ms2.Sub(b.Min, a.Max))
+	return ms2.Norm(ms2.MaxElem(gap, ms2.Vec{}))
+}
+
+// boxFarDist2 returns the distance between the farthest points of boxes a and b.
+func boxFarDist2(a, b ms2.Box) float32 {
+	return ms2.Norm(ms2.MaxElem(ms2.AbsElem(ms2.Sub(a.Max, b.Min)), ms2.AbsElem(ms2.Sub(b.Max, a.Min))))
+}
+
+// EvaluateInterval implements [gleval.SDF2Interval]. The bezier distance is calculated numerically
+// and is not exact so the distance range is bounded by the distance to the control point bounds,
+// which contain the curve.
+func (bz *quadbezier2d) EvaluateInterval(boxes []ms2.Box, lo, hi []float32, userData any) error {
+	thick := bz.thick / 2
+	hull := ms2.Box{Min: bz.a, Max: bz.a}.IncludePoint(bz.b).IncludePoint(bz.c)
+	for i, b := range boxes {
+		lo[i] = boxDist2(b, hull) - thick
+		hi[i] = boxFarDist2(b, hull) - thick
+	}
+	return nil
+}
+
+// distRange returns the range of the unsigned distance over box calculated by distSq.
+func (s *pathSeg) distRange(box ms2.Box) interval {
+	center := box.Center()
+	r := 0.5 * box.Diagonal()
+	switch s.kind {
+	case pathLine, pathArc:
+		// Exact distances.
+		d := math32.Sqrt(s.distSq(center))
+		return interval{lo: maxf(d-r, 0), hi: d + r}
+	case pathCubic:
+		// Numerical distance is never larger than the distance to the sampled curve points.
+		d2 := float32(largenum)
+		for i := 0; i <= 16; i++ {
+			d2 = minf(d2, ms2.Norm2(ms2.Sub(center, s.at(float32(i)/16))))
+		}
+		return interval{lo: boxDist2(box, s.bounds()), hi: math32.Sqrt(d2) + r}
+	}
+	hull := s.bounds()
+	return interval{lo: boxDist2(box, hull), hi: boxFarDist2(box, hull)}
+}
+
+// EvaluateInterval implements [gleval.SDF2Interval]. The distance sign may only change
+// across the path so it is constant over boxes which do not touch the path.
+func (c *path2D) EvaluateInterval(boxes []ms2.Box, lo, hi []float32, userData any) error {
+	for i, b := range boxes {
+		d := interval{lo: largenum, hi: largenum}
+		for j := range c.segs {
+			d = d.min(c.segs[j].distRange(b))
+		}
+		if d.lo <= 0 {
+			lo[i], hi[i] = -d.hi, d.hi
+			continue
+		}
+		center := b.Center()
+		winding := 0
+		for j := range c.segs {
+			winding += c.segs[j].winding(center)
+		}
+		if winding != 0 {
+			d = d.neg()
+		}
+		lo[i], hi[i] = d.lo, d.hi
+	}
+	return nil
+}
+
+func (c *circle2D) EvaluateInterval(boxes []ms2.Box, lo, hi []float32, userData any) error {
+	r := c.r
+	for i, b := range boxes {
+		x, y := boxIntervals2(b)
+		d := x.hypot(y).addf(-r)
+		lo[i], hi[i] = d.lo, d.hi
+	}
+	return nil
+}
+
+func (t *equilateralTri2d) EvaluateInterval(boxes []ms2.Box, lo, hi []float32, userData any) error {
+	return evaluateIntervalLipschitz2(t, boxes, lo, hi, userData)
+}
+
+func (c *rect2D) EvaluateInterval(boxes []ms2.Box, lo, hi []float32, userData any) error {
+	b := ms2.Scale(0.5, c.d)
+	// Distance is non-decreasing with respect to d so its range is found at the limits of d.
+	dist := func(d ms2.Vec) float32 {
+		return ms2.Norm(ms2.MaxElem(d, ms2.Vec{})) + math32.Min(0, math32.Max(d.X, d.Y))
+	}
+	for i, bb := range boxes {
+		ab := absBox2(bb)
+		lo[i] = dist(ms2.Sub(ab.Min, b))
+		hi[i] = dist(ms2.Sub(ab.Max, b))
+	}
+	return nil
+}
+
+func (c *diamond) EvaluateInterval(boxes []ms2.Box, lo, hi []float32, userData any) error {
+	return evaluateIntervalLipschitz2(c, boxes, lo, hi, userData)
+}
+
+func (c *x2d) EvaluateInterval(boxes []ms2.Box, lo, hi []float32, userData any) error {
+	return evaluateIntervalLipschitz2(c, boxes, lo, hi, userData)
+}
+
+func (c *hex2D) EvaluateInterval(boxes []ms2.Box, lo, hi []float32, userData any) error {
+	return evaluateIntervalLipschitz2(c, boxes, lo, hi, userData)
+}
+
+func (c *oct2D) EvaluateInterval(boxes []ms2.Box, lo, hi []float32, userData any) error {
+	return evaluateIntervalLipschitz2(c, boxes, lo, hi, userData)
+}
+
+func (c *ellipse2D) EvaluateInterval(boxes []ms2.Box, lo, hi []float32, userData any) error {
+	return evaluateIntervalLipschitz2(c, boxes, lo, hi, userData)
+}
+
+func (p *poly2D) EvaluateInterval(boxes []ms2.Box, lo, hi []float32, userData any) error {
+	return evaluateIntervalLipschitz2(p, boxes, lo, hi, userData)
+}
+
+func (c *polySet) EvaluateInterval(boxes []ms2.Box, lo, hi []float32, userData any) error {
+	return evaluateIntervalLipschitz2(c, boxes, lo, hi, userData)
+}
+
+func (l *lines2D) EvaluateInterval(boxes []ms2.Box, lo, hi []float32, userData any) error {
+	return evaluateIntervalLipschitz2(l, boxes, lo, hi, userData)
+}
+
+// EvaluateInterval implements [gleval.SDF2Interval].
+func (u *OpUnion2D) EvaluateInterval(boxes []ms2.Box, lo, hi []float32, userData any) error {
+	u.mustValidate()
+	vp, err := gleval.GetVecPool(userData)
+	if err != nil {
+		return err
+	}
+	auxLo := vp.Float.Acquire(len(lo))
+	auxHi := vp.Float.Acquire(len(hi))
+	defer vp.Float.Release(auxLo)
+	defer vp.Float.Release(auxHi)
+	err = evaluateInterval2(u.joined[0], boxes, lo, hi, userData)
+	if err != nil {
+		return err
+	}
+	for _, shape := range u.joined[1:] {
+		err = evaluateInterval2(shape, boxes, auxLo, auxHi, userData)
+		if err != nil {
+			return err
+		}
+		minReduce(lo, auxLo)
+		minReduce(hi, auxHi)
+	}
+	return nil
+}
+
+func (u *intersect2D) EvaluateInterval(boxes []ms2.Box, lo, hi []float32, userData any) error {
+	return evaluateIntervalBinary2(u.s1, u.s2, boxes, lo, hi, userData, intersectInterval)
+}
+
+func (u *diff2D) EvaluateInterval(boxes []ms2.Box, lo, hi []float32, userData any) error {
+	return evaluateIntervalBinary2(u.s1, u.s2, boxes, lo, hi, userData, diffInterval)
+}
+
+func (u *xor2D) EvaluateInterval(boxes []ms2.Box, lo, hi []float32, userData any) error {
+	return evaluateIntervalBinary2(u.s1, u.s2, boxes, lo, hi, userData, xorInterval)
+}
+
+// EvaluateInterval implements [gleval.SDF2Interval]. See [array.EvaluateInterval] for commentary.
+func (a *array2D) EvaluateInterval(boxes []ms2.Box, lo, hi []float32, userData any) error {
+	vp, err := gleval.GetVecPool(userData)
+	if err != nil {
+		return err
+	}
+	translated := vp.Box2.Acquire(len(boxes))
+	defer vp.Box2.Release(translated)
+	idmin := vp.V2.Acquire(len(boxes))
+	idmax := vp.V2.Acquire(len(boxes))
+	defer vp.V2.Release(idmin)
+	defer vp.V2.Release(idmax)
+	auxLo := vp.Float.Acquire(len(lo))
+	auxHi := vp.Float.Acquire(len(hi))
+	defer vp.Float.Release(auxLo)
+	defer vp.Float.Release(auxHi)
+	s := a.d
+	n := ms2.AddScalar(-1, a.nvec2())
+	minlim := ms2.Vec{}
+	var span ms2.Vec
+	for i, b := range boxes {
+		idmin[i] = ms2.ClampElem(ms2.AddScalar(-1, ms2.RoundElem(ms2.DivElem(b.Min, s))), minlim, n)
+		idmax[i] = ms2.ClampElem(ms2.AddScalar(1, ms2.RoundElem(ms2.DivElem(b.Max, s))), minlim, n)
+		span = ms2.MaxElem(span, ms2.Sub(idmax[i], idmin[i]))
+		lo[i] = largenum
+		hi[i] = -largenum
+	}
+	var ij ms2.Vec
+	for j := float32(0.); j <= span.Y; j++ {
+		ij.Y = j
+		for i := float32(0.); i <= span.X; i++ {
+			ij.X = i
+			for ib, b := range boxes {
+				rid := ms2.MinElem(ms2.Add(idmin[ib], ij), idmax[ib])
+				translated[ib] = b.Add(ms2.Scale(-1, ms2.MulElem(s, rid)))
+			}
+			err = evaluateInterval2(a.s, translated, auxLo, auxHi, userData)
+			if err != nil {
+				return err
+			}
+			for ib, b := range boxes {
+				rid := ms2.Add(idmin[ib], ij)
+				if rid != ms2.MinElem(rid, idmax[ib]) {
+					continue // Instance outside of box neighborhood.
+				}
+				lo[ib] = minf(lo[ib], auxLo[ib])
+				closestMin := ms2.ClampElem(ms2.RoundElem(ms2.DivElem(b.Min, s)), minlim, n)
+				closestMax := ms2.ClampElem(ms2.RoundElem(ms2.DivElem(b.Max, s)), minlim, n)
+				if rid == ms2.ClampElem(rid, closestMin, closestMax) {
+					hi[ib] = maxf(hi[ib], auxHi[ib])
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (r *offset2D) EvaluateInterval(boxes []ms2.Box, lo, hi []float32, userData any) error {
+	err := evaluateInterval2(r.s, boxes, lo, hi, userData)
+	if err != nil {
+		return err
+	}
+	radius := r.f
+	for i := range lo {
+		lo[i] += radius
+		hi[i] += radius
+	}
+	return nil
+}
+
+func (t *translate2D) EvaluateInterval(boxes []ms2.Box, lo, hi []float32, userData any) error {
+	T := ms2.Scale(-1, t.p)
+	return evaluateIntervalTransformed2(t.s, boxes, lo, hi, userData, func(b ms2.Box) ms2.Box {
+		return b.Add(T)
+	})
+}
+
+func (s *symmetry2D) EvaluateInterval(boxes []ms2.Box, lo, hi []float32, userData any) error {
+	xb, yb := s.xy.X(), s.xy.Y()
+	return evaluateIntervalTransformed2(s.s, boxes, lo, hi, userData, func(b ms2.Box) ms2.Box {
+		ab := absBox2(b)
+		if xb {
+			b.Min.X, b.Max.X = ab.Min.X, ab.Max.X
+		}
+		if yb {
+			b.Min.Y, b.Max.Y = ab.Min.Y, ab.Max.Y
+		}
+		return b
+	})
+}
+
+func (s *annulus2D) EvaluateInterval(boxes []ms2.Box, lo, hi []float32, userData any) error {
+	err := evaluateInterval2(s.s, boxes, lo, hi, userData)
+	if err != nil {
+		return err
+	}
+	r := s.r
+	for i := range lo {
+		d := interval{lo: lo[i], hi: hi[i]}.abs().addf(-r)
+		lo[i], hi[i] = d.lo, d.hi
+	}
+	return nil
+}
+
+// EvaluateInterval implements [gleval.SDF2Interval]. See [circarray.EvaluateInterval] for commentary.
+func (c *circarray2D) EvaluateInterval(boxes []ms2.Box, lo, hi []float32, userData any) error {
+	vp, err := gleval.GetVecPool(userData)
+	if err != nil {
+		return err
+	}
+	rotated := vp.Box2.Acquire(len(boxes))
+	defer vp.Box2.Release(rotated)
+	auxLo := vp.Float.Acquire(len(lo))
+	auxHi := vp.Float.Acquire(len(hi))
+	defer vp.Float.Release(auxLo)
+	defer vp.Float.Release(auxHi)
+	angle := 2 * math32.Pi / float32(c.circleDiv)
+	for i := range lo {
+		lo[i] = largenum
+		hi[i] = -largenum
+	}
+	for inst := 0; inst < c.nInst; inst++ {
+		rot := ms2.RotationMat2(angle * float32(inst)).Transpose()
+		for i, b := range boxes {
+			rotated[i] = mulBox2(rot, b)
+		}
+		err = evaluateInterval2(c.s, rotated, auxLo, auxHi, userData)
+		if err != nil {
+			return err
+		}
+		for i := range lo {
+			lo[i] = minf(lo[i], auxLo[i])
+			hi[i] = maxf(hi[i], auxHi[i])
+		}
+	}
+	return nil
+}
+
+func (c *translateMulti2D) EvaluateInterval(boxes []ms2.Box, lo, hi []float32, userData any) error {
+	vp, err := gleval.GetVecPool(userData)
+	if err != nil {
+		return err
+	}
+	auxLo := vp.Float.Acquire(len(lo))
+	auxHi := vp.Float.Acquire(len(hi))
+	defer vp.Float.Release(auxLo)
+	defer vp.Float.Release(auxHi)
+	for i := range lo {
+		lo[i] = largenum
+		hi[i] = largenum
+	}
+	for _, p := range c.displacements {
+		t2d := translate2D{
+			s: c.s,
+			p: p,
+		}
+		err = t2d.EvaluateInterval(boxes, auxLo, auxHi, userData)
+		if err != nil {
+			return err
+		}
+		minReduce(lo, auxLo)
+		minReduce(hi, auxHi)
+	}
+	return nil
+}
+
+func (c *rotation2D) EvaluateInterval(boxes []ms2.Box, lo, hi []float32, userData any) error {
+	invT := c.tInv
+	return evaluateIntervalTransformed2(c.s, boxes, lo, hi, userData, func(b ms2.Box) ms2.Box {
+		return mulBox2(invT, b)
+	})
+}
+
+func (c *scale2D) EvaluateInterval(boxes []ms2.Box, lo, hi []float32, userData any) error {
+	scale := c.scale
+	invScale := 1. / c.scale
+	scaleInv := ms2.Vec{X: invScale, Y: invScale}
+	err := evaluateIntervalTransformed2(c.s, boxes, lo, hi, userData, func(b ms2.Box) ms2.Box {
+		return b.Scale(scaleInv)
+	})
+	if err != nil {
+		return err
+	}
+	for i := range lo {
+		d := interval{lo: lo[i], hi: hi[i]}.scale(scale)
+		lo[i], hi[i] = d.lo, d.hi
+	}
+	return nil
+}
+
+func (e *elongate2D) EvaluateInterval(boxes []ms2.Box, lo, hi []float32, userData any) error {
+	vp, err := gleval.GetVecPool(userData)
+	if err != nil {
+		return err
+	}
+	transformed := vp.Box2.Acquire(len(boxes))
+	defer vp.Box2.Release(transformed)
+	auxLo := vp.Float.Acquire(len(lo))
+	auxHi := vp.Float.Acquire(len(hi))
+	defer vp.Float.Release(auxLo)
+	defer vp.Float.Release(auxHi)
+	h := ms2.Scale(0.5, e.h)
+	for i, b := range boxes {
+		ab := absBox2(b)
+		qlo := ms2.Sub(ab.Min, h)
+		qhi := ms2.Sub(ab.Max, h)
+		auxLo[i] = math32.Min(qlo.Max(), 0)
+		auxHi[i] = math32.Min(qhi.Max(), 0)
+		transformed[i] = ms2.Box{Min: ms2.MaxElem(qlo, ms2.Vec{}), Max: ms2.MaxElem(qhi, ms2.Vec{})}
+	}
+	err = evaluateInterval2(e.s, transformed, lo, hi, userData)
+	if err != nil {
+		return err
+	}
+	for i := range lo {
+		lo[i] += auxLo[i]
+		hi[i] += auxHi[i]
+	}
+	return nil
+}
+
+// EvaluateInterval implements [gleval.SDF3Interval]. Interpolated distances within the bounds are
+// bounded by the range of samples which contribute to them; cubic interpolation weights may be negative
+// so the range is widened accordingly. Distances outside the bounds increase with the distance to the
+// bounds and with the interpolated distance so the range ends are evaluated at the closest and farthest points.
+func (g *gridSDF3) EvaluateInterval(boxes []ms3.Box, lo, hi []float32, userData any) error {
+	bb := g.bb
+	scale := ms3.DivElem(ms3.Vec{X: float32(g.nx - 1), Y: float32(g.ny - 1), Z: float32(g.nz - 1)}, bb.Size())
+	for i, b := range boxes {
+		cmin := ms3.MulElem(ms3.Sub(ms3.ClampElem(b.Min, bb.Min, bb.Max), bb.Min), scale)
+		cmax := ms3.MulElem(ms3.Sub(ms3.ClampElem(b.Max, bb.Min, bb.Max), bb.Min), scale)
+		x0, x1 := gridSampleRange(g.interp, cmin.X, cmax.X, g.nx)
+		y0, y1 := gridSampleRange(g.interp, cmin.Y, cmax.Y, g.ny)
+		z0, z1 := gridSampleRange(g.interp, cmin.Z, cmax.Z, g.nz)
+		d := interval{lo: largenum, hi: -largenum}
+		for k := z0; k <= z1; k++ {
+			for j := y0; j <= y1; j++ {
+				row := g.data[g.nx*(j+g.ny*k):]
+				for ii := x0; ii <= x1; ii++ {
+					d.lo = math32.Min(d.lo, row[ii])
+					d.hi = math32.Max(d.hi, row[ii])
+				}
+			}
+		}
+		d = g.interp.widen(d, 3)
+		outLo := ms3.MaxElem(ms3.MaxElem(ms3.Sub(bb.Min, b.Max), ms3.Sub(b.Min, bb.Max)), ms3.Vec{})
+		outHi := ms3.MaxElem(ms3.MaxElem(ms3.Sub(bb.Min, b.Min), ms3.Sub(b.Max, bb.Max)), ms3.Vec{})
+		lo[i] = gridOutside(d.lo, ms3.Norm(outLo))
+		hi[i] = gridOutside(d.hi, ms3.Norm(outHi))
+	}
+	return nil
+}
+
+// EvaluateInterval implements [gleval.SDF2Interval]. See [gridSDF3.EvaluateInterval].
+func (g *gridSDF2) EvaluateInterval(boxes []ms2.Box, lo, hi []float32, userData any) error {
+	bb := g.bb
+	scale := ms2.DivElem(ms2.Vec{X: float32(g.nx - 1), Y: float32(g.ny - 1)}, bb.Size())
+	for i, b := range boxes {
+		cmin := ms2.MulElem(ms2.Sub(ms2.ClampElem(b.Min, bb.Min, bb.Max), bb.Min), scale)
+		cmax := ms2.MulElem(ms2.Sub(ms2.ClampElem(b.Max, bb.Min, bb.Max), bb.Min), scale)
+		x0, x1 := gridSampleRange(g.interp, cmin.X, cmax.X, g.nx)
+		y0, y1 := gridSampleRange(g.interp, cmin.Y, cmax.Y, g.ny)
+		d := interval{lo: largenum, hi: -largenum}
+		for j := y0; j <= y1; j++ {
+			row := g.data[g.nx*j:]
+			for ii := x0; ii <= x1; ii++ {
+				d.lo = math32.Min(d.lo, row[ii])
+				d.hi = math32.Max(d.hi, row[ii])
+			}
+		}
+		d = g.interp.widen(d, 2)
+		outLo := ms2.MaxElem(ms2.MaxElem(ms2.Sub(bb.Min, b.Max), ms2.Sub(b.Min, bb.Max)), ms2.Vec{})
+		outHi := ms2.MaxElem(ms2.MaxElem(ms2.Sub(bb.Min, b.Min), ms2.Sub(b.Max, bb.Max)), ms2.Vec{})
+		lo[i] = gridOutside(d.lo, ms2.Norm(outLo))
+		hi[i] = gridOutside(d.hi, ms2.Norm(outHi))
+	}
+	return nil
+}
+
+// EvaluateInterval implements [gleval.SDF3Interval]. The heightmap distance is Lipschitz corrected.
+func (hm *heightmap) EvaluateInterval(boxes []ms3.Box, lo, hi []float32, userData any) error {
+	return evaluateIntervalLipschitz3(hm, boxes, lo, hi, userData)
+}
+
+// gridSampleRange returns the range of sample indices along an axis of n samples which contribute
+// to interpolated distances between grid coordinates cmin and cmax.
+func gridSampleRange(interp GridInterpolation, cmin, cmax float32, n int) (first, last int) {
+	first, _ = gridCell(cmin, n)
+	last, _ = gridCell(cmax, n)
+	last++
+	if interp == GridCubic {
+		first = max(first-1, 0)
+		last = min(last+1, n-1)
+	}
+	return first, last
+}
+
+// widen widens the range of samples d to contain the distances interpolated from them in dims dimensions.
+func (interp GridInterpolation) widen(d interval, dims int) interval {
+	if interp != GridCubic {
+		return d // Linear interpolation is a convex combination of samples.
+	}
+	// Catmull-Rom weights sum to 1 and their absolute values sum to at most 1.25 along each axis.
+	// The positive weights of the tensor product then sum to at most (1.25^dims+1)/2.
+	absSum := math32.Pow(1.25, float32(dims))
+	pos := (absSum + 1) / 2
+	span := d.hi - d.lo
+	return interval{lo: d.hi - pos*span, hi: d.lo + pos*span}
+}
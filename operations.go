@@ -1,8 +1,12 @@
 package gsdf
 
 import (
+	"cmp"
 	"fmt"
+	"reflect"
+	"slices"
 	"strconv"
+	"sync/atomic"
 
 	"github.com/chewxy/math32"
 	"github.com/soypat/geometry/ms2"
@@ -28,6 +32,11 @@ type OpUnion struct {
 	// joined contains 2 or more 3D SDFs.
 	// OpUnion methods will panic if joined less than 2 elements.
 	joined []glbuild.Shader3D
+	// cullDist is the distance above which CPU evaluation returns conservative distances. Zero means no limit.
+	cullDist float32
+	// bvh caches the bounding volume hierarchy over joined used to cull far away shapes during CPU evaluation.
+	// It is built on first evaluation and discarded when children are visited, which is how descendants are replaced.
+	bvh atomic.Pointer[[]unionBVHNode]
 }
 
 // Union joins the shapes of several 3D SDFs into one. Is exact.
@@ -65,6 +74,8 @@ func (u *OpUnion) Bounds() ms3.Box {
 // ForEachChild implements [glbuild.Shader3D].
 func (u *OpUnion) ForEachChild(userData any, fn func(userData any, s *glbuild.Shader3D) error) error {
 	u.mustValidate()
+	// fn may replace children or their descendants which changes their bounds.
+	defer u.bvh.Store(nil)
 	for i := range u.joined {
 		err := fn(userData, &u.joined[i])
 		if err != nil {
@@ -74,6 +85,80 @@ func (u *OpUnion) ForEachChild(userData any, fn func(userData any, s *glbuild.Sh
 	return nil
 }
 
+// SetCullDistance sets the distance above which CPU evaluation of unions with many shapes
+// may return a conservative distance, that is a distance which is smaller than the actual distance but
+// never smaller than cullDist. Distances below cullDist are exact. Renderers which only need
+// exact distances near the surface can set it to speed up evaluation of large unions.
+// A non-positive cullDist disables conservative distances, which is the default.
+//
+// Culling assumes the distance of each joined shape is not smaller than the distance to its bounding box.
+func (u *OpUnion) SetCullDistance(cullDist float32) {
+	u.cullDist = max(cullDist, 0)
+}
+
+// unionBVHMinShapes is the minimum number of shapes in a union for which a bounding volume hierarchy is built.
+const unionBVHMinShapes = 8
+
+// unionBVHNode is a node of the bounding volume hierarchy of a union. Nodes are stored
+// in depth-first order so that the left child of an internal node is the next node.
+type unionBVHNode struct {
+	box ms3.Box
+	// shape is the index of the joined shape of a leaf node or -1 for internal nodes.
+	shape int32
+	// right is the index of the right child of an internal node.
+	right int32
+}
+
+// cachedBVH returns the bounding volume hierarchy over the joined shapes, building it if not cached.
+func (u *OpUnion) cachedBVH() []unionBVHNode {
+	if bvh := u.bvh.Load(); bvh != nil {
+		return *bvh
+	}
+	bvh := u.appendBVH(nil)
+	u.bvh.Store(&bvh)
+	return bvh
+}
+
+// appendBVH appends the bounding volume hierarchy over the joined shapes to dst.
+func (u *OpUnion) appendBVH(dst []unionBVHNode) []unionBVHNode {
+	shapes := make([]int32, len(u.joined))
+	boxes := make([]ms3.Box, len(u.joined))
+	for i := range u.joined {
+		shapes[i] = int32(i)
+		boxes[i] = u.joined[i].Bounds()
+	}
+	return appendBVHNodes(dst, shapes, boxes)
+}
+
+// appendBVHNodes appends the hierarchy over shapes to dst, splitting shapes at the median
+// of box centers along the longest axis of the box containing the centers.
+func appendBVHNodes(dst []unionBVHNode, shapes []int32, boxes []ms3.Box) []unionBVHNode {
+	if len(shapes) == 1 {
+		return append(dst, unionBVHNode{box: boxes[shapes[0]], shape: shapes[0], right: -1})
+	}
+	bb := boxes[shapes[0]]
+	centers := ms3.Box{Min: bb.Center(), Max: bb.Center()}
+	for _, shape := range shapes[1:] {
+		bb = bb.Union(boxes[shape])
+		centers = centers.IncludePoint(boxes[shape].Center())
+	}
+	axis := 0
+	size := centers.Size()
+	if size.Y > size.X && size.Y >= size.Z {
+		axis = 1
+	} else if size.Z > size.X && size.Z > size.Y {
+		axis = 2
+	}
+	slices.SortFunc(shapes, func(a, b int32) int {
+		return cmp.Compare(boxes[a].Center().Array()[axis], boxes[b].Center().Array()[axis])
+	})
+	parent := len(dst)
+	dst = append(dst, unionBVHNode{box: bb, shape: -1})
+	dst = appendBVHNodes(dst, shapes[:len(shapes)/2], boxes)
+	dst[parent].right = int32(len(dst))
+	return appendBVHNodes(dst, shapes[len(shapes)/2:], boxes)
+}
+
 // AppendShaderName implements [glbuild.Shader].
 func (u *OpUnion) AppendShaderName(b []byte) []byte {
 	u.mustValidate()
@@ -343,7 +428,9 @@ func (bld *Builder) Transform(s glbuild.Shader3D, m ms3.Mat4) glbuild.Shader3D {
 		bld.shapeErrorf("singular Mat4")
 	}
 	t := &transform{s: s, t: m, tInv: m.Inverse()}
-	t.hash = hashshaderptr(t) // TODO(soypat): fix TestTransformDuplicateBug so that hashing input shader works.
+	if s != nil {
+		t.hashed, t.hash = s, hashShaderName(s)
+	}
 	return t
 }
 
@@ -356,7 +443,11 @@ type transform struct {
 	// The SDF receives points which we must evaluate in
 	// transformed coordinates, so we must work backwards, thus inverse.
 	tInv ms3.Mat4
-	hash uint64
+	// hash is the hash of the name of hashed, the child at construction. It is recomputed on
+	// every call to AppendShaderName if the child was replaced. Hashing the child name once prevents
+	// nested transforms from computing the names of all their descendants each time they are named.
+	hashed glbuild.Shader3D
+	hash   uint64
 }
 
 func (t *transform) Bounds() ms3.Box {
@@ -374,10 +465,25 @@ func (t *transform) AppendShaderName(b []byte) []byte {
 	values := t.t.Array()
 	b = glbuild.AppendFloat(b, 'n', 'p', hashf(values[:]))
 	b = append(b, '_')
-	b = strconv.AppendUint(b, t.hash, 32)
+	// Hash input shader name so that transforms of different shaders have different names.
+	h := t.hash
+	if !sameShader(t.hashed, t.s) {
+		h = hashShaderName(t.s)
+	}
+	b = strconv.AppendUint(b, h, 32)
 	return b
 }
 
+func hashShaderName(s glbuild.Shader) uint64 {
+	return hash(s.AppendShaderName(nil), 0xbf58476d1ce4e5b9)
+}
+
+// sameShader reports whether a and b are the same shader without panicking on non-comparable types.
+func sameShader(a, b glbuild.Shader3D) bool {
+	ta := reflect.TypeOf(a)
+	return ta == reflect.TypeOf(b) && ta.Comparable() && a == b
+}
+
 func (t *transform) AppendShaderBody(b []byte) []byte {
 	b = glbuild.AppendMat4Decl(b, "invT", t.tInv)
 	b = append(b, "return "...)
@@ -573,7 +679,10 @@ type smoothUnion struct {
 }
 
 func (s *smoothUnion) Bounds() ms3.Box {
-	return s.s1.Bounds().Union(s.s2.Bounds())
+	// Smooth minimum lowers distances by at most k/4 so the shape may grow past its children's bounds.
+	bb := s.s1.Bounds().Union(s.s2.Bounds())
+	pad := ms3.Vec{X: s.k / 4, Y: s.k / 4, Z: s.k / 4}
+	return ms3.Box{Min: ms3.Sub(bb.Min, pad), Max: ms3.Add(bb.Max, pad)}
 }
 
 func (s *smoothUnion) ForEachChild(userData any, fn func(any, *glbuild.Shader3D) error) error {
@@ -167,10 +167,10 @@ type revolution struct {
 
 func (r *revolution) Bounds() ms3.Box {
 	b2 := r.s2d.Bounds()
-	radius := math32.Max(0, b2.Max.X-r.off)
+	radius := math32.Max(0, b2.Max.X+r.off)
 	return ms3.Box{
 		Min: ms3.Vec{X: -radius, Y: b2.Min.Y, Z: -radius},
-		Max: ms3.Vec{X: radius, Y: b2.Max.Y, Z: radius}, // TODO
+		Max: ms3.Vec{X: radius, Y: b2.Max.Y, Z: radius},
 	}
 }
 
@@ -0,0 +1,310 @@
+package gsdf
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/soypat/geometry/ms3"
+	"github.com/soypat/gsdf/glbuild"
+)
+
+// Optimize3D returns a shape equivalent to s with a simplified shape tree. The following rewrites are applied:
+//   - Consecutive Translate and Transform operations are fused into a single operation.
+//   - Nested Union and Intersection operations are flattened and repeated operands removed.
+//   - Consecutive Offset, Scale, Translate2D and Rotate2D operations are merged.
+//   - Operations with no effect such as zero offsets, unit scales and identity transforms are removed.
+//   - Structurally identical subtrees are built once and shared so that their shader code is generated once.
+//
+// Distances of the result match those of s up to floating point rounding. The tree of s is not modified;
+// the optimized tree is built with bld. Nodes which do not implement [glbuild.Describer] or whose
+// constructor is not registered with [RegisterShape] are kept as they are.
+func (bld *Builder) Optimize3D(s glbuild.Shader3D) (glbuild.Shader3D, error) {
+	r, err := bld.optimize(s)
+	if err != nil {
+		return nil, err
+	}
+	return r.(glbuild.Shader3D), nil
+}
+
+// Optimize2D returns a 2D shape equivalent to s with a simplified shape tree. See [Builder.Optimize3D].
+func (bld *Builder) Optimize2D(s glbuild.Shader2D) (glbuild.Shader2D, error) {
+	r, err := bld.optimize(s)
+	if err != nil {
+		return nil, err
+	}
+	return r.(glbuild.Shader2D), nil
+}
+
+func (bld *Builder) optimize(s glbuild.Shader) (r glbuild.Shader, err error) {
+	if s == nil {
+		return nil, errors.New("nil shape")
+	}
+	o := optimizer{
+		bld:   bld,
+		done:  make(map[glbuild.Shader]glbuild.Shader),
+		built: make(map[string]glbuild.Shader),
+		ids:   make(map[glbuild.Shader]int),
+	}
+	defer func() {
+		if a := recover(); a != nil {
+			r = nil
+			err = fmt.Errorf("building optimized shape: %v", a)
+		}
+	}()
+	r, err = o.optimize(s)
+	if err != nil {
+		return nil, err
+	}
+	return r, bld.Err()
+}
+
+type optimizer struct {
+	bld *Builder
+	// done maps visited nodes of the original tree to their optimized counterpart.
+	done map[glbuild.Shader]glbuild.Shader
+	// built maps the structural key of optimized nodes to the node.
+	built map[string]glbuild.Shader
+	// ids identifies optimized nodes in structural keys.
+	ids map[glbuild.Shader]int
+	key []byte
+}
+
+func (o *optimizer) optimize(s glbuild.Shader) (glbuild.Shader, error) {
+	if r, ok := o.done[s]; ok {
+		return r, nil
+	}
+	fn, params := glbuild.Describe(s)
+	if _, ok := shapeRegistry[fn]; !ok {
+		o.done[s] = s // Opaque node, keep as is.
+		return s, nil
+	}
+	params = slices.Clone(params)
+	var err error
+	for i := range params {
+		switch v := params[i].Value.(type) {
+		case glbuild.Shader:
+			params[i].Value, err = o.optimize(v)
+		case []glbuild.Shader3D:
+			params[i].Value, err = optimizeAll(o, v)
+		case []glbuild.Shader2D:
+			params[i].Value, err = optimizeAll(o, v)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	r, err := o.simplify(fn, params)
+	if err != nil {
+		return nil, err
+	}
+	o.done[s] = r
+	return r, nil
+}
+
+func optimizeAll[T glbuild.Shader](o *optimizer, shapes []T) ([]T, error) {
+	optimized := make([]T, len(shapes))
+	for i, s := range shapes {
+		r, err := o.optimize(s)
+		if err != nil {
+			return nil, err
+		}
+		optimized[i] = r.(T)
+	}
+	return optimized, nil
+}
+
+// simplify builds the node described by fn and params, whose children are already optimized,
+// applying the rewrite rules of the node's kind. Since children are optimized they are not
+// themselves simplifiable, i.e: the child of a Translate is never a Translate.
+func (o *optimizer) simplify(fn string, p []glbuild.Param) (glbuild.Shader, error) {
+	if len(p) == 0 {
+		return o.build(fn, p)
+	}
+	child, _ := p[0].Value.(glbuild.Shader)
+	cfn, cp := glbuild.Describe(child)
+	switch fn {
+	case "Translate", "Transform":
+		m := affineMatrix(fn, p)
+		if cfn == "Translate" || cfn == "Transform" {
+			m = ms3.MulMat4(m, affineMatrix(cfn, cp))
+			child = cp[0].Value.(glbuild.Shader)
+		}
+		a := m.Array()
+		isTranslation := a[0] == 1 && a[1] == 0 && a[2] == 0 && a[4] == 0 && a[5] == 1 && a[6] == 0 && a[8] == 0 && a[9] == 0 && a[10] == 1
+		if !isTranslation {
+			return o.build("Transform", params("s", child, "m", m))
+		} else if a[3] == 0 && a[7] == 0 && a[11] == 0 {
+			return child, nil
+		}
+		return o.build("Translate", params("s", child, "dirX", a[3], "dirY", a[7], "dirZ", a[11]))
+
+	case "Offset", "Offset2D", "Translate2D", "Rotate2D":
+		// Operations which are merged by adding their parameters.
+		if cfn == fn {
+			for i := 1; i < len(p); i++ {
+				p[i].Value = p[i].Value.(float32) + cp[i].Value.(float32)
+			}
+			child = cp[0].Value.(glbuild.Shader)
+			p[0].Value = child
+		}
+		if isZeroParams(p[1:]) {
+			return child, nil
+		}
+
+	case "Scale", "Scale2D":
+		if cfn == fn {
+			p[1].Value = p[1].Value.(float32) * cp[1].Value.(float32)
+			child = cp[0].Value.(glbuild.Shader)
+			p[0].Value = child
+		}
+		if p[1].Value.(float32) == 1 {
+			return child, nil
+		}
+
+	case "Elongate", "Elongate2D":
+		if isZeroParams(p[1:]) {
+			return child, nil
+		}
+
+	case "Union":
+		return o.union(fn, flattenUnion(fn, p[0].Value.([]glbuild.Shader3D)))
+	case "Union2D":
+		return o.union(fn, flattenUnion(fn, p[0].Value.([]glbuild.Shader2D)))
+
+	case "Intersection", "Intersection2D":
+		operands := appendIntersected(nil, fn, p[0].Value.(glbuild.Shader))
+		operands = appendIntersected(operands, fn, p[1].Value.(glbuild.Shader))
+		operands = dedup(operands)
+		result := operands[0]
+		for _, operand := range operands[1:] {
+			var err error
+			result, err = o.build(fn, params("a", result, "b", operand))
+			if err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	}
+	return o.build(fn, p)
+}
+
+func (o *optimizer) union(fn string, shapes []glbuild.Shader) (glbuild.Shader, error) {
+	shapes = dedup(shapes)
+	if len(shapes) == 1 {
+		return shapes[0], nil
+	}
+	if fn == "Union" {
+		return o.build(fn, params("shaders", convertShaders[glbuild.Shader3D](shapes)))
+	}
+	return o.build(fn, params("shaders", convertShaders[glbuild.Shader2D](shapes)))
+}
+
+// build returns the node built by constructor fn with params. Structurally identical nodes are built once.
+func (o *optimizer) build(fn string, p []glbuild.Param) (glbuild.Shader, error) {
+	key := append(o.key[:0], fn...)
+	for _, param := range p {
+		key = append(key, '|')
+		switch v := param.Value.(type) {
+		case glbuild.Shader:
+			key = o.appendID(key, v)
+		case []glbuild.Shader3D:
+			for _, s := range v {
+				key = o.appendID(key, s)
+			}
+		case []glbuild.Shader2D:
+			for _, s := range v {
+				key = o.appendID(key, s)
+			}
+		case *Path2D:
+			key = fmt.Appendf(key, "%v", v.segs)
+		default:
+			key = fmt.Appendf(key, "%v", v)
+		}
+	}
+	o.key = key
+	if s, ok := o.built[string(key)]; ok {
+		return s, nil
+	}
+	args := make([]reflect.Value, len(p)+1)
+	args[0] = reflect.ValueOf(o.bld)
+	for i := range p {
+		args[i+1] = reflect.ValueOf(p[i].Value)
+	}
+	s, err := shapeRegistry[fn].call(fn, args)
+	if err != nil {
+		return nil, err
+	}
+	o.built[string(key)] = s
+	return s, nil
+}
+
+func (o *optimizer) appendID(key []byte, s glbuild.Shader) []byte {
+	id, ok := o.ids[s]
+	if !ok {
+		id = len(o.ids)
+		o.ids[s] = id
+	}
+	return fmt.Appendf(key, "#%d,", id)
+}
+
+// affineMatrix returns the transformation matrix of a Translate or Transform node.
+func affineMatrix(fn string, p []glbuild.Param) ms3.Mat4 {
+	if fn == "Transform" {
+		return p[1].Value.(ms3.Mat4)
+	}
+	return ms3.TranslatingMat4(ms3.Vec{X: p[1].Value.(float32), Y: p[2].Value.(float32), Z: p[3].Value.(float32)})
+}
+
+func isZeroParams(p []glbuild.Param) bool {
+	for i := range p {
+		if p[i].Value.(float32) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// flattenUnion returns the operands of a union, expanding operands which are unions themselves.
+func flattenUnion[T glbuild.Shader](fn string, shapes []T) []glbuild.Shader {
+	var flat []glbuild.Shader
+	for _, s := range shapes {
+		sfn, sp := glbuild.Describe(s)
+		if sfn != fn {
+			flat = append(flat, s)
+			continue
+		}
+		flat = append(flat, flattenUnion(fn, sp[0].Value.([]T))...)
+	}
+	return flat
+}
+
+// appendIntersected appends the operands of s to dst if s is an intersection or s itself otherwise.
+func appendIntersected(dst []glbuild.Shader, fn string, s glbuild.Shader) []glbuild.Shader {
+	sfn, sp := glbuild.Describe(s)
+	if sfn != fn {
+		return append(dst, s)
+	}
+	dst = appendIntersected(dst, fn, sp[0].Value.(glbuild.Shader))
+	return appendIntersected(dst, fn, sp[1].Value.(glbuild.Shader))
+}
+
+// dedup removes repeated shapes while preserving order.
+func dedup(shapes []glbuild.Shader) []glbuild.Shader {
+	unique := shapes[:0]
+	for _, s := range shapes {
+		if !slices.Contains(unique, s) {
+			unique = append(unique, s)
+		}
+	}
+	return unique
+}
+
+func convertShaders[T glbuild.Shader](shapes []glbuild.Shader) []T {
+	converted := make([]T, len(shapes))
+	for i, s := range shapes {
+		converted[i] = s.(T)
+	}
+	return converted
+}
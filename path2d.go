@@ -0,0 +1,456 @@
+package gsdf
+
+import (
+	"errors"
+	"math"
+	"strconv"
+
+	"github.com/chewxy/math32"
+	"github.com/soypat/geometry/ms2"
+	"github.com/soypat/gsdf/glbuild"
+	"github.com/soypat/gsdf/glbuild/glsllib"
+)
+
+// Path2D records a sequence of lines, circular arcs and bezier curves which enclose a 2D area.
+// A Path2D is converted to a SDF with [Builder.NewPath2D]. The zero value is an empty path ready for use.
+//
+// A path is made up of one or more closed subpaths. A subpath starts with [Path2D.MoveTo] and is closed
+// either explicitly with [Path2D.Close] or implicitly when the next subpath starts or the path is built.
+// Drawing commands issued before the first MoveTo start at the origin.
+type Path2D struct {
+	segs  []pathSeg
+	start ms2.Vec // Start of current subpath.
+	cur   ms2.Vec // Current point.
+	err   error
+}
+
+// MoveTo closes the current subpath and starts a new one at (x,y).
+func (p *Path2D) MoveTo(x, y float32) {
+	p.Close()
+	p.start = ms2.Vec{X: x, Y: y}
+	p.cur = p.start
+}
+
+// LineTo adds a straight line from the current point to (x,y).
+func (p *Path2D) LineTo(x, y float32) {
+	end := ms2.Vec{X: x, Y: y}
+	p.addSeg(pathSeg{kind: pathLine, p: [4]ms2.Vec{p.cur, end}})
+}
+
+// QuadTo adds a quadratic bezier from the current point to (x,y) with control point (cx,cy).
+func (p *Path2D) QuadTo(cx, cy, x, y float32) {
+	ctrl, end := ms2.Vec{X: cx, Y: cy}, ms2.Vec{X: x, Y: y}
+	if isLinearBezierQ(p.cur, ctrl, end) {
+		// Quadratic bezier SDF is not defined for straight curves.
+		p.LineTo(x, y)
+		return
+	}
+	p.addSeg(pathSeg{kind: pathQuad, p: [4]ms2.Vec{p.cur, ctrl, end}})
+}
+
+// CubicTo adds a cubic bezier from the current point to (x,y) with control points (c1x,c1y) and (c2x,c2y).
+func (p *Path2D) CubicTo(c1x, c1y, c2x, c2y, x, y float32) {
+	c1, c2, end := ms2.Vec{X: c1x, Y: c1y}, ms2.Vec{X: c2x, Y: c2y}, ms2.Vec{X: x, Y: y}
+	p.addSeg(pathSeg{kind: pathCubic, p: [4]ms2.Vec{p.cur, c1, c2, end}})
+}
+
+// ArcTo adds a circular arc of given radius from the current point to (x,y). Of the four arcs which
+// satisfy these constraints largeArc selects the arc spanning more than 180 degrees and clockwise
+// the direction of travel, much like the SVG arc command. The radius must be at least half the distance
+// between the current point and (x,y).
+func (p *Path2D) ArcTo(x, y, radius float32, largeArc, clockwise bool) {
+	a, b := p.cur, ms2.Vec{X: x, Y: y}
+	if a == b {
+		return
+	}
+	chord := ms2.Sub(b, a)
+	halfLen := ms2.Norm(chord) / 2
+	if !(radius >= halfLen*(1-1e-6)) {
+		p.setErr(errors.New("arc radius too small to reach endpoint"))
+		return
+	}
+	h := math32.Sqrt(math32.Max(0, radius*radius-halfLen*halfLen))
+	normal := ms2.Scale(1/(2*halfLen), ms2.Vec{X: -chord.Y, Y: chord.X})
+	if largeArc != clockwise {
+		// Center lies to the right of the chord.
+		h = -h
+	}
+	center := ms2.Add(ms2.Scale(0.5, ms2.Add(a, b)), ms2.Scale(h, normal))
+	a0 := math32.Atan2(a.Y-center.Y, a.X-center.X)
+	da := math32.Atan2(b.Y-center.Y, b.X-center.X) - a0
+	if clockwise && da > 0 {
+		da -= 2 * math.Pi
+	} else if !clockwise && da < 0 {
+		da += 2 * math.Pi
+	}
+	p.addSeg(pathSeg{kind: pathArc, p: [4]ms2.Vec{a, b, center}, r: radius, a0: a0, da: da})
+}
+
+// Close closes the current subpath with a straight line to its start point if not already closed.
+func (p *Path2D) Close() {
+	if p.cur != p.start {
+		p.LineTo(p.start.X, p.start.Y)
+	}
+}
+
+// Reset clears the path so that it may be reused.
+func (p *Path2D) Reset() {
+	*p = Path2D{segs: p.segs[:0]}
+}
+
+func (p *Path2D) addSeg(seg pathSeg) {
+	end := seg.end()
+	if seg.kind == pathLine && seg.p[0] == end {
+		return // Discard zero length lines.
+	}
+	for _, v := range seg.p {
+		if math32.IsNaN(v.X) || math32.IsNaN(v.Y) || math32.IsInf(v.X, 0) || math32.IsInf(v.Y, 0) {
+			p.setErr(errors.New("NaN or infinite coordinate in path"))
+			return
+		}
+	}
+	p.segs = append(p.segs, seg)
+	p.cur = end
+}
+
+func (p *Path2D) setErr(err error) {
+	if p.err == nil {
+		p.err = err
+	}
+}
+
+// NewPath2D creates an exact SDF of the area enclosed by the path. Regions with a non-zero winding number
+// are interior as per the non-zero fill rule, so overlapping subpaths of equal orientation are joined and
+// subpaths of opposite orientation cut holes. The path is copied so it may be
+// modified after the call. Distances to lines, arcs and quadratic beziers are exact;
+// distances to cubic beziers are found numerically.
+func (bld *Builder) NewPath2D(path *Path2D) glbuild.Shader2D {
+	cp := *path
+	cp.segs = append([]pathSeg{}, path.segs...)
+	cp.Close()
+	segs := cp.segs
+	if cp.err != nil {
+		bld.shapeErrorf(cp.err.Error())
+	} else if len(segs) < 2 {
+		bld.shapeErrorf("path needs at least 2 segments to enclose an area")
+	}
+	p := path2D{segs: segs, data: make([][2]ms2.Vec, 0, 3*len(segs))}
+	for _, seg := range segs {
+		p.data = seg.appendGPU(p.data)
+	}
+	if bld.useShaderBuffer(len(p.data) * 4) {
+		return &path2DSSBO{path2D: p, bufname: makeHashName(nil, "ssboPath", p.data)}
+	}
+	return &p
+}
+
+// isLinearBezierQ reports whether the quadratic bezier is a straight line.
+func isLinearBezierQ(a, b, c ms2.Vec) bool {
+	const tol = 1e-5
+	curvature := ms2.Norm(ms2.Add(ms2.Sub(a, ms2.Scale(2, b)), c))
+	return curvature <= tol*ms2.Norm(ms2.Sub(c, a))
+}
+
+type pathSegKind uint8
+
+// Segment kinds. Values are used as segment type in shader.
+const (
+	pathLine pathSegKind = iota
+	pathQuad
+	pathCubic
+	pathArc
+)
+
+// pathSeg is a single segment of a path.
+type pathSeg struct {
+	kind pathSegKind
+	// p contains start point, control points and end point of lines and beziers.
+	// Arcs store start point, end point and center.
+	p [4]ms2.Vec
+	// Arc radius, start angle and signed angle spanned.
+	r, a0, da float32
+}
+
+func (s *pathSeg) end() ms2.Vec {
+	switch s.kind {
+	case pathQuad:
+		return s.p[2]
+	case pathCubic:
+		return s.p[3]
+	}
+	return s.p[1]
+}
+
+// at returns the segment's point at parameter t in 0..1.
+func (s *pathSeg) at(t float32) ms2.Vec {
+	if t == 0 {
+		return s.p[0]
+	}
+	u := 1 - t
+	switch s.kind {
+	case pathLine:
+		return ms2.Add(s.p[0], ms2.Scale(t, ms2.Sub(s.p[1], s.p[0])))
+	case pathQuad:
+		return ms2.Add(ms2.Scale(u*u, s.p[0]), ms2.Add(ms2.Scale(2*u*t, s.p[1]), ms2.Scale(t*t, s.p[2])))
+	case pathCubic:
+		mid := ms2.Add(ms2.Scale(u, s.p[1]), ms2.Scale(t, s.p[2]))
+		return ms2.Add(ms2.Scale(u*u*u, s.p[0]), ms2.Add(ms2.Scale(3*u*t, mid), ms2.Scale(t*t*t, s.p[3])))
+	}
+	if t == 1 {
+		return s.p[1]
+	}
+	sin, cos := math32.Sincos(s.a0 + t*s.da)
+	return ms2.Add(s.p[2], ms2.Vec{X: s.r * cos, Y: s.r * sin})
+}
+
+// xRange returns a conservative range of x values covered by the segment.
+func (s *pathSeg) xRange() (lo, hi float32) {
+	switch s.kind {
+	case pathArc:
+		return s.p[2].X - s.r, s.p[2].X + s.r
+	case pathLine:
+		return math32.Min(s.p[0].X, s.p[1].X), math32.Max(s.p[0].X, s.p[1].X)
+	}
+	n := 3 + int(s.kind-pathQuad)
+	lo, hi = s.p[0].X, s.p[0].X
+	for _, v := range s.p[1:n] {
+		lo = math32.Min(lo, v.X)
+		hi = math32.Max(hi, v.X)
+	}
+	return lo, hi
+}
+
+// ySplits appends the parameters in 0..1 of the segment's y extrema in increasing order.
+// Pieces of the segment between these parameters are monotone in y.
+func (s *pathSeg) ySplits(dst []float32) []float32 {
+	n := len(dst)
+	add := func(t float32) {
+		if t > 0 && t < 1 && len(dst)-n < 2 {
+			dst = append(dst, t)
+		}
+	}
+	switch s.kind {
+	case pathQuad:
+		y0, y1, y2 := s.p[0].Y, s.p[1].Y, s.p[2].Y
+		if den := y0 - 2*y1 + y2; den != 0 {
+			add((y0 - y1) / den)
+		}
+	case pathCubic:
+		ya, yb, yc := s.p[1].Y-s.p[0].Y, s.p[2].Y-s.p[1].Y, s.p[3].Y-s.p[2].Y
+		qa, qb, qc := ya-2*yb+yc, 2*(yb-ya), ya
+		if math32.Abs(qa) < 1e-12 {
+			if qb != 0 {
+				add(-qc / qb)
+			}
+		} else if disc := qb*qb - 4*qa*qc; disc >= 0 {
+			disc = math32.Sqrt(disc)
+			add((-qb - disc) / (2 * qa))
+			add((-qb + disc) / (2 * qa))
+		}
+	case pathArc:
+		k0 := math32.Ceil((math32.Min(s.a0, s.a0+s.da) - math.Pi/2) / math.Pi)
+		for i := float32(0); i < 3; i++ {
+			add((math.Pi/2 + (k0+i)*math.Pi - s.a0) / s.da)
+		}
+	}
+	if len(dst)-n == 2 && dst[n] > dst[n+1] {
+		dst[n], dst[n+1] = dst[n+1], dst[n]
+	}
+	return dst
+}
+
+// winding returns the signed amount of times a ray starting at p in the +x direction crosses the segment.
+// Crossings of the segment going upwards count as +1 and downwards as -1.
+func (s *pathSeg) winding(p ms2.Vec) int {
+	a, b := s.p[0], s.end()
+	if s.kind == pathLine {
+		// Same rule as the polygon non-zero winding number.
+		e := ms2.Sub(b, a)
+		w := ms2.Sub(p, a)
+		isLeft := e.X*w.Y - e.Y*w.X
+		if a.Y <= p.Y {
+			if b.Y > p.Y && isLeft > 0 {
+				return 1
+			}
+		} else if b.Y <= p.Y && isLeft < 0 {
+			return -1
+		}
+		return 0
+	}
+	lo, hi := s.xRange()
+	if p.X >= hi {
+		return 0
+	} else if p.X < lo {
+		// All crossings to the right of p, only endpoints determine the winding.
+		return windingDir(a.Y <= p.Y, b.Y <= p.Y)
+	}
+	var buf [4]float32
+	ts := append(buf[:1], s.ySplits(buf[1:1])...)
+	ts = append(ts, 1)
+	winding := 0
+	for i := 0; i < len(ts)-1; i++ {
+		ta, tb := ts[i], ts[i+1]
+		below := s.at(ta).Y <= p.Y
+		dir := windingDir(below, s.at(tb).Y <= p.Y)
+		if dir == 0 {
+			continue
+		}
+		for k := 0; k < 24; k++ {
+			tm := 0.5 * (ta + tb)
+			if (s.at(tm).Y <= p.Y) == below {
+				ta = tm
+			} else {
+				tb = tm
+			}
+		}
+		if s.at(0.5*(ta+tb)).X > p.X {
+			winding += dir
+		}
+	}
+	return winding
+}
+
+// windingDir returns +1 for a y-monotone piece going from below to above a ray, -1 for the opposite and 0 if it does not cross.
+func windingDir(startBelow, endBelow bool) int {
+	if startBelow == endBelow {
+		return 0
+	} else if startBelow {
+		return 1
+	}
+	return -1
+}
+
+// distSq returns the squared distance from p to the segment.
+func (s *pathSeg) distSq(p ms2.Vec) float32 {
+	switch s.kind {
+	case pathLine:
+		e := ms2.Sub(s.p[1], s.p[0])
+		w := ms2.Sub(p, s.p[0])
+		h := clampf(ms2.Dot(w, e)/ms2.Dot(e, e), 0, 1)
+		return ms2.Norm2(ms2.Sub(w, ms2.Scale(h, e)))
+	case pathQuad:
+		bz := makeBezierQ(s.p[0], s.p[1], s.p[2])
+		return bz.distSq(p)
+	case pathCubic:
+		return bezierCDistSq(p, s.p[0], s.p[1], s.p[2], s.p[3])
+	}
+	// Arc: closest point is on circle if p lies within the arc's angular span, else closest point is an endpoint.
+	w := ms2.Sub(p, s.p[2])
+	lw := ms2.Norm(w)
+	sin, cos := math32.Sincos(s.a0 + s.da/2)
+	if ms2.Dot(w, ms2.Vec{X: cos, Y: sin}) >= math32.Cos(math32.Abs(s.da)/2)*lw {
+		return (lw - s.r) * (lw - s.r)
+	}
+	return math32.Min(ms2.Norm2(ms2.Sub(p, s.p[0])), ms2.Norm2(ms2.Sub(p, s.p[1])))
+}
+
+// bounds returns the segment's bounding box. Bezier bounds are the control point bounds.
+func (s *pathSeg) bounds() ms2.Box {
+	if s.kind != pathArc {
+		bb := ms2.Box{Min: s.p[0], Max: s.p[0]}
+		for _, v := range s.p[1 : 2+int(s.kind)] {
+			bb = bb.IncludePoint(v)
+		}
+		return bb
+	}
+	bb := ms2.Box{Min: s.p[0], Max: s.p[0]}.IncludePoint(s.p[1])
+	// Include axis aligned extrema of the circle within arc's span.
+	k0 := math32.Ceil(math32.Min(s.a0, s.a0+s.da) / (math.Pi / 2))
+	for i := float32(0); i < 4; i++ {
+		t := ((k0+i)*math.Pi/2 - s.a0) / s.da
+		if t > 0 && t < 1 {
+			bb = bb.IncludePoint(s.at(t))
+		}
+	}
+	return bb
+}
+
+// appendGPU appends the segment's shader representation of three vec4 to dst. See gsdfPathSeg2D.
+func (s *pathSeg) appendGPU(dst [][2]ms2.Vec) [][2]ms2.Vec {
+	kind := ms2.Vec{X: float32(s.kind)}
+	switch s.kind {
+	case pathLine:
+		return append(dst, [2]ms2.Vec{kind, s.p[0]}, [2]ms2.Vec{s.p[1]}, [2]ms2.Vec{})
+	case pathQuad:
+		return append(dst, [2]ms2.Vec{kind, s.p[0]}, [2]ms2.Vec{s.p[1], s.p[2]}, [2]ms2.Vec{})
+	case pathCubic:
+		return append(dst, [2]ms2.Vec{kind, s.p[0]}, [2]ms2.Vec{s.p[1], s.p[2]}, [2]ms2.Vec{s.p[3]})
+	}
+	return append(dst, [2]ms2.Vec{kind, s.p[0]}, [2]ms2.Vec{s.p[1], s.p[2]}, [2]ms2.Vec{{X: s.r, Y: s.a0}, {X: s.da}})
+}
+
+type path2D struct {
+	segs []pathSeg
+	data [][2]ms2.Vec // Segments encoded for use in shader.
+}
+
+func (c *path2D) Bounds() ms2.Box {
+	if len(c.segs) == 0 {
+		return ms2.Box{} // Invalid path built with FlagNoDimensionPanic.
+	}
+	bb := c.segs[0].bounds()
+	for i := range c.segs[1:] {
+		bb = bb.Union(c.segs[i+1].bounds())
+	}
+	return bb
+}
+
+func (c *path2D) AppendShaderName(b []byte) []byte {
+	var hash uint64 = 0xfafa0fa_c0feebeef
+	for _, v := range c.data {
+		hash = hash*31 ^ uint64(math.Float32bits(v[0].X)) ^ uint64(math.Float32bits(v[0].Y))<<32
+		hash = hash*31 ^ uint64(math.Float32bits(v[1].X)) ^ uint64(math.Float32bits(v[1].Y))<<32
+	}
+	b = append(b, "path2D"...)
+	b = strconv.AppendUint(b, hash, 32)
+	return b
+}
+
+const pathShader = `const int num = segs.length();
+vec2 d_w = vec2(1.0e23, 0.0);
+for( int i=0; i<num; i+=3 )
+{
+	d_w = gsdfPathSeg2D(p,segs[i],segs[i+1],segs[i+2],d_w);
+}
+return (d_w.y != 0.0 ? -1.0 : 1.0)*sqrt(d_w.x);
+`
+
+func (c *path2D) AppendShaderBody(b []byte) []byte {
+	b = glbuild.AppendGenericSliceDecl(b, "vec4", "segs", len(c.data), func(b []byte, i int) []byte {
+		v := c.data[i]
+		b = append(b, "vec4("...)
+		b = glbuild.AppendFloats(b, ',', '-', '.', v[0].X, v[0].Y, v[1].X, v[1].Y)
+		b = append(b, ')')
+		return b
+	})
+	b = append(b, pathShader...)
+	return b
+}
+
+func (c *path2D) ForEach2DChild(userData any, fn func(userData any, s *glbuild.Shader2D) error) error {
+	return nil
+}
+
+func (u *path2D) AppendShaderObjects(objects []glbuild.ShaderObject) []glbuild.ShaderObject {
+	return append(objects, glsllib.WindingNonZero(), glsllib.QuadraticBezier2D(), glsllib.PathPoint2D(), glsllib.PathSegment2D())
+}
+
+type path2DSSBO struct {
+	path2D
+	bufname []byte
+}
+
+func (c *path2DSSBO) AppendShaderBody(b []byte) []byte {
+	b = glbuild.AppendDefineDecl(b, "segs", string(c.bufname))
+	b = append(b, pathShader...)
+	b = glbuild.AppendUndefineDecl(b, "segs")
+	return b
+}
+
+func (u *path2DSSBO) AppendShaderObjects(objects []glbuild.ShaderObject) []glbuild.ShaderObject {
+	ssbo, err := glbuild.MakeShaderBufferReadOnly(u.bufname, u.data)
+	if err != nil {
+		panic(err)
+	}
+	return append(objects, ssbo, glsllib.WindingNonZero(), glsllib.QuadraticBezier2D(), glsllib.PathPoint2D(), glsllib.PathSegment2D())
+}
@@ -553,6 +553,239 @@ func (u *polySSBO) AppendShaderObjects(objects []glbuild.ShaderObject) []glbuild
 	return append(objects, ssbo, glsllib.WindingNumber())
 }
 
+// FillRule determines which regions enclosed by a set of contours are interior to the shape.
+type FillRule uint8
+
+const (
+	// FillEvenOdd fills regions which are enclosed an odd amount of times. This is the rule used by [Builder.NewPolygon].
+	FillEvenOdd FillRule = iota
+	// FillNonZero fills regions around which the contours wind a nonzero amount of times.
+	// Regions enclosed by contours of opposite orientation cancel each other out.
+	FillNonZero
+)
+
+// NewPolygonSet creates a single shape from several polygon contours, i.e: a polygon with holes or several disjoint polygons.
+// All contours are evaluated in one pass which is much faster than combining polygons with [Builder.Difference2D] or [Builder.Union2D].
+// The fill rule determines the interior of the shape. For [FillEvenOdd] holes may be specified with any orientation;
+// for [FillNonZero] holes must be oriented opposite to the contour that encloses them.
+// The distance is exact when contours do not intersect each other. The contours are copied so they may be modified after the call.
+func (bld *Builder) NewPolygonSet(contours [][]ms2.Vec, fillRule FillRule) glbuild.Shader2D {
+	if fillRule > FillNonZero {
+		bld.shapeErrorf("invalid fill rule")
+	}
+	if len(contours) == 0 {
+		bld.shapeErrorf("no contours in polygon set")
+	}
+	var edges [][2]ms2.Vec
+	for i, vertices := range contours {
+		if len(vertices) == 0 {
+			bld.shapeErrorf("empty contour %d in polygon set", i)
+			continue
+		}
+		vertices, err := bld.validatePolygon(vertices)
+		if err != nil {
+			bld.shapeErrorf("contour %d: %s", i, err.Error())
+			continue
+		} else if len(vertices) < 3 {
+			continue
+		}
+		prev := vertices[len(vertices)-1]
+		for _, v := range vertices {
+			edges = append(edges, [2]ms2.Vec{prev, v})
+			prev = v
+		}
+	}
+	// Contours are kept for describing the shape so are copied to prevent modification by the caller.
+	cp := make([][]ms2.Vec, len(contours))
+	for i := range contours {
+		cp[i] = append([]ms2.Vec{}, contours[i]...)
+	}
+	set := polySet{contours: cp, edges: edges, rule: fillRule}
+	if bld.useShaderBuffer(len(edges) * 4) {
+		return &polySetSSBO{polySet: set, bufname: makeHashName(nil, "ssboPolySet", edges)}
+	}
+	return &set
+}
+
+type polySet struct {
+	contours [][]ms2.Vec
+	edges    [][2]ms2.Vec // Directed edges of all contours.
+	rule     FillRule
+}
+
+func (c *polySet) Bounds() ms2.Box {
+	min := ms2.Vec{X: largenum, Y: largenum}
+	max := ms2.Vec{X: -largenum, Y: -largenum}
+	for _, e := range c.edges {
+		min = ms2.MinElem(min, e[0])
+		max = ms2.MaxElem(max, e[0])
+	}
+	return ms2.Box{Min: min, Max: max}
+}
+
+func (c *polySet) AppendShaderName(b []byte) []byte {
+	var hash uint64 = 0xfafa0fa_c0feebeef
+	for _, e := range c.edges {
+		hash = hash*31 ^ uint64(math.Float32bits(e[0].X)) ^ uint64(math.Float32bits(e[0].Y))<<32
+		hash = hash*31 ^ uint64(math.Float32bits(e[1].X)) ^ uint64(math.Float32bits(e[1].Y))<<32
+	}
+	b = append(b, "polySet"...)
+	b = strconv.AppendUint(b, uint64(c.rule), 10)
+	b = append(b, '_')
+	b = strconv.AppendUint(b, hash, 32)
+	return b
+}
+
+const polySetEvenOddShader = `const int num = e.length();
+vec2 d_s = vec2(1.0e23, 1.0);
+for( int i=0; i<num; i++ )
+{
+	d_s = gsdfWinding(p,e[i].xy,e[i].zw,d_s);
+}
+return d_s.y*sqrt(d_s.x);
+`
+
+const polySetNonZeroShader = `const int num = e.length();
+vec2 d_w = vec2(1.0e23, 0.0);
+for( int i=0; i<num; i++ )
+{
+	d_w = gsdfWindingNonZero(p,e[i].xy,e[i].zw,d_w);
+}
+return (d_w.y == 0.0 ? 1.0 : -1.0)*sqrt(d_w.x);
+`
+
+func (c *polySet) appendShader(b []byte) []byte {
+	if c.rule == FillNonZero {
+		return append(b, polySetNonZeroShader...)
+	}
+	return append(b, polySetEvenOddShader...)
+}
+
+func (c *polySet) AppendShaderBody(b []byte) []byte {
+	b = glbuild.AppendGenericSliceDecl(b, "vec4", "e", len(c.edges), func(b []byte, i int) []byte {
+		e := c.edges[i]
+		b = append(b, "vec4("...)
+		b = glbuild.AppendFloats(b, ',', '-', '.', e[0].X, e[0].Y, e[1].X, e[1].Y)
+		b = append(b, ')')
+		return b
+	})
+	return c.appendShader(b)
+}
+
+func (c *polySet) ForEach2DChild(userData any, fn func(userData any, s *glbuild.Shader2D) error) error {
+	return nil
+}
+
+func (c *polySet) AppendShaderObjects(objects []glbuild.ShaderObject) []glbuild.ShaderObject {
+	if c.rule == FillNonZero {
+		return append(objects, glsllib.WindingNonZero())
+	}
+	return append(objects, glsllib.WindingNumber())
+}
+
+type polySetSSBO struct {
+	polySet
+	bufname []byte
+}
+
+func (c *polySetSSBO) AppendShaderBody(b []byte) []byte {
+	b = glbuild.AppendDefineDecl(b, "e", string(c.bufname))
+	b = c.appendShader(b)
+	b = glbuild.AppendUndefineDecl(b, "e")
+	return b
+}
+
+func (c *polySetSSBO) AppendShaderObjects(objects []glbuild.ShaderObject) []glbuild.ShaderObject {
+	ssbo, err := glbuild.MakeShaderBufferReadOnly(c.bufname, c.edges)
+	if err != nil {
+		panic(err)
+	}
+	return c.polySet.AppendShaderObjects(append(objects, ssbo))
+}
+
+// NewRoundedPolygon creates a polygon whose vertices are rounded with a tangent arc of radius radii[i].
+// A radius of zero leaves the vertex sharp. Unlike offsetting an inset polygon each vertex may have a distinct radius
+// and the resulting SDF is exact. The arcs of neighboring vertices may not overlap.
+// The shape is built as a [Path2D] and so uses shader buffers for large vertex counts, see [Builder.NewPath2D].
+// The interior is therefore given by the non-zero winding rule, which differs from the even-odd rule of
+// [Builder.NewPolygon] for self-intersecting polygons.
+func (bld *Builder) NewRoundedPolygon(vertices []ms2.Vec, radii []float32) glbuild.Shader2D {
+	return bld.newCornerPolygon(vertices, radii, false)
+}
+
+// NewChamferedPolygon creates a polygon whose vertices are cut by a chamfer starting at distance chamfers[i] from vertex i,
+// as measured along both edges adjacent to the vertex. A distance of zero leaves the vertex sharp.
+// The chamfers of neighboring vertices may not overlap. The shape is built as a [Path2D] like [Builder.NewRoundedPolygon]
+// and so shares its non-zero fill rule.
+func (bld *Builder) NewChamferedPolygon(vertices []ms2.Vec, chamfers []float32) glbuild.Shader2D {
+	return bld.newCornerPolygon(vertices, chamfers, true)
+}
+
+func (bld *Builder) newCornerPolygon(vertices []ms2.Vec, sizes []float32, chamfer bool) glbuild.Shader2D {
+	if len(sizes) != len(vertices) {
+		bld.shapeErrorf("need one corner size per polygon vertex")
+		sizes = make([]float32, len(vertices))
+	}
+	vertices, err := bld.validatePolygon(vertices)
+	if err != nil {
+		bld.shapeErrorf(err.Error())
+	}
+	sizes = sizes[:len(vertices)]
+	n := len(vertices)
+	// Corners are cut at distance setback from vertex along both edges.
+	setback := make([]float32, n)
+	turnsLeft := make([]bool, n)
+	for i, v := range vertices {
+		size := sizes[i]
+		if !(size >= 0) || math32.IsInf(size, 1) {
+			bld.shapeErrorf("corner size must be positive or zero")
+		}
+		e1 := ms2.Unit(ms2.Sub(v, vertices[(i+n-1)%n]))
+		e2 := ms2.Unit(ms2.Sub(vertices[(i+1)%n], v))
+		cross := ms2.Cross(e1, e2)
+		turnsLeft[i] = cross > 0
+		if chamfer {
+			setback[i] = size
+		} else if size > 0 {
+			// Tangent arc setback is r*tan(turn/2) where turn is the angle between edges.
+			turn := math32.Atan2(math32.Abs(cross), ms2.Dot(e1, e2))
+			setback[i] = size * math32.Tan(turn/2)
+		}
+		// Setback grows without bound as edges approach reversal.
+		maxSetback := math32.Min(ms2.Norm(ms2.Sub(v, vertices[(i+n-1)%n])), ms2.Norm(ms2.Sub(vertices[(i+1)%n], v)))
+		if !(setback[i] >= 0 && setback[i] <= maxSetback) {
+			bld.shapeErrorf("corner of polygon vertex %d does not fit in its adjacent edges", i)
+			setback[i] = 0
+		}
+	}
+	for i, v := range vertices {
+		next := (i + 1) % n
+		if setback[i]+setback[next] > ms2.Norm(ms2.Sub(vertices[next], v))*(1+1e-6) {
+			bld.shapeErrorf("corners of polygon edge %d overlap", i)
+		}
+	}
+	var path Path2D
+	for i, v := range vertices {
+		e1 := ms2.Unit(ms2.Sub(v, vertices[(i+n-1)%n]))
+		e2 := ms2.Unit(ms2.Sub(vertices[(i+1)%n], v))
+		t1 := ms2.Sub(v, ms2.Scale(setback[i], e1))
+		t2 := ms2.Add(v, ms2.Scale(setback[i], e2))
+		if i == 0 {
+			path.MoveTo(t1.X, t1.Y)
+		} else {
+			path.LineTo(t1.X, t1.Y)
+		}
+		switch {
+		case setback[i] == 0:
+		case chamfer:
+			path.LineTo(t2.X, t2.Y)
+		default:
+			path.ArcTo(t2.X, t2.Y, sizes[i], false, !turnsLeft[i])
+		}
+	}
+	return bld.NewPath2D(&path)
+}
+
 type diamond struct {
 	d ms2.Vec
 }